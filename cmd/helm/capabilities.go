@@ -0,0 +1,112 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+var capabilitiesHelp = `
+This command consists of multiple subcommands to work with capabilities
+profiles. A capabilities profile holds the Kubernetes version and API versions
+exposed to templates as .Capabilities, and can be passed to 'helm template',
+'helm lint' and 'helm install --dry-run' with the --capabilities-file flag to
+render charts offline as they would be rendered for a real cluster.
+`
+
+var capabilitiesDumpHelp = `
+This command captures the Kubernetes version and the API versions of the
+cluster currently pointed at, and writes them to FILE or to standard output.
+`
+
+var capabilitiesListHelp = `
+This command lists the built-in capabilities profiles. Each one approximates
+an upstream Kubernetes cluster of that version without additional API services
+or CRDs.
+`
+
+func newCapabilitiesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "capabilities",
+		Short: "capture and list capabilities profiles for offline rendering",
+		Long:  capabilitiesHelp,
+		Args:  require.NoArgs,
+	}
+
+	cmd.AddCommand(newCapabilitiesDumpCmd(cfg, out))
+	cmd.AddCommand(newCapabilitiesListCmd(out))
+
+	return cmd
+}
+
+func newCapabilitiesDumpCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewCapabilities(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "dump [FILE]",
+		Short: "capture the capabilities of the current cluster",
+		Long:  capabilitiesDumpHelp,
+		Args:  require.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			caps, err := client.Run()
+			if err != nil {
+				return err
+			}
+			data, err := chartutil.MarshalCapabilities(caps)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				_, err = out.Write(data)
+				return err
+			}
+			if err := os.WriteFile(args[0], data, 0644); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Capabilities of Kubernetes %s written to %s\n", caps.KubeVersion.Version, args[0])
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newCapabilitiesListCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "list",
+		Short:             "list the built-in capabilities profiles",
+		Long:              capabilitiesListHelp,
+		Args:              require.NoArgs,
+		ValidArgsFunction: noCompletions,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			for _, name := range chartutil.BuiltinCapabilitiesNames() {
+				fmt.Fprintln(out, name)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,40 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCapabilitiesListCmd(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "list built-in capabilities profiles",
+		cmd:    "capabilities list",
+		golden: "output/capabilities-list.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestCapabilitiesDumpCmd(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "dump with too many arguments",
+		cmd:       "capabilities dump a b",
+		golden:    "output/capabilities-dump-too-many-args.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
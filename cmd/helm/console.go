@@ -0,0 +1,215 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+const consoleDesc = `
+This command loads a chart, as 'helm template' does, and evaluates template
+expressions against it interactively, with the same functions and named
+templates as its templates. Values are the values of the chart merged with
+those given with '--values' and '--set'.
+
+Expressions are evaluated with the same '.' as the templates of the chart,
+holding .Values, .Release, .Chart and .Capabilities, and their result is
+shown as YAML:
+
+    > .Values.image
+    repository: nginx
+    tag: stable
+    > include "mychart.labels" . | fromYaml
+    app.kubernetes.io/name: mychart
+
+Input containing '{{' is rendered as a template instead, and the named
+templates it defines can be used by the next expressions.
+
+Press Tab to complete paths such as '.Values.ima', and the names of named
+templates after an opening quote. Type ':help' for the console commands.
+`
+
+const consoleHelp = `Enter a template expression, e.g. '.Values.image' or 'include "mychart.labels" .',
+or a template containing '{{'.
+
+  :templates  list the named templates
+  :help       show this help
+  :quit       leave the console
+`
+
+func newConsoleCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewConsole(cfg)
+	valueOpts := &values.Options{}
+	var kubeVersion string
+	var extraAPIs []string
+
+	cmd := &cobra.Command{
+		Use:   "console CHART",
+		Short: "evaluate template expressions against a chart interactively",
+		Long:  consoleDesc,
+		Args:  require.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if kubeVersion != "" {
+				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
+				if err != nil {
+					return fmt.Errorf("invalid kube version '%s': %s", kubeVersion, err)
+				}
+				client.KubeVersion = parsedKubeVersion
+			}
+			client.APIVersions = chartutil.VersionSet(extraAPIs)
+			client.Namespace = settings.Namespace()
+
+			vals, err := valueOpts.MergeValues(getter.All(settings))
+			if err != nil {
+				return err
+			}
+			console, err := client.Run(args[0], vals)
+			if err != nil {
+				return err
+			}
+			return runConsole(console, cmd.InOrStdin(), out)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&client.ReleaseName, "release-name", client.ReleaseName, "name of the release, as seen by the templates")
+	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for Capabilities.KubeVersion")
+	f.StringSliceVarP(&extraAPIs, "api-versions", "a", []string{}, "Kubernetes api versions used for Capabilities.APIVersions")
+	addValueOptionsFlags(f, valueOpts)
+	bindCapabilitiesFileFlag(cmd, &client.CapabilitiesProfile)
+
+	return cmd
+}
+
+// runConsole evaluates the lines read from in. When in is a terminal, the
+// lines are edited with completion; otherwise, e.g. when the input is piped,
+// they are evaluated as they are read.
+func runConsole(console *engine.Console, in io.Reader, out io.Writer) error {
+	f, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if !evalConsoleLine(console, scanner.Text(), out) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	fd := int(f.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, out}, "> ")
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		start, completions := console.Complete(line[:pos])
+		if len(completions) == 0 {
+			return "", 0, false
+		}
+		completion := commonPrefix(completions)
+		if completion == line[start:pos] && len(completions) > 1 {
+			// The terminal is unlocked while completing, and redraws the
+			// line below the candidates.
+			fmt.Fprintln(t, strings.Join(completions, "  "))
+			return "", 0, false
+		}
+		return line[:start] + completion + line[pos:], start + len(completion), true
+	}
+
+	fmt.Fprintln(t, "Type ':help' for help.")
+	for {
+		line, err := t.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !evalConsoleLine(console, line, t) {
+			return nil
+		}
+	}
+}
+
+// evalConsoleLine evaluates a line and writes its result to out. It returns
+// false when the console is to be left.
+func evalConsoleLine(console *engine.Console, line string, out io.Writer) bool {
+	line = strings.TrimSpace(line)
+	switch line {
+	case "":
+	case ":quit", ":exit":
+		return false
+	case ":help":
+		fmt.Fprint(out, consoleHelp)
+	case ":templates":
+		for _, name := range console.TemplateNames() {
+			fmt.Fprintln(out, name)
+		}
+	default:
+		result, err := console.Eval(line)
+		if err != nil {
+			fmt.Fprintf(out, "Error: %s\n", err)
+			return true
+		}
+		if s, ok := result.(string); ok {
+			fmt.Fprintln(out, strings.TrimSuffix(s, "\n"))
+			return true
+		}
+		data, err := yaml.Marshal(result)
+		if err != nil {
+			fmt.Fprintf(out, "Error: %s\n", err)
+			return true
+		}
+		fmt.Fprint(out, string(data))
+	}
+	return true
+}
+
+// commonPrefix returns the longest common prefix of strs.
+func commonPrefix(strs []string) string {
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"helm.sh/helm/v3/internal/test"
+)
+
+func TestConsoleCmd(t *testing.T) {
+	in, err := os.Open("testdata/console-input.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	_, out, err := executeActionCommandStdinC(storageFixture(), in, "console testdata/testcharts/chart-with-starlark --set replicas=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertGoldenString(t, out, "output/console.txt")
+}
+
+func TestConsoleCmdCapabilitiesFile(t *testing.T) {
+	in, err := os.Open("testdata/console-capabilities-input.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	_, out, err := executeActionCommandStdinC(storageFixture(), in, "console testdata/testcharts/chart-with-starlark --capabilities-file testdata/capabilities.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertGoldenString(t, out, "output/console-capabilities.txt")
+}
+
+func TestConsoleCmdErrors(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "console with no chart",
+		cmd:       "console",
+		golden:    "output/console-no-args.txt",
+		wantError: true,
+	}, {
+		name:      "console with a missing chart",
+		cmd:       "console testdata/testcharts/missing",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
@@ -23,23 +23,30 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
 
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/kubeschema"
+	"helm.sh/helm/v3/pkg/policy"
 	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/repo"
 )
 
 const (
-	outputFlag         = "output"
-	postRenderFlag     = "post-renderer"
-	postRenderArgsFlag = "post-renderer-args"
+	outputFlag           = "output"
+	postRenderFlag       = "post-renderer"
+	postRenderArgsFlag   = "post-renderer-args"
+	capabilitiesFileFlag = "capabilities-file"
+	policyFlag           = "policy"
 )
 
 func addValueOptionsFlags(f *pflag.FlagSet, v *values.Options) {
@@ -66,6 +73,46 @@ func addChartPathOptionsFlags(f *pflag.FlagSet, c *action.ChartPathOptions) {
 	f.BoolVar(&c.PassCredentialsAll, "pass-credentials", false, "pass credentials to all domains")
 }
 
+// addRenderLimitFlags adds the flags bounding the resources used to render templates.
+func addRenderLimitFlags(f *pflag.FlagSet, limits *engine.RenderLimits, timeout *time.Duration) {
+	f.DurationVar(timeout, "render-timeout", 0, "maximum time spent rendering the templates. Use 0 for no limit")
+	f.Int64Var(&limits.MaxOutputBytes, "max-output-bytes", 0, "maximum size of all the rendered templates in bytes. Use 0 for no limit")
+	f.Int64Var(&limits.MaxTemplateOutputBytes, "max-template-output-bytes", 0, "maximum size of a rendered template, or of the output of an 'include' or 'tpl' call, in bytes. Use 0 for no limit")
+	f.IntVar(&limits.MaxTplDepth, "max-tpl-depth", 0, "maximum number of nested 'tpl' calls. Use 0 for no limit")
+}
+
+// schemaValidationOptions configure the offline validation of the rendered
+// resources against the schemas of their kinds.
+type schemaValidationOptions struct {
+	validate  bool
+	locations []string
+}
+
+// addSchemaValidationFlags adds the flags of the offline validation of the
+// rendered resources.
+func addSchemaValidationFlags(f *pflag.FlagSet, opts *schemaValidationOptions) {
+	f.BoolVar(&opts.validate, "validate-schema", false, "validate the rendered resources offline against the schemas of their kinds: the built-in ones of the Kubernetes version, those of the CustomResourceDefinitions of the chart and those of --schema-location")
+	f.StringArrayVar(&opts.locations, "schema-location", []string{}, "file or directory of OpenAPI documents of the Kubernetes API (JSON), JSON schemas or CustomResourceDefinitions (YAML) to validate the rendered resources against. Implies --validate-schema. Can be specified multiple times")
+}
+
+// validator returns the schema validator for the Kubernetes version, or of
+// the capabilities if it is nil, or nil if validation is not enabled.
+func (o *schemaValidationOptions) validator(kubeVersion *chartutil.KubeVersion, caps *chartutil.Capabilities) (*kubeschema.Validator, error) {
+	if !o.validate && len(o.locations) == 0 {
+		return nil, nil
+	}
+	if kubeVersion == nil && caps != nil {
+		kubeVersion = &caps.KubeVersion
+	}
+	v := kubeschema.NewValidator(kubeVersion)
+	for _, location := range o.locations {
+		if err := v.AddLocation(location); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
 // bindOutputFlag will add the output flag to the given command and bind the
 // value to the given format pointer
 func bindOutputFlag(cmd *cobra.Command, varRef *output.Format) {
@@ -195,6 +242,69 @@ func (p *postRendererArgsSlice) GetSlice() []string {
 	return p.options.args
 }
 
+func bindCapabilitiesFileFlag(cmd *cobra.Command, varRef **chartutil.Capabilities) {
+	cmd.Flags().Var(&capabilitiesFileValue{caps: varRef}, capabilitiesFileFlag, fmt.Sprintf("a file created by 'helm capabilities dump', or the name of a built-in profile (%s), providing the Kubernetes version and API versions used for Capabilities", strings.Join(chartutil.BuiltinCapabilitiesNames(), ", ")))
+	err := cmd.RegisterFlagCompletionFunc(capabilitiesFileFlag, func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return chartutil.BuiltinCapabilitiesNames(), cobra.ShellCompDirectiveDefault
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type capabilitiesFileValue struct {
+	caps **chartutil.Capabilities
+	path string
+}
+
+func (c *capabilitiesFileValue) String() string {
+	return c.path
+}
+
+func (c *capabilitiesFileValue) Type() string {
+	return "string"
+}
+
+func (c *capabilitiesFileValue) Set(val string) error {
+	if val == "" {
+		return nil
+	}
+	caps, err := chartutil.LoadCapabilities(val)
+	if err != nil {
+		return err
+	}
+	c.path = val
+	*c.caps = caps
+	return nil
+}
+
+func bindPolicyFlag(cmd *cobra.Command, varRef **policy.Set) {
+	cmd.Flags().Var(&policyValue{set: varRef}, policyFlag, "a file of policies, or a directory of policy files, evaluated against the rendered objects before they are applied (can specify multiple)")
+}
+
+type policyValue struct {
+	set   **policy.Set
+	paths []string
+}
+
+func (p *policyValue) String() string {
+	return "[" + strings.Join(p.paths, ",") + "]"
+}
+
+func (p *policyValue) Type() string {
+	return "stringArray"
+}
+
+func (p *policyValue) Set(val string) error {
+	p.paths = append(p.paths, val)
+	set, err := policy.Load(p.paths...)
+	if err != nil {
+		return err
+	}
+	*p.set = set
+	return nil
+}
+
 func compVersionFlag(chartRef string, _ string) ([]string, cobra.ShellCompDirective) {
 	chartInfo := strings.Split(chartRef, "/")
 	if len(chartInfo) != 2 {
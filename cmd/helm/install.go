@@ -100,6 +100,24 @@ The --dry-run flag will output all generated chart manifests, including Secrets
 which can contain sensitive values. To hide Kubernetes Secrets use the
 --hide-secret flag. Please carefully consider how and when these flags are used.
 
+The --policy flag evaluates policies, CEL expressions, against the rendered
+objects, after post-rendering and before they are applied, with the variables
+'object', 'namespace' and 'release'. Policies of the release scope see the list
+'objects' instead. A violated policy of the deny mode fails the operation,
+listing the violations, and those of the warn mode are reported as warnings:
+
+    policies:
+      - name: team-label
+        match:
+          kinds: [Deployment]
+        validation: "'app.kubernetes.io/team' in object.metadata.?labels.orValue({})"
+      - name: no-load-balancers
+        mode: warn
+        match:
+          kinds: [Service]
+          namespaces: [internal]
+        validation: "object.spec.?type.orValue('') != 'LoadBalancer'"
+
 If --verify is set, the chart MUST have a provenance file, and the provenance
 file MUST pass all verification steps.
 
@@ -139,7 +157,8 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return compInstall(args, toComplete, client)
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client.PolicyWarnings = cmd.ErrOrStderr()
 			registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
 				client.InsecureSkipTLSverify, client.PlainHTTP)
 			if err != nil {
@@ -169,6 +188,7 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.HideSecret, "hide-secret", false, "hide Kubernetes Secrets when also using the --dry-run flag")
 	bindOutputFlag(cmd, &outfmt)
 	bindPostRenderFlag(cmd, &client.PostRenderer)
+	bindPolicyFlag(cmd, &client.Policies)
 
 	return cmd
 }
@@ -199,9 +219,12 @@ func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Instal
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
 	f.StringToStringVarP(&client.Labels, "labels", "l", nil, "Labels that would be added to release metadata. Should be divided by comma.")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
+	f.BoolVar(&client.StrictTemplateNames, "strict-template-names", false, "fail when a named template is defined more than once with different contents")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in install output. Does not affect presence in chart metadata")
 	addValueOptionsFlags(f, valueOpts)
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
+	addRenderLimitFlags(f, &client.RenderLimits, &client.RenderTimeout)
+	bindCapabilitiesFileFlag(cmd, &client.CapabilitiesProfile)
 
 	err := cmd.RegisterFlagCompletionFunc("version", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		requiredArgs := 2
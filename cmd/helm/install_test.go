@@ -317,3 +317,25 @@ func TestInstallFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "install myname", true)
 	checkFileCompletion(t, "install myname mychart", false)
 }
+
+func TestInstallWithPolicies(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "install denied by a policy",
+		cmd:       "install virgil testdata/testcharts/alpine --policy testdata/policy/deny.yaml --policy testdata/policy/warn.yaml",
+		golden:    "output/install-with-denied-policy.txt",
+		wantError: true,
+	}, {
+		name:   "install with a policy warning",
+		cmd:    "install virgil testdata/testcharts/alpine --policy testdata/policy/warn.yaml",
+		golden: "output/install-with-policy-warning.txt",
+	}, {
+		name:   "upgrade --install with a policy warning",
+		cmd:    "upgrade --install virgil testdata/testcharts/alpine --policy testdata/policy/warn.yaml",
+		golden: "output/upgrade-with-policy-warning.txt",
+	}, {
+		name:      "install with a missing policy file",
+		cmd:       "install virgil testdata/testcharts/alpine --policy testdata/policy/does-not-exist.yaml",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
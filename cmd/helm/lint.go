@@ -21,15 +21,21 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
+	"github.com/gosuri/uitable"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/kubeschema"
+	"helm.sh/helm/v3/pkg/lint"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -40,29 +46,160 @@ the chart is well-formed.
 If the linter encounters things that will cause the chart to fail installation,
 it will emit [ERROR] messages. If it encounters issues that break with convention
 or recommendation, it will emit [WARNING] messages.
+
+Each check is a rule with an ID, listed by '--list-rules'. Rules are configured
+by '.helmlint.yaml' files in the chart directory and in its parent directories,
+up to the root of the repository, the closest to the chart taking precedence:
+
+    rules:
+      chart-icon-present: off    # disable a rule
+      metadata-name: error       # change the severity of a rule
+    ignore:
+      - paths: ["templates/legacy/*"]  # relative to the file
+        rules: ["deprecated-api"]      # all rules if omitted
+
+Some rules are opt-in: they belong to packs, which are enabled by
+'--rule-pack' or by the 'packs' list of a '.helmlint.yaml' file. A rule of a
+pack is also enabled by configuring its severity. The 'policy' pack checks
+the pod specs of the rendered workloads and hooks for privileged containers,
+hostPath volumes, the host network, missing resource requests and limits,
+images without a tag or with the latest tag, missing probes, containers
+running as root and writable root filesystems. Its rules are disabled for a
+resource by the 'lint.helm.sh/disable' annotation, listing their IDs
+separated by commas, or empty to disable all of them.
+
+The messages of rules are also suppressed for a file by a comment in it, e.g.
+'{{/* helm-lint-disable metadata-name */}}' in a template or
+'# helm-lint-disable chart-icon-present' in Chart.yaml. Without rule IDs, all
+the rules are suppressed for the file.
+
+The 'values' pack checks that the values the templates use, e.g.
+'.Values.image.tag', including through 'include' and variables, are defined
+in values.yaml or in the values schema, unless the templates test them or
+give them a default. It also reports the values of values.yaml which no
+template or subchart uses.
+
+The messages are printed as text by default. '--output' prints them as JSON,
+as a SARIF 2.1.0 log for code scanning tools, or as a JUnit XML report, with
+their severity, rule ID, file, and line and column where known.
+
+'--validate-schema' validates the rendered resources offline against the
+schemas of their kinds, reporting unknown fields and values of the wrong type.
+The schemas of the built-in kinds are those of the Kubernetes version of
+'--kube-version', or of the API Helm is built with. Those of custom resources
+come from the CustomResourceDefinitions of the 'crds' directory of the charts.
+'--schema-location' adds OpenAPI documents of the Kubernetes API, e.g. saved by
+'kubectl get --raw /openapi/v2', JSON schemas and CustomResourceDefinitions.
+Resources of kinds without schema are not validated.
+
+'--fix' remediates the findings which are mechanical, rewriting the files of
+the chart in place and keeping their comments and formatting: deprecated API
+versions with a known replacement are replaced in the templates, versions
+which are not SemVer are rewritten, e.g. '1.2.3.4' as '1.2.3', templates with
+an invalid extension are renamed, and the icon of '--fix-icon' is set in
+charts without one. The charts are linted again after the fixes. With
+'--dry-run', the changes are printed as a diff instead.
+
+The charts are linted with each combination of a set of values files and a
+Kubernetes version when '--values-matrix', '--values-group' or several
+'--kube-version' flags are given. Each values file of the '--values-matrix'
+directory is a set, named after the file, and so is each of its
+subdirectories, with the values files it contains. '--values-group' adds a set
+of files, e.g. '--values-group prod=values-prod.yaml,values-eu.yaml'. The
+files of a set are merged after those of '--values' and before the values of
+'--set'. The findings are reported once, with the combinations reporting them,
+e.g. '(in prod@1.27.0, staging@1.27.0)'.
 `
 
 func newLintCmd(out io.Writer) *cobra.Command {
 	client := action.NewLint()
 	valueOpts := &values.Options{}
-	var kubeVersion string
+	var kubeVersions []string
+	var valuesMatrix string
+	var valuesGroups []string
+	var lookupFixtures string
+	var configFile string
+	var listRules bool
+	var packs []string
+	var fix, dryRun bool
+	var fixOpts support.FixOptions
+	var outfmt string
+	var schemaOpts schemaValidationOptions
 
 	cmd := &cobra.Command{
 		Use:   "lint PATH",
 		Short: "examine a chart for possible issues",
 		Long:  longLintHelp,
 		RunE: func(_ *cobra.Command, args []string) error {
+			if listRules {
+				return writeLintRules(out)
+			}
 			paths := []string{"."}
 			if len(args) > 0 {
 				paths = args
 			}
+			var write func(io.Writer, []lint.ChartReport) error
+			switch outfmt {
+			case "text":
+			case "json":
+				write = lint.WriteJSON
+			case "sarif":
+				write = lint.WriteSARIF
+			case "junit":
+				write = lint.WriteJUnit
+			default:
+				return errors.Errorf("invalid output format %q: allowed values are text, json, sarif and junit", outfmt)
+			}
 
-			if kubeVersion != "" {
+			if dryRun && !fix {
+				return errors.New("--dry-run requires --fix")
+			}
+			if fix && write != nil {
+				return errors.Errorf("--fix cannot be used with the %s output", outfmt)
+			}
+
+			if err := checkRulePacks(packs); err != nil {
+				return err
+			}
+			client.Packs = packs
+
+			if configFile != "" {
+				config, err := support.ReadConfigFile(configFile)
+				if err != nil {
+					return err
+				}
+				client.Config = config
+			}
+
+			parsedKubeVersions := make([]*chartutil.KubeVersion, len(kubeVersions))
+			for i, kubeVersion := range kubeVersions {
 				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
 				if err != nil {
 					return fmt.Errorf("invalid kube version '%s': %s", kubeVersion, err)
 				}
-				client.KubeVersion = parsedKubeVersion
+				parsedKubeVersions[i] = parsedKubeVersion
+			}
+			if len(parsedKubeVersions) == 1 {
+				client.KubeVersion = parsedKubeVersions[0]
+			}
+
+			validator, err := schemaOpts.validator(client.KubeVersion, client.CapabilitiesProfile)
+			if err != nil {
+				return err
+			}
+			client.SchemaValidator = validator
+
+			sets, err := lintValuesSets(valuesMatrix, valuesGroups)
+			if err != nil {
+				return err
+			}
+
+			if lookupFixtures != "" {
+				provider, err := engine.LoadLookupFixtures(lookupFixtures)
+				if err != nil {
+					return err
+				}
+				client.LookupClientProvider = provider
 			}
 
 			if client.WithSubcharts {
@@ -85,13 +222,47 @@ func newLintCmd(out io.Writer) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			run := func(path string) *action.LintResult {
+				return client.Run([]string{path}, vals)
+			}
+			if len(sets) > 0 || len(kubeVersions) > 1 {
+				combinations, err := lintCombinations(valueOpts, sets, kubeVersions, parsedKubeVersions, func(kubeVersion *chartutil.KubeVersion) (*kubeschema.Validator, error) {
+					return schemaOpts.validator(kubeVersion, client.CapabilitiesProfile)
+				})
+				if err != nil {
+					return err
+				}
+				run = func(path string) *action.LintResult {
+					return client.RunMatrix([]string{path}, combinations)
+				}
+			}
 
 			var message strings.Builder
+			var reports []lint.ChartReport
 			failed := 0
 			errorsOrWarnings := 0
 
 			for _, path := range paths {
-				result := client.Run([]string{path}, vals)
+				result := run(path)
+				if fix {
+					fixed, err := fixChart(&message, path, result.Messages, fixOpts, dryRun)
+					if err != nil {
+						return err
+					}
+					// Report the messages the fixes leave
+					if fixed {
+						result = run(path)
+					}
+				}
+				if len(result.Errors) != 0 {
+					failed++
+				}
+				if write != nil {
+					for _, r := range result.Charts {
+						reports = append(reports, quietReport(r, client.Quiet))
+					}
+					continue
+				}
 
 				// If there is no errors/warnings and quiet flag is set
 				// go to the next chart
@@ -121,19 +292,25 @@ func newLintCmd(out io.Writer) *cobra.Command {
 					}
 				}
 
-				if len(result.Errors) != 0 {
-					failed++
-				}
-
 				// Adding extra new line here to break up the
 				// results, stops this from being a big wall of
 				// text and makes it easier to follow.
 				fmt.Fprint(&message, "\n")
 			}
 
+			summary := fmt.Sprintf("%d chart(s) linted, %d chart(s) failed", len(paths), failed)
+			if write != nil {
+				if err := write(out, reports); err != nil {
+					return err
+				}
+				if failed > 0 {
+					return errors.New(summary)
+				}
+				return nil
+			}
+
 			fmt.Fprint(out, message.String())
 
-			summary := fmt.Sprintf("%d chart(s) linted, %d chart(s) failed", len(paths), failed)
 			if failed > 0 {
 				return errors.New(summary)
 			}
@@ -148,8 +325,210 @@ func newLintCmd(out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Strict, "strict", false, "fail on lint warnings")
 	f.BoolVar(&client.WithSubcharts, "with-subcharts", false, "lint dependent charts")
 	f.BoolVar(&client.Quiet, "quiet", false, "print only warnings and errors")
-	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for capabilities and deprecation checks")
+	f.StringSliceVar(&kubeVersions, "kube-version", nil, "Kubernetes version used for capabilities and deprecation checks (can specify multiple to lint with each of them)")
+	f.StringVar(&valuesMatrix, "values-matrix", "", "lint with each values file, or each subdirectory of values files, of this directory")
+	f.StringArrayVar(&valuesGroups, "values-group", nil, "lint with a named set of values files, e.g. 'prod=values-prod.yaml,values-eu.yaml' (can specify multiple)")
+	f.StringVar(&lookupFixtures, "lookup-fixtures", "", "directory of Kubernetes manifests returned by the 'lookup' function while linting")
+	f.StringVar(&configFile, "lint-config", "", "configure the lint rules with this file instead of the .helmlint.yaml files of the charts")
+	f.BoolVar(&listRules, "list-rules", false, "list the lint rules and exit")
+	f.StringSliceVar(&packs, "rule-pack", nil, "enable a pack of opt-in lint rules, e.g. 'policy' (can specify multiple or separate values with commas)")
+	f.StringVarP(&outfmt, "output", "o", "text", "the format of the messages (text, json, sarif, junit)")
+	f.BoolVar(&fix, "fix", false, "fix the findings which can be remediated automatically, rewriting Chart.yaml and the templates in place")
+	f.BoolVar(&dryRun, "dry-run", false, "with --fix, print the changes as a diff instead of writing them")
+	f.StringVar(&fixOpts.Icon, "fix-icon", "", "with --fix, the icon URL set in the charts without one")
+	addSchemaValidationFlags(f, &schemaOpts)
 	addValueOptionsFlags(f, valueOpts)
+	addRenderLimitFlags(f, &client.RenderLimits, &client.RenderTimeout)
+	bindCapabilitiesFileFlag(cmd, &client.CapabilitiesProfile)
+
+	cmd.RegisterFlagCompletionFunc("rule-pack", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return rulePacks(), cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("output", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "junit", "sarif", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
 
 	return cmd
 }
+
+// quietReport returns the report without its info messages if quiet is set.
+func quietReport(r lint.ChartReport, quiet bool) lint.ChartReport {
+	if !quiet {
+		return r
+	}
+	messages := []support.Message{}
+	for _, msg := range r.Messages {
+		if msg.Severity > support.InfoSev {
+			messages = append(messages, msg)
+		}
+	}
+	r.Messages = messages
+	return r
+}
+
+// lintValuesSet is a named set of values files of a lint matrix.
+type lintValuesSet struct {
+	name  string
+	files []string
+}
+
+// lintValuesSets returns the sets of values files of the values files and
+// subdirectories of the matrix directory, if any, followed by those of the
+// groups, given as NAME=FILE[,FILE...].
+func lintValuesSets(matrix string, groups []string) ([]lintValuesSet, error) {
+	var sets []lintValuesSet
+	if matrix != "" {
+		entries, err := os.ReadDir(matrix)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() {
+				files, err := valuesFiles(filepath.Join(matrix, name))
+				if err != nil {
+					return nil, err
+				}
+				if len(files) == 0 {
+					return nil, errors.Errorf("no values files in %s", filepath.Join(matrix, name))
+				}
+				sets = append(sets, lintValuesSet{name: name, files: files})
+			} else if ext := filepath.Ext(name); ext == ".yaml" || ext == ".yml" {
+				sets = append(sets, lintValuesSet{name: strings.TrimSuffix(name, ext), files: []string{filepath.Join(matrix, name)}})
+			}
+		}
+		if len(sets) == 0 {
+			return nil, errors.Errorf("no values files in %s", matrix)
+		}
+	}
+	for _, group := range groups {
+		name, files, ok := strings.Cut(group, "=")
+		if !ok || name == "" || files == "" {
+			return nil, errors.Errorf("invalid values group %q: expected NAME=FILE[,FILE...]", group)
+		}
+		sets = append(sets, lintValuesSet{name: name, files: strings.Split(files, ",")})
+	}
+	for i, set := range sets {
+		for _, other := range sets[:i] {
+			if other.name == set.name {
+				return nil, errors.Errorf("duplicate values set %q", set.name)
+			}
+		}
+	}
+	return sets, nil
+}
+
+// valuesFiles returns the YAML files of a directory, sorted.
+func valuesFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if ext := filepath.Ext(entry.Name()); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// lintCombinations returns the combinations of each values set with each
+// Kubernetes version. The files of a values set are merged after those of
+// the values options, and before their --set values. A combination is named
+// after its values set and its Kubernetes version, e.g. "prod@1.27".
+func lintCombinations(valueOpts *values.Options, sets []lintValuesSet, kubeVersions []string, parsedKubeVersions []*chartutil.KubeVersion, validator func(*chartutil.KubeVersion) (*kubeschema.Validator, error)) ([]action.LintCombination, error) {
+	if len(sets) == 0 {
+		sets = []lintValuesSet{{}}
+	}
+	var combinations []action.LintCombination
+	for _, set := range sets {
+		opts := *valueOpts
+		opts.ValueFiles = append(append([]string{}, valueOpts.ValueFiles...), set.files...)
+		vals, err := opts.MergeValues(getter.All(settings))
+		if err != nil {
+			return nil, err
+		}
+		if len(kubeVersions) == 0 {
+			combinations = append(combinations, action.LintCombination{Name: set.name, Values: vals})
+			continue
+		}
+		for i, kubeVersion := range kubeVersions {
+			v, err := validator(parsedKubeVersions[i])
+			if err != nil {
+				return nil, err
+			}
+			name := kubeVersion
+			if set.name != "" {
+				name = set.name + "@" + kubeVersion
+			}
+			combinations = append(combinations, action.LintCombination{
+				Name:            name,
+				Values:          vals,
+				KubeVersion:     parsedKubeVersions[i],
+				SchemaValidator: v,
+			})
+		}
+	}
+	return combinations, nil
+}
+
+// fixChart applies the fixes of the lint messages of the chart at path, or
+// writes them as a diff if dryRun is set, and writes the fixes which cannot be
+// applied. It returns whether files are changed.
+func fixChart(out io.Writer, path string, messages []support.Message, opts support.FixOptions, dryRun bool) (bool, error) {
+	fixes, errs := lint.Fixes(path, messages, opts)
+	if len(fixes) == 0 && len(errs) == 0 {
+		return false, nil
+	}
+	fmt.Fprintf(out, "==> Fixing %s\n", path)
+	for _, f := range fixes {
+		if dryRun {
+			fmt.Fprint(out, f.Diff())
+			continue
+		}
+		if err := f.Apply(path); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "Fixed %s\n", f)
+	}
+	for _, err := range errs {
+		fmt.Fprintf(out, "Not fixed %s\n", err)
+	}
+	fmt.Fprint(out, "\n")
+	return !dryRun && len(fixes) > 0, nil
+}
+
+// rulePacks returns the packs of the lint rules, sorted.
+func rulePacks() []string {
+	var packs []string
+	for _, rule := range lint.Rules() {
+		if rule.Pack != "" && !slices.Contains(packs, rule.Pack) {
+			packs = append(packs, rule.Pack)
+		}
+	}
+	sort.Strings(packs)
+	return packs
+}
+
+// checkRulePacks checks that packs of lint rules exist.
+func checkRulePacks(packs []string) error {
+	known := rulePacks()
+	for _, pack := range packs {
+		if !slices.Contains(known, pack) {
+			return errors.Errorf("unknown lint rule pack %q: the packs are %s", pack, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// writeLintRules writes the lint rules as a table.
+func writeLintRules(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("ID", "SEVERITY", "PACK", "DESCRIPTION")
+	for _, rule := range lint.Rules() {
+		table.AddRow(rule.ID, support.SeverityName(rule.Severity), rule.Pack, rule.Description)
+	}
+	_, err := fmt.Fprintln(out, table)
+	return err
+}
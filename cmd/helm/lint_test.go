@@ -87,6 +87,11 @@ func TestLintCmdWithKubeVersionFlag(t *testing.T) {
 		cmd:       fmt.Sprintf("lint --kube-version 1.21.0 --strict %s", testChart),
 		golden:    "output/lint-chart-with-deprecated-api-old-k8s.txt",
 		wantError: false,
+	}, {
+		name:      "lint chart with deprecated api version using a capabilities profile",
+		cmd:       fmt.Sprintf("lint --capabilities-file kubernetes-1.27 --strict %s", testChart),
+		golden:    "output/lint-chart-with-deprecated-api-strict.txt",
+		wantError: true,
 	}}
 	runTestCmd(t, tests)
 }
@@ -95,3 +100,117 @@ func TestLintFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "lint", true)
 	checkFileCompletion(t, "lint mypath", true) // Multiple paths can be given
 }
+
+func TestLintCmdWithLookupFixtures(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint chart using lookup fixtures",
+		cmd:    "lint testdata/testcharts/chart-with-lookup --lookup-fixtures testdata/lookup-fixtures",
+		golden: "output/lint-with-lookup-fixtures.txt",
+	}, {
+		name:      "lint chart using missing lookup fixtures",
+		cmd:       "lint testdata/testcharts/chart-with-lookup --lookup-fixtures testdata/does-not-exist",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithRules(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "list the lint rules",
+		cmd:    "lint --list-rules",
+		golden: "output/lint-list-rules.txt",
+	}, {
+		name:      "lint with a lint configuration file",
+		cmd:       "lint testdata/testcharts/chart-with-deprecated-api --kube-version 1.22.0 --lint-config testdata/lint/helmlint.yaml",
+		golden:    "output/lint-with-lint-config.txt",
+		wantError: true,
+	}, {
+		name:      "lint with a missing lint configuration file",
+		cmd:       "lint testdata/testcharts/chart-with-deprecated-api --lint-config testdata/lint/does-not-exist.yaml",
+		wantError: true,
+	}, {
+		name:   "lint with the policy rule pack",
+		cmd:    "lint testdata/testcharts/chart-with-policy-violations --rule-pack policy",
+		golden: "output/lint-with-policy-pack.txt",
+	}, {
+		name:      "lint with an unknown rule pack",
+		cmd:       "lint testdata/testcharts/alpine --rule-pack security",
+		golden:    "output/lint-with-unknown-pack.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithOutput(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "lint with json output",
+		cmd:       "lint testdata/testcharts/chart-bad-requirements testdata/testcharts/chart-with-deprecated-api -o json",
+		golden:    "output/lint-json.txt",
+		wantError: true,
+	}, {
+		name:      "lint with junit output",
+		cmd:       "lint testdata/testcharts/chart-bad-requirements testdata/testcharts/chart-with-deprecated-api -o junit --quiet",
+		golden:    "output/lint-junit.txt",
+		wantError: true,
+	}, {
+		name:      "lint with an invalid output",
+		cmd:       "lint testdata/testcharts/chart-with-deprecated-api -o yaml",
+		golden:    "output/lint-invalid-output.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithValidateSchema(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "lint the rendered resources against their schemas",
+		cmd:       "lint testdata/testcharts/chart-with-schema-errors --validate-schema",
+		golden:    "output/lint-validate-schema.txt",
+		wantError: true,
+	}, {
+		name:   "lint without validating the rendered resources",
+		cmd:    "lint testdata/testcharts/chart-with-schema-errors",
+		golden: "output/lint-without-validate-schema.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithMatrix(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint with a values matrix and several kube versions",
+		cmd:    "lint testdata/testcharts/chart-with-values-matrix --values-matrix testdata/lint-matrix --kube-version 1.22.0 --kube-version 1.26.0",
+		golden: "output/lint-values-matrix.txt",
+	}, {
+		name:   "lint with a values group as json",
+		cmd:    "lint testdata/testcharts/chart-with-values-matrix --values-group beta=testdata/lint-matrix/prod/autoscaling.yaml --kube-version 1.23.0 -o json",
+		golden: "output/lint-values-group-json.txt",
+	}, {
+		name:      "lint with an invalid values group",
+		cmd:       "lint testdata/testcharts/chart-with-values-matrix --values-group testdata/lint-matrix/dev.yaml",
+		golden:    "output/lint-invalid-values-group.txt",
+		wantError: true,
+	}, {
+		name:      "lint with a values group named after a file of the values matrix",
+		cmd:       "lint testdata/testcharts/chart-with-values-matrix --values-matrix testdata/lint-matrix --values-group dev=testdata/lint-matrix/dev.yaml",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithFix(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "lint with fixes printed as a diff",
+		cmd:    "lint testdata/testcharts/chart-with-deprecated-api --kube-version 1.22.0 --fix --dry-run --fix-icon https://example.com/icon.png",
+		golden: "output/lint-fix-dry-run.txt",
+	}, {
+		name:      "lint with --dry-run and without --fix",
+		cmd:       "lint testdata/testcharts/chart-with-deprecated-api --dry-run",
+		golden:    "output/lint-dry-run-without-fix.txt",
+		wantError: true,
+	}, {
+		name:      "lint with fixes and the json output",
+		cmd:       "lint testdata/testcharts/chart-with-deprecated-api --fix -o json",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+const mapKubeAPIsDesc = `
+This command rewrites the API versions removed from Kubernetes in the manifest
+and the hooks of the last deployed revision of a release.
+
+Once a cluster no longer serves an API version, e.g. 'policy/v1beta1' for
+PodDisruptionBudgets since Kubernetes 1.25, the releases whose deployed revision
+uses it cannot be upgraded anymore. This command replaces the API versions removed in
+the version of the cluster, or in that of '--kube-version', with those
+replacing them, in the stored revision. The changes are appended to the
+description of the revision. The resources in the cluster are not changed.
+
+'--mapping-file' replaces the mappings known to Helm with those of a file:
+
+    mappings:
+      - kind: PodDisruptionBudget
+        deprecatedAPIVersion: policy/v1beta1
+        newAPIVersion: policy/v1
+        removedInVersion: v1.25  # mapped for all versions if omitted
+`
+
+func newMapKubeAPIsCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewMapKubeAPIs(cfg)
+	var mappingFile string
+	var kubeVersion string
+
+	cmd := &cobra.Command{
+		Use:   "mapkubeapis RELEASE",
+		Short: "rewrite the removed Kubernetes APIs of a release",
+		Long:  mapKubeAPIsDesc,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if mappingFile != "" {
+				mappings, err := action.LoadAPIMappings(mappingFile)
+				if err != nil {
+					return err
+				}
+				client.Mappings = mappings
+			}
+			if kubeVersion != "" {
+				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
+				if err != nil {
+					return fmt.Errorf("invalid kube version '%s': %s", kubeVersion, err)
+				}
+				client.KubeVersion = parsedKubeVersion
+			}
+
+			rel, changes, err := client.Run(args[0])
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				fmt.Fprintf(out, "Release %q has no removed API to map\n", rel.Name)
+				return nil
+			}
+			for _, c := range changes {
+				fmt.Fprintln(out, c)
+			}
+			if client.DryRun {
+				fmt.Fprintf(out, "Release %q revision %d would be updated (dry run)\n", rel.Name, rel.Version)
+				return nil
+			}
+			fmt.Fprintf(out, "Release %q revision %d updated\n", rel.Name, rel.Version)
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&client.DryRun, "dry-run", false, "print the changes without storing them")
+	f.StringVar(&mappingFile, "mapping-file", "", "map the API versions of this file instead of those known to Helm")
+	f.StringVar(&kubeVersion, "kube-version", "", "map the API versions removed in this Kubernetes version instead of that of the cluster")
+
+	return cmd
+}
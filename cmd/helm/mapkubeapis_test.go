@@ -0,0 +1,81 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestMapKubeAPIsCmd(t *testing.T) {
+	rels := func() []*release.Release {
+		return []*release.Release{{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusDeployed},
+			Chart:   &chart.Chart{},
+			Version: 1,
+			Manifest: `---
+# Source: funny-honey/templates/pdb.yaml
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: funny-honey
+---
+# Source: funny-honey/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: funny-honey
+`,
+		}}
+	}
+
+	tests := []cmdTestCase{{
+		name:   "map the APIs removed in the version of the cluster",
+		cmd:    "mapkubeapis funny-honey",
+		golden: "output/mapkubeapis-none.txt",
+		rels:   rels(),
+	}, {
+		name:   "map the APIs removed in a Kubernetes version",
+		cmd:    "mapkubeapis funny-honey --kube-version 1.25.0",
+		golden: "output/mapkubeapis.txt",
+		rels:   rels(),
+	}, {
+		name:   "map the APIs with a dry run",
+		cmd:    "mapkubeapis funny-honey --kube-version 1.25.0 --dry-run",
+		golden: "output/mapkubeapis-dry-run.txt",
+		rels:   rels(),
+	}, {
+		name:   "map the APIs of a mapping file",
+		cmd:    "mapkubeapis funny-honey --mapping-file testdata/mapkubeapis.yaml --kube-version 1.25.0",
+		golden: "output/mapkubeapis-mapping-file.txt",
+		rels:   rels(),
+	}, {
+		name:      "map the APIs of a missing release",
+		cmd:       "mapkubeapis angry-bird",
+		golden:    "output/mapkubeapis-no-release.txt",
+		rels:      rels(),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestMapKubeAPIsCompletion(t *testing.T) {
+	checkReleaseCompletion(t, "mapkubeapis", false)
+}
@@ -191,6 +191,24 @@ func TestSetAppVersion(t *testing.T) {
 	}
 }
 
+func TestPackageExcludesUnitTests(t *testing.T) {
+	dir := t.TempDir()
+	cmd := fmt.Sprintf("package testdata/testcharts/chart-with-unittests --destination=%s", dir)
+	if _, output, err := executeActionCommand(cmd); err != nil {
+		t.Logf("Output: %s", output)
+		t.Fatal(err)
+	}
+	ch, err := loader.Load(filepath.Join(dir, "chart-with-unittests-0.1.0.tgz"))
+	if err != nil {
+		t.Fatalf("unexpected error loading packaged chart: %v", err)
+	}
+	for _, f := range ch.Files {
+		if strings.HasPrefix(f.Name, "tests/") {
+			t.Errorf("expected the unit tests not to be packaged, found %s", f.Name)
+		}
+	}
+}
+
 func TestPackageFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "package", true)
 	checkFileCompletion(t, "package mypath", true) // Multiple paths can be given
@@ -0,0 +1,152 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartdocs"
+)
+
+// readmeTemplateName is the name of the template of the README of a chart,
+// in the chart directory.
+const readmeTemplateName = "README.md.gotmpl"
+
+const readmeDesc = `
+This command generates the README of charts from their metadata and values.
+
+The README lists the version, maintainers, dependencies and Kubernetes version
+of the chart from Chart.yaml, and a table of the values of values.yaml, with
+their type, default and description. The description of a value is the
+comment above its key, or at the end of its line:
+
+    image:
+      # The image repository
+      repository: nginx
+      tag: "" # Defaults to the appVersion of the chart
+
+The README is the output of a Go template, with the functions of Sprig, and
+'escape' and 'code' to format the cells of Markdown tables. Its data are the
+'.Chart' metadata and the '.Values', each with a '.Key', '.Type', '.Default'
+and '.Description'. The template is that of '--template', else the
+README.md.gotmpl file of the chart, else the default template.
+
+With '--check', the READMEs are not written, and the command fails if one of
+them is out of date, e.g. in continuous integration.
+`
+
+func newReadmeCmd(out io.Writer) *cobra.Command {
+	var templateFile string
+	var outputFile string
+	var check bool
+
+	cmd := &cobra.Command{
+		Use:   "readme [CHART...]",
+		Short: "generate the README of charts from their metadata and values",
+		Long:  readmeDesc,
+		ValidArgsFunction: func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			paths := []string{"."}
+			if len(args) > 0 {
+				paths = args
+			}
+			var tmpl string
+			if templateFile != "" {
+				data, err := os.ReadFile(templateFile)
+				if err != nil {
+					return err
+				}
+				tmpl = string(data)
+			}
+
+			outdated := 0
+			for _, path := range paths {
+				file := filepath.Join(path, outputFile)
+				readme, err := chartReadme(path, tmpl)
+				if err != nil {
+					return err
+				}
+				if check {
+					current, err := os.ReadFile(file)
+					if err != nil && !os.IsNotExist(err) {
+						return err
+					}
+					if bytes.Equal(current, readme) {
+						fmt.Fprintf(out, "%s is up to date\n", file)
+					} else {
+						fmt.Fprintf(out, "%s is out of date\n", file)
+						outdated++
+					}
+					continue
+				}
+				if err := os.WriteFile(file, readme, 0644); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Wrote %s\n", file)
+			}
+			if outdated > 0 {
+				return errors.Errorf("%d README(s) out of date: run 'helm readme' to update them", outdated)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&templateFile, "template", "", "the Go template of the READMEs, instead of the README.md.gotmpl file of the charts")
+	f.StringVar(&outputFile, "output-file", "README.md", "the file of the README, relative to the chart directory")
+	f.BoolVar(&check, "check", false, "check that the READMEs are up to date instead of writing them")
+
+	return cmd
+}
+
+// chartReadme returns the README of the chart at path, from tmpl, or from the
+// template of the chart or the default template if tmpl is empty.
+func chartReadme(path, tmpl string) ([]byte, error) {
+	if fi, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, errors.Errorf("%s is not a chart directory", path)
+	}
+	c, err := loader.LoadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == "" {
+		tmpl = chartdocs.DefaultTemplate
+		data, err := os.ReadFile(filepath.Join(path, readmeTemplateName))
+		if err == nil {
+			tmpl = string(data)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if err := chartdocs.Render(&buf, c, tmpl); err != nil {
+		return nil, errors.Wrapf(err, "chart %s", path)
+	}
+	return buf.Bytes(), nil
+}
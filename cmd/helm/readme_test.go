@@ -0,0 +1,91 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/internal/test/ensure"
+)
+
+func TestReadmeCmd(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "check an up-to-date README",
+		cmd:    "readme testdata/testcharts/chart-with-readme --check",
+		golden: "output/readme-check.txt",
+	}, {
+		name:      "check an out-of-date README",
+		cmd:       "readme testdata/testcharts/chart-with-readme testdata/testcharts/alpine --check",
+		golden:    "output/readme-check-out-of-date.txt",
+		wantError: true,
+	}, {
+		name:      "generate the README of a packaged chart",
+		cmd:       "readme testdata/testcharts/compressedchart-0.1.0.tgz --check",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestReadmeCmdWrite(t *testing.T) {
+	ensure.HelmHome(t)
+	defer testChdir(t, t.TempDir())()
+
+	if _, _, err := executeActionCommand("create hello"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := executeActionCommand("readme hello"); err != nil {
+		t.Fatal(err)
+	}
+	readme, err := os.ReadFile(filepath.Join("hello", "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(readme), "| image.tag | string | `\"\"` | Overrides the image tag whose default is the chart appVersion. |\n") {
+		t.Errorf("expected the values of the chart in the README, got\n%s", readme)
+	}
+	if _, _, err := executeActionCommand("readme hello --check"); err != nil {
+		t.Errorf("expected the README to be up to date: %s", err)
+	}
+
+	// The template of the chart replaces the default one.
+	if err := os.WriteFile(filepath.Join("hello", "README.md.gotmpl"), []byte("# {{ .Chart.Name }} {{ .Chart.Version }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, out, err := executeActionCommand("readme hello --check"); err == nil || !strings.Contains(out, "is out of date") {
+		t.Errorf("expected the README to be out of date, got %q, %v", out, err)
+	}
+	if _, _, err := executeActionCommand("readme hello --output-file DOCS.md"); err != nil {
+		t.Fatal(err)
+	}
+	if docs, err := os.ReadFile(filepath.Join("hello", "DOCS.md")); err != nil || string(docs) != "# hello 0.1.0\n" {
+		t.Errorf("unexpected docs %q, %v", docs, err)
+	}
+
+	// The template of the flag replaces that of the chart.
+	if err := os.WriteFile("template", []byte("{{ range .Values }}{{ .Key }}\n{{ end }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := executeActionCommand("readme hello --template template --output-file KEYS"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := os.ReadFile(filepath.Join("hello", "KEYS")); err != nil || !strings.HasPrefix(string(keys), "replicaCount\nimage.repository\n") {
+		t.Errorf("unexpected keys %q, %v", keys, err)
+	}
+}
@@ -167,7 +167,9 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 		newPullCmd(actionConfig, out),
 		newShowCmd(actionConfig, out),
 		newLintCmd(out),
+		newUnitTestCmd(out),
 		newPackageCmd(actionConfig, out),
+		newReadmeCmd(out),
 		newRepoCmd(out),
 		newSearchCmd(out),
 		newVerifyCmd(out),
@@ -177,13 +179,16 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 		newHistoryCmd(actionConfig, out),
 		newInstallCmd(actionConfig, out),
 		newListCmd(actionConfig, out),
+		newMapKubeAPIsCmd(actionConfig, out),
 		newReleaseTestCmd(actionConfig, out),
 		newRollbackCmd(actionConfig, out),
 		newStatusCmd(actionConfig, out),
 		newTemplateCmd(actionConfig, out),
+		newConsoleCmd(actionConfig, out),
 		newUninstallCmd(actionConfig, out),
 		newUpgradeCmd(actionConfig, out),
 
+		newCapabilitiesCmd(actionConfig, out),
 		newCompletionCmd(out),
 		newEnvCmd(out),
 		newPluginCmd(out),
@@ -26,16 +26,22 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"helm.sh/helm/v3/pkg/release"
 
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/kubeschema"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/unittest"
 )
 
 const templateDesc = `
@@ -44,6 +50,19 @@ Render chart templates locally and display the output.
 Any values that would normally be looked up or retrieved in-cluster will be
 faked locally. Additionally, none of the server-side testing of chart validity
 (e.g. whether an API is supported) is done.
+
+With '--snapshot', the rendered manifests of a chart directory are compared
+with snapshots instead of being displayed. There is a snapshot with the default
+values of the chart, and one for each values file in its 'ci' directory ending
+in '-values.yaml', which are applied before the values given with '--values'
+and '--set'. Resources are sorted by kind, namespace and name, and their fields
+are sorted, so that only changes to the rendered resources are reported. The
+snapshots are stored in 'tests/__snapshot__/template' and taken the first time;
+'--update-snapshots' replaces them by the rendered manifests.
+
+With '--validate-schema', the rendered resources are validated offline against
+the schemas of their kinds, as 'helm lint --validate-schema' does, and the
+unknown fields and values of the wrong type are reported after the output.
 `
 
 func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -55,6 +74,13 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	var kubeVersion string
 	var extraAPIs []string
 	var showFiles []string
+	var lookupFixtures string
+	var profile bool
+	var profileOutput string
+	var previousReleaseFile string
+	var snapshot bool
+	var updateSnapshots bool
+	var schemaOpts schemaValidationOptions
 
 	cmd := &cobra.Command{
 		Use:   "template [NAME] [CHART]",
@@ -64,7 +90,7 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return compInstall(args, toComplete, client)
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if kubeVersion != "" {
 				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
 				if err != nil {
@@ -73,6 +99,28 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				client.KubeVersion = parsedKubeVersion
 			}
 
+			validator, err := schemaOpts.validator(client.KubeVersion, client.CapabilitiesProfile)
+			if err != nil {
+				return err
+			}
+
+			if lookupFixtures != "" {
+				provider, err := engine.LoadLookupFixtures(lookupFixtures)
+				if err != nil {
+					return err
+				}
+				cfg.LookupClientProvider = provider
+			}
+
+			if previousReleaseFile != "" {
+				previous, err := chartutil.ReadPreviousReleaseFile(previousReleaseFile)
+				if err != nil {
+					return err
+				}
+				client.PreviousRelease = previous
+				client.IsUpgrade = true
+			}
+
 			registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
 				client.InsecureSkipTLSverify, client.PlainHTTP)
 			if err != nil {
@@ -92,8 +140,20 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			client.ClientOnly = !validate
 			client.APIVersions = chartutil.VersionSet(extraAPIs)
 			client.IncludeCRDs = includeCrds
+			if snapshot || updateSnapshots {
+				return runTemplateSnapshots(args, client, valueOpts, skipTests, updateSnapshots, out)
+			}
+			if profile || profileOutput != "" {
+				client.RenderProfile = engine.NewRenderProfile()
+			}
 			rel, err := runInstall(args, client, valueOpts, out)
 
+			if client.RenderProfile != nil {
+				if perr := writeRenderProfile(cmd.ErrOrStderr(), client.RenderProfile, profile, profileOutput); perr != nil {
+					return perr
+				}
+			}
+
 			if err != nil && !settings.Debug {
 				if rel != nil {
 					return fmt.Errorf("%w\n\nUse --debug flag to render out invalid YAML", err)
@@ -185,6 +245,9 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				}
 			}
 
+			if err == nil && rel != nil && validator != nil {
+				return validateReleaseSchemas(validator, rel, !client.DisableHooks, skipTests)
+			}
 			return err
 		},
 	}
@@ -197,14 +260,177 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&includeCrds, "include-crds", false, "include CRDs in the templated output")
 	f.BoolVar(&skipTests, "skip-tests", false, "skip tests from templated output")
 	f.BoolVar(&client.IsUpgrade, "is-upgrade", false, "set .Release.IsUpgrade instead of .Release.IsInstall")
+	f.StringVar(&previousReleaseFile, "previous-release", "", "YAML file describing the release being upgraded (revision, chartVersion, appVersion, values and computedValues), available to templates as .Release.Previous. Implies --is-upgrade")
 	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for Capabilities.KubeVersion")
 	f.StringSliceVarP(&extraAPIs, "api-versions", "a", []string{}, "Kubernetes api versions used for Capabilities.APIVersions")
 	f.BoolVar(&client.UseReleaseName, "release-name", false, "use release name in the output-dir path.")
+	f.BoolVar(&profile, "profile", false, "print the time spent executing each template, the most frequent include and tpl calls and the largest outputs to stderr")
+	f.StringVar(&profileOutput, "profile-output", "", "write a Chrome trace (chrome://tracing, Perfetto) of the template executions to the given file")
+	f.BoolVar(&snapshot, "snapshot", false, "compare the rendered manifests of the chart directory, with its default values and each values file in its 'ci' directory, with their snapshots")
+	f.BoolVar(&updateSnapshots, "update-snapshots", false, "replace the snapshots of --snapshot by the rendered manifests. Implies --snapshot")
+	f.StringVar(&lookupFixtures, "lookup-fixtures", "", "directory of Kubernetes manifests returned by the 'lookup' function instead of querying the cluster")
+	addSchemaValidationFlags(f, &schemaOpts)
 	bindPostRenderFlag(cmd, &client.PostRenderer)
 
 	return cmd
 }
 
+// validateReleaseSchemas validates the rendered resources of a release, and
+// of its hooks if hooks is set, against the schemas of the validator and
+// those of the CustomResourceDefinitions of its chart.
+func validateReleaseSchemas(validator *kubeschema.Validator, rel *release.Release, hooks, skipTests bool) error {
+	validator = validator.Copy()
+	if err := validator.AddChartCRDs(rel.Chart); err != nil {
+		return err
+	}
+	var manifest strings.Builder
+	fmt.Fprintln(&manifest, rel.Manifest)
+	if hooks {
+		for _, h := range rel.Hooks {
+			if !skipTests || !isTestHook(h) {
+				fmt.Fprintf(&manifest, "---\n# Source: %s\n%s\n", h.Path, h.Manifest)
+			}
+		}
+	}
+
+	errs := validator.ValidateManifest(manifest.String())
+	if len(errs) == 0 {
+		return nil
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "the rendered resources do not match the schemas of their kinds (%d error(s)):", len(errs))
+	for _, err := range errs {
+		fmt.Fprintf(&msg, "\n  %s", err)
+	}
+	return errors.New(msg.String())
+}
+
+// runTemplateSnapshots renders a chart directory with each of its snapshot
+// values files and compares the manifests with the snapshots.
+func runTemplateSnapshots(args []string, client *action.Install, valueOpts *values.Options, skipTests, update bool, out io.Writer) error {
+	_, chartRef, err := client.NameAndChart(args)
+	if err != nil {
+		return err
+	}
+	chartPath, err := client.ChartPathOptions.LocateChart(chartRef, settings)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(chartPath); err != nil || !fi.IsDir() {
+		return fmt.Errorf("--snapshot requires a chart directory, got %s", chartRef)
+	}
+	snapshots, err := unittest.TemplateSnapshots(chartPath)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, s := range snapshots {
+		opts := *valueOpts
+		if s.ValuesFile != "" {
+			opts.ValueFiles = append([]string{s.ValuesFile}, valueOpts.ValueFiles...)
+		}
+		rel, err := runInstall(args, client, &opts, io.Discard)
+		if err != nil {
+			return errors.Wrapf(err, "failed to render the %s snapshot", s.Name)
+		}
+
+		var manifest strings.Builder
+		fmt.Fprintln(&manifest, rel.Manifest)
+		if !client.DisableHooks {
+			for _, h := range rel.Hooks {
+				if !skipTests || !isTestHook(h) {
+					fmt.Fprintf(&manifest, "---\n%s\n", h.Manifest)
+				}
+			}
+		}
+
+		diff, written, err := s.Check(manifest.String(), update)
+		if err != nil {
+			return err
+		}
+		switch {
+		case written:
+			fmt.Fprintf(out, "%s: wrote %s\n", s.Name, s.Path)
+		case diff != "":
+			changed++
+			fmt.Fprintf(out, "%s: the rendered manifest differs from the snapshot\n%s", s.Name, diff)
+		default:
+			fmt.Fprintf(out, "%s: ok\n", s.Name)
+		}
+	}
+	if changed > 0 {
+		return fmt.Errorf("%d of %d snapshots differ from the rendered manifests; use --update-snapshots to accept the changes", changed, len(snapshots))
+	}
+	return nil
+}
+
+// profileTableRows is the maximum number of rows of each render profile table.
+const profileTableRows = 20
+
+// writeRenderProfile prints the render profile as tables to out, and writes it
+// as a Chrome trace to traceFile if given.
+func writeRenderProfile(out io.Writer, profile *engine.RenderProfile, table bool, traceFile string) error {
+	if traceFile != "" {
+		f, err := os.Create(traceFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := profile.WriteChromeTrace(f); err != nil {
+			return err
+		}
+	}
+	if !table {
+		return nil
+	}
+
+	stats := profile.Stats()
+	tbl := uitable.New()
+	tbl.AddRow("KIND", "TEMPLATE", "CALLS", "TOTAL", "SELF", "OUTPUT BYTES")
+	for i, s := range stats {
+		if i == profileTableRows {
+			break
+		}
+		tbl.AddRow(s.Kind, s.Name, s.Calls, s.Total.Round(time.Microsecond), s.Self.Round(time.Microsecond), s.OutputBytes)
+	}
+	fmt.Fprintf(out, "%s\n\n", tbl)
+
+	calls := profile.Calls()
+	edges := make([]engine.ProfileCall, 0, len(calls))
+	for c := range calls {
+		edges = append(edges, c)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if calls[edges[i]] != calls[edges[j]] {
+			return calls[edges[i]] > calls[edges[j]]
+		}
+		if edges[i].Caller != edges[j].Caller {
+			return edges[i].Caller < edges[j].Caller
+		}
+		return edges[i].Callee < edges[j].Callee
+	})
+	if len(edges) > 0 {
+		tbl = uitable.New()
+		tbl.AddRow("CALLER", "CALLEE", "CALLS")
+		for i, c := range edges {
+			if i == profileTableRows {
+				break
+			}
+			tbl.AddRow(c.Caller, c.Callee, calls[c])
+		}
+		fmt.Fprintf(out, "%s\n\n", tbl)
+	}
+
+	tbl = uitable.New()
+	tbl.AddRow("KIND", "TEMPLATE", "LARGEST OUTPUT BYTES")
+	for _, s := range profile.LargestOutputs(profileTableRows) {
+		tbl.AddRow(s.Kind, s.Name, s.MaxOutputBytes)
+	}
+	fmt.Fprintf(out, "%s\n", tbl)
+	return nil
+}
+
 func isTestHook(h *release.Hook) bool {
 	for _, e := range h.Events {
 		if e == release.HookTest {
@@ -17,8 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -81,6 +84,46 @@ func TestTemplateCmd(t *testing.T) {
 			cmd:    fmt.Sprintf("template --kube-version 1.16.0 '%s'", chartPath),
 			golden: "output/template-with-kube-version.txt",
 		},
+		{
+			name:   "check capabilities file",
+			cmd:    fmt.Sprintf("template --capabilities-file testdata/capabilities.yaml '%s'", chartPath),
+			golden: "output/template-with-capabilities-file.txt",
+		},
+		{
+			name:   "check built-in capabilities profile with kube version",
+			cmd:    fmt.Sprintf("template --capabilities-file kubernetes-1.28 --kube-version 1.28.5 '%s'", chartPath),
+			golden: "output/template-with-capabilities-profile.txt",
+		},
+		{
+			name:      "check unknown capabilities profile",
+			cmd:       fmt.Sprintf("template --capabilities-file kubernetes-0.1 '%s'", chartPath),
+			wantError: true,
+		},
+		{
+			name:   "check lookup fixtures",
+			cmd:    "template testdata/testcharts/chart-with-lookup --lookup-fixtures testdata/lookup-fixtures",
+			golden: "output/template-with-lookup-fixtures.txt",
+		},
+		{
+			name:   "check lookup without fixtures",
+			cmd:    "template testdata/testcharts/chart-with-lookup",
+			golden: "output/template-with-lookup.txt",
+		},
+		{
+			name:   "check starlark renderer",
+			cmd:    "template testdata/testcharts/chart-with-starlark",
+			golden: "output/template-with-starlark.txt",
+		},
+		{
+			name:   "check previous release on install",
+			cmd:    "template testdata/testcharts/chart-with-previous-release",
+			golden: "output/template-previous-release-install.txt",
+		},
+		{
+			name:   "check previous release",
+			cmd:    "template testdata/testcharts/chart-with-previous-release --previous-release testdata/previous-release.yaml",
+			golden: "output/template-previous-release.txt",
+		},
 		{
 			name:   "check kube api versions",
 			cmd:    fmt.Sprintf("template --api-versions helm.k8s.io/test '%s'", chartPath),
@@ -116,6 +159,12 @@ func TestTemplateCmd(t *testing.T) {
 			// don't accidentally get the expected result.
 			repeat: 10,
 		},
+		{
+			name:      "check template output limit",
+			cmd:       fmt.Sprintf("template '%s' --max-template-output-bytes 64", chartPath),
+			wantError: true,
+			golden:    "output/template-output-limit.txt",
+		},
 		{
 			name:      "chart with template with invalid yaml",
 			cmd:       fmt.Sprintf("template '%s'", "testdata/testcharts/chart-with-template-with-invalid-yaml"),
@@ -165,6 +214,89 @@ func TestTemplateCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestTemplateProfile(t *testing.T) {
+	traceFile := filepath.Join(t.TempDir(), "trace.json")
+	_, out, err := executeActionCommand(fmt.Sprintf("template '%s' --profile --profile-output %s", chartPath, traceFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, header := range []string{"KIND", "TOTAL", "LARGEST OUTPUT BYTES", "subchart/templates/service.yaml"} {
+		if !strings.Contains(out, header) {
+			t.Errorf("Expected profile output to contain %q, got:\n%s", header, out)
+		}
+	}
+
+	data, err := os.ReadFile(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []struct {
+			Name string `json:"name"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(data, &trace); err != nil {
+		t.Fatalf("Expected a JSON trace: %s", err)
+	}
+	if len(trace.TraceEvents) == 0 {
+		t.Error("Expected trace events")
+	}
+}
+
+func TestTemplateSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":                "apiVersion: v2\nname: snap\nversion: 0.1.0\n",
+		"values.yaml":               "replicas: 1\n",
+		"ci/production-values.yaml": "replicas: 3\n",
+		"templates/deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\nspec:\n  replicas: {{ .Values.replicas }}\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The snapshots are taken the first time.
+	_, out, err := executeActionCommand("template --snapshot " + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"default", "production"} {
+		if !strings.Contains(out, fmt.Sprintf("%s: wrote %s", name, filepath.Join(dir, "tests", "__snapshot__", "template", name+".yaml"))) {
+			t.Errorf("Expected the %s snapshot to be written, got:\n%s", name, out)
+		}
+	}
+
+	_, out, err = executeActionCommand("template --snapshot " + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "default: ok\nproduction: ok\n" {
+		t.Errorf("Expected the snapshots to match, got:\n%s", out)
+	}
+
+	// Changes are reported resource by resource.
+	_, out, err = executeActionCommand("template --snapshot --set replicas=5 " + dir)
+	if err == nil || !strings.Contains(err.Error(), "2 of 2 snapshots differ") {
+		t.Errorf("Expected the snapshots to differ, got %v", err)
+	}
+	expected := "production: the rendered manifest differs from the snapshot\nDeployment/release-name: changed\n--- snapshot\n+++ rendered\n"
+	if !strings.Contains(out, expected) || !strings.Contains(out, "-  replicas: 3\n+  replicas: 5\n") {
+		t.Errorf("Expected a diff of the production snapshot, got:\n%s", out)
+	}
+
+	if _, _, err := executeActionCommand("template --update-snapshots --set replicas=5 " + dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := executeActionCommand("template --snapshot --set replicas=5 " + dir); err != nil {
+		t.Errorf("Expected the updated snapshots to match, got %v", err)
+	}
+}
+
 func TestTemplateVersionCompletion(t *testing.T) {
 	repoFile := "testdata/helmhome/helm/repositories.yaml"
 	repoCache := "testdata/helmhome/helm/repository"
@@ -201,3 +333,18 @@ func TestTemplateFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "template myname", true)
 	checkFileCompletion(t, "template myname mychart", false)
 }
+
+func TestTemplateValidateSchema(t *testing.T) {
+	chartPath := "testdata/testcharts/chart-with-schema-errors"
+	tests := []cmdTestCase{{
+		name:      "check the rendered resources against their schemas",
+		cmd:       fmt.Sprintf("template '%s' --validate-schema", chartPath),
+		golden:    "output/template-validate-schema.txt",
+		wantError: true,
+	}, {
+		name:      "check the rendered resources against a missing schema location",
+		cmd:       fmt.Sprintf("template '%s' --schema-location testdata/does-not-exist", chartPath),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
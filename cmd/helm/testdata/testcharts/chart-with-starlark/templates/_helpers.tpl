@@ -0,0 +1,3 @@
+{{- define "chart-with-starlark.fullname" -}}
+{{ .Release.Name }}-{{ .Chart.Name }}
+{{- end }}
@@ -0,0 +1,129 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/unittest"
+)
+
+const unittestDesc = `
+This command runs the unit tests of charts. The tests render the templates
+locally, as 'helm template' does, and assert on the resulting documents.
+
+Test suites are the files ending in '_test.yaml' in the 'tests' directory of a
+chart. 'helm package' leaves the 'tests' directory of the charts with test
+suites or snapshots out of their package:
+
+    suite: deployment
+    templates:
+      - deployment.yaml
+    kubeVersions: ["1.27.0", "1.29.0"]
+    tests:
+      - it: sets the number of replicas
+        set:
+          replicaCount: 3
+        asserts:
+          - kind: Deployment
+            equal:
+              path: spec.replicas
+              value: 3
+          - hasDocuments:
+              count: 1
+          - matchSnapshot: {}
+
+Each test sets its values ('values' files and 'set'), release ('name',
+'namespace', 'revision' and 'upgrade') and capabilities ('kubeVersion' and
+'apiVersions'), overriding those of the suite, and is run once for each
+Kubernetes version of the suite.
+
+Assertions apply to the documents selected by 'template', 'kind' and 'name',
+all of them by default, and are negated by 'not: true'. They are:
+
+- equal: the value at a JSONPath ('path') equals 'value'
+- matchRegex: the string at a JSONPath ('path') matches 'pattern'
+- exists: there is a value at a JSONPath ('path')
+- hasDocuments: the number of documents is 'count'
+- failedTemplate: rendering fails, with an error containing 'errorMessage'
+  or matching 'errorPattern' if given
+- matchSnapshot: the documents are the same as in the snapshot, which is
+  taken in 'tests/__snapshot__' the first time the assertion is checked
+
+The results are written in the TAP format, or as a JUnit XML report with
+'--output junit'.
+`
+
+func newUnitTestCmd(out io.Writer) *cobra.Command {
+	client := action.NewUnitTest()
+	var outfmt string
+
+	cmd := &cobra.Command{
+		Use:   "unittest [CHART...]",
+		Short: "run the unit tests of charts",
+		Long:  unittestDesc,
+		RunE: func(_ *cobra.Command, args []string) error {
+			paths := []string{"."}
+			if len(args) > 0 {
+				paths = args
+			}
+			write := unittest.WriteTAP
+			switch outfmt {
+			case "tap":
+			case "junit":
+				write = unittest.WriteJUnit
+			default:
+				return errors.Errorf("invalid output format %q: allowed values are tap and junit", outfmt)
+			}
+
+			results, err := client.Run(paths)
+			if err != nil {
+				return err
+			}
+			if err := write(out, results); err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, r := range results {
+				if !r.Passed() {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d tests failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&outfmt, "output", "o", "tap", "the format of the results (tap, junit)")
+	f.StringSliceVar(&client.KubeVersions, "kube-versions", nil, "run the tests for these Kubernetes versions instead of those of the suites")
+	f.BoolVarP(&client.UpdateSnapshots, "update-snapshots", "u", false, "replace the snapshots by the rendered documents")
+
+	cmd.RegisterFlagCompletionFunc("output", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"junit", "tap"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
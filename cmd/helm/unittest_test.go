@@ -0,0 +1,41 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestUnitTestCmd(t *testing.T) {
+	chartPath := "testdata/testcharts/chart-with-unittests"
+	tests := []cmdTestCase{{
+		name:   "run the unit tests of a chart",
+		cmd:    "unittest " + chartPath,
+		golden: "output/unittest.txt",
+	}, {
+		name:      "run the unit tests of a chart for other kube versions",
+		cmd:       "unittest --kube-versions 1.25.0 " + chartPath,
+		golden:    "output/unittest-failed.txt",
+		wantError: true,
+	}, {
+		name:      "unittest with an invalid output format",
+		cmd:       "unittest --output json " + chartPath,
+		golden:    "output/unittest-invalid-output.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
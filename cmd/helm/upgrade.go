@@ -77,6 +77,32 @@ or '--set' flags. Priority is given to new values.
 The --dry-run flag will output all generated chart manifests, including Secrets
 which can contain sensitive values. To hide Kubernetes Secrets use the
 --hide-secret flag. Please carefully consider how and when these flags are used.
+
+The --policy flag evaluates policies, CEL expressions, against the rendered
+objects, after post-rendering and before they are applied, with the variables
+'object', 'namespace' and 'release'. Policies of the release scope see the list
+'objects' instead. A violated policy of the deny mode fails the operation,
+listing the violations, and those of the warn mode are reported as warnings:
+
+    policies:
+      - name: team-label
+        match:
+          kinds: [Deployment]
+        validation: "'app.kubernetes.io/team' in object.metadata.?labels.orValue({})"
+      - name: no-load-balancers
+        mode: warn
+        match:
+          kinds: [Service]
+          namespaces: [internal]
+        validation: "object.spec.?type.orValue('') != 'LoadBalancer'"
+
+The --prune flag deletes the resources of the release which are no longer in
+its chart, such as those of a template removed or renamed since an earlier
+revision. The resources pruned are those of the kinds of any revision of the
+release, labelled 'app.kubernetes.io/managed-by: Helm' and annotated with the
+name and namespace of the release. Resources annotated with
+'helm.sh/resource-policy: keep' are not deleted. With --dry-run, the resources
+which would be pruned are listed instead.
 `
 
 func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -99,7 +125,12 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client.PolicyWarnings = cmd.ErrOrStderr()
+			client.PruneOutput = out
+			if outfmt != output.Table {
+				client.PruneOutput = cmd.ErrOrStderr()
+			}
 			client.Namespace = settings.Namespace()
 
 			registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
@@ -142,6 +173,8 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					instClient.Namespace = client.Namespace
 					instClient.Atomic = client.Atomic
 					instClient.PostRenderer = client.PostRenderer
+					instClient.Policies = client.Policies
+					instClient.PolicyWarnings = client.PolicyWarnings
 					instClient.DisableOpenAPIValidation = client.DisableOpenAPIValidation
 					instClient.SubNotes = client.SubNotes
 					instClient.HideNotes = client.HideNotes
@@ -149,6 +182,9 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					instClient.DependencyUpdate = client.DependencyUpdate
 					instClient.Labels = client.Labels
 					instClient.EnableDNS = client.EnableDNS
+					instClient.StrictTemplateNames = client.StrictTemplateNames
+					instClient.RenderLimits = client.RenderLimits
+					instClient.RenderTimeout = client.RenderTimeout
 					instClient.HideSecret = client.HideSecret
 
 					if isReleaseUninstalled(versions) {
@@ -271,6 +307,7 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.Atomic, "atomic", false, "if set, upgrade process rolls back changes made in case of failed upgrade. The --wait flag will be set automatically if --atomic is used")
 	f.IntVar(&client.MaxHistory, "history-max", settings.MaxHistory, "limit the maximum number of revisions saved per release. Use 0 for no limit")
+	f.BoolVar(&client.Prune, "prune", false, "delete the resources of the release which are no longer in its manifest, unless annotated with 'helm.sh/resource-policy: keep'")
 	f.BoolVar(&client.CleanupOnFail, "cleanup-on-fail", false, "allow deletion of new resources created in this upgrade when upgrade fails")
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in upgrade output. Does not affect presence in chart metadata")
@@ -278,10 +315,14 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&client.Description, "description", "", "add a custom description")
 	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing before installing the chart")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
+	f.BoolVar(&client.StrictTemplateNames, "strict-template-names", false, "fail when a named template is defined more than once with different contents")
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 	addValueOptionsFlags(f, valueOpts)
+	f.StringSliceVar(&client.Regenerate, "regenerate", []string{}, "generate the values of the given keys of the 'generated' template function again instead of reusing those of the current release (can specify multiple or separate keys with commas: key1,key2)")
+	addRenderLimitFlags(f, &client.RenderLimits, &client.RenderTimeout)
 	bindOutputFlag(cmd, &outfmt)
 	bindPostRenderFlag(cmd, &client.PostRenderer)
+	bindPolicyFlag(cmd, &client.Policies)
 
 	err := cmd.RegisterFlagCompletionFunc("version", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) != 2 {
@@ -18,12 +18,14 @@ package action
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -42,14 +44,14 @@ import (
 	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
-	"helm.sh/helm/v3/pkg/time"
+	helmtime "helm.sh/helm/v3/pkg/time"
 )
 
 // Timestamper is a function capable of producing a timestamp.Timestamper.
 //
 // By default, this is a time.Time function from the Helm time package. This can
 // be overridden for testing though, so that timestamps are predictable.
-var Timestamper = time.Now
+var Timestamper = helmtime.Now
 
 var (
 	// errMissingChart indicates that a chart was not provided.
@@ -94,16 +96,51 @@ type Configuration struct {
 	// Capabilities describes the capabilities of the Kubernetes cluster.
 	Capabilities *chartutil.Capabilities
 
+	// LookupClientProvider, when set, backs the 'lookup' template function
+	// instead of the cluster, e.g. with offline fixtures.
+	LookupClientProvider engine.ClientProvider
+
+	// TemplateFuncs, when set, provides additional template functions.
+	TemplateFuncs *engine.FuncRegistry
+
+	// TemplateRenderers provides additional renderers for the templates of
+	// the charts selecting them in their Chart.yaml.
+	TemplateRenderers map[string]engine.Renderer
+
 	Log func(string, ...interface{})
 }
 
+// renderOptions are the options of renderResources.
+type renderOptions struct {
+	releaseName string
+	// outputDir, when set, is the directory the manifests are written to
+	// instead of the returned buffer.
+	outputDir      string
+	subNotes       bool
+	useReleaseName bool
+	includeCRDs    bool
+	postRenderer   postrender.PostRenderer
+	// interactWithRemote allows 'lookup' to query the cluster.
+	interactWithRemote  bool
+	enableDNS           bool
+	strictTemplateNames bool
+	hideSecret          bool
+	limits              engine.RenderLimits
+	timeout             time.Duration
+	profile             *engine.RenderProfile
+	sourceMap           *engine.SourceMap
+	generated           *engine.GeneratedValues
+	// trace, when set, records the rendered templates to trace errors.
+	trace *sourceTracer
+}
+
 // renderResources renders the templates in a chart
 //
 // TODO: This function is badly in need of a refactor.
 // TODO: As part of the refactor the duplicate code in cmd/helm/template.go should be removed
 //
 //	This code has to do with writing files to disk.
-func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Values, releaseName, outputDir string, subNotes, useReleaseName, includeCrds bool, pr postrender.PostRenderer, interactWithRemote, enableDNS, hideSecret bool) ([]*release.Hook, *bytes.Buffer, string, error) {
+func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Values, opts renderOptions) ([]*release.Hook, *bytes.Buffer, string, error) {
 	hs := []*release.Hook{}
 	b := bytes.NewBuffer(nil)
 
@@ -118,29 +155,11 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 		}
 	}
 
-	var files map[string]string
-	var err2 error
-
-	// A `helm template` should not talk to the remote cluster. However, commands with the flag
-	//`--dry-run` with the value of `false`, `none`, or `server` should try to interact with the cluster.
-	// It may break in interesting and exotic ways because other data (e.g. discovery) is mocked.
-	if interactWithRemote && cfg.RESTClientGetter != nil {
-		restConfig, err := cfg.RESTClientGetter.ToRESTConfig()
-		if err != nil {
-			return hs, b, "", err
-		}
-		e := engine.New(restConfig)
-		e.EnableDNS = enableDNS
-		files, err2 = e.Render(ch, values)
-	} else {
-		var e engine.Engine
-		e.EnableDNS = enableDNS
-		files, err2 = e.Render(ch, values)
-	}
-
-	if err2 != nil {
-		return hs, b, "", err2
+	files, err := cfg.renderTemplates(ch, values, opts)
+	if err != nil {
+		return hs, b, "", err
 	}
+	opts.trace.record(files)
 
 	// NOTES.txt gets rendered like all the other files, but because it's not a hook nor a resource,
 	// pull it out of here into a separate file so that we can actually use the output of the rendered
@@ -150,7 +169,7 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	var notesBuffer bytes.Buffer
 	for k, v := range files {
 		if strings.HasSuffix(k, notesFileSuffix) {
-			if subNotes || (k == path.Join(ch.Name(), "templates", notesFileSuffix)) {
+			if opts.subNotes || (k == path.Join(ch.Name(), "templates", notesFileSuffix)) {
 				// If buffer contains data, add newline before adding more
 				if notesBuffer.Len() > 0 {
 					notesBuffer.WriteString("\n")
@@ -184,12 +203,12 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	// Aggregate all valid manifests into one big doc.
 	fileWritten := make(map[string]bool)
 
-	if includeCrds {
+	if opts.includeCRDs {
 		for _, crd := range ch.CRDObjects() {
-			if outputDir == "" {
+			if opts.outputDir == "" {
 				fmt.Fprintf(b, "---\n# Source: %s\n%s\n", crd.Filename, string(crd.File.Data[:]))
 			} else {
-				err = writeToFile(outputDir, crd.Filename, string(crd.File.Data[:]), fileWritten[crd.Filename])
+				err = writeToFile(opts.outputDir, crd.Filename, string(crd.File.Data[:]), fileWritten[crd.Filename])
 				if err != nil {
 					return hs, b, "", err
 				}
@@ -199,16 +218,16 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	}
 
 	for _, m := range manifests {
-		if outputDir == "" {
-			if hideSecret && m.Head.Kind == "Secret" && m.Head.Version == "v1" {
+		if opts.outputDir == "" {
+			if opts.hideSecret && m.Head.Kind == "Secret" && m.Head.Version == "v1" {
 				fmt.Fprintf(b, "---\n# Source: %s\n# HIDDEN: The Secret output has been suppressed\n", m.Name)
 			} else {
 				fmt.Fprintf(b, "---\n# Source: %s\n%s\n", m.Name, m.Content)
 			}
 		} else {
-			newDir := outputDir
-			if useReleaseName {
-				newDir = filepath.Join(outputDir, releaseName)
+			newDir := opts.outputDir
+			if opts.useReleaseName {
+				newDir = filepath.Join(opts.outputDir, opts.releaseName)
 			}
 			// NOTE: We do not have to worry about the post-renderer because
 			// output dir is only used by `helm template`. In the next major
@@ -222,8 +241,8 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 		}
 	}
 
-	if pr != nil {
-		b, err = pr.Run(b)
+	if opts.postRenderer != nil {
+		b, err = opts.postRenderer.Run(b)
 		if err != nil {
 			return hs, b, notes, errors.Wrap(err, "error while running post render on files")
 		}
@@ -232,6 +251,40 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	return hs, b, notes, nil
 }
 
+// renderTemplates renders the templates of a chart with the engine
+// configured by the options.
+func (cfg *Configuration) renderTemplates(ch *chart.Chart, values chartutil.Values, opts renderOptions) (map[string]string, error) {
+	// A `helm template` should not talk to the remote cluster. However, commands with the flag
+	//`--dry-run` with the value of `false`, `none`, or `server` should try to interact with the cluster.
+	// It may break in interesting and exotic ways because other data (e.g. discovery) is mocked.
+	var e engine.Engine
+	if cfg.LookupClientProvider != nil {
+		e = engine.NewWithClientProvider(cfg.LookupClientProvider)
+	} else if opts.interactWithRemote && cfg.RESTClientGetter != nil {
+		restConfig, err := cfg.RESTClientGetter.ToRESTConfig()
+		if err != nil {
+			return nil, err
+		}
+		e = engine.New(restConfig)
+	}
+	e.EnableDNS = opts.enableDNS
+	e.StrictTemplateNames = opts.strictTemplateNames
+	e.Profile = opts.profile
+	e.SourceMap = opts.sourceMap
+	e.Funcs = cfg.TemplateFuncs
+	e.Renderers = cfg.TemplateRenderers
+	e.Limits = opts.limits
+	e.Generated = opts.generated
+
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+	return e.RenderContext(ctx, ch, values)
+}
+
 // RESTClientGetter gets the rest client
 type RESTClientGetter interface {
 	ToRESTConfig() (*rest.Config, error)
@@ -298,7 +351,7 @@ func (cfg *Configuration) KubernetesClientSet() (kubernetes.Interface, error) {
 //
 // If the configuration has a Timestamper on it, that will be used.
 // Otherwise, this will use time.Now().
-func (cfg *Configuration) Now() time.Time {
+func (cfg *Configuration) Now() helmtime.Time {
 	return Timestamper()
 }
 
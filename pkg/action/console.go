@@ -0,0 +1,87 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+// Console is the action for evaluating template expressions against a chart.
+//
+// It provides the implementation of 'helm console'. The chart is loaded as
+// 'helm template' does, without contacting a cluster.
+type Console struct {
+	cfg *Configuration
+
+	ReleaseName string
+	Namespace   string
+	KubeVersion *chartutil.KubeVersion
+	APIVersions chartutil.VersionSet
+	// CapabilitiesProfile, when set, replaces the default capabilities, e.g.
+	// with a profile captured by 'helm capabilities dump'.
+	CapabilitiesProfile *chartutil.Capabilities
+}
+
+// NewConsole creates a new Console object with the given configuration.
+func NewConsole(cfg *Configuration) *Console {
+	return &Console{
+		cfg:         cfg,
+		ReleaseName: "release-name",
+	}
+}
+
+// Run loads the chart at chartPath and returns a console evaluating
+// expressions against it, with the given values merged into those of the chart.
+func (c *Console) Run(chartPath string, vals map[string]interface{}) (*engine.Console, error) {
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(chrt, vals); err != nil {
+		return nil, err
+	}
+
+	caps := chartutil.DefaultCapabilities.Copy()
+	if c.CapabilitiesProfile != nil {
+		caps = c.CapabilitiesProfile.Copy()
+	}
+	if c.KubeVersion != nil {
+		caps.KubeVersion = *c.KubeVersion
+	}
+	caps.APIVersions = append(caps.APIVersions, c.APIVersions...)
+
+	options := chartutil.ReleaseOptions{
+		Name:      c.ReleaseName,
+		Namespace: c.Namespace,
+		Revision:  1,
+		IsInstall: true,
+	}
+	valuesToRender, err := chartutil.ToRenderValues(chrt, vals, options, caps)
+	if err != nil {
+		return nil, err
+	}
+
+	var e engine.Engine
+	if c.cfg.LookupClientProvider != nil {
+		e = engine.NewWithClientProvider(c.cfg.LookupClientProvider)
+	}
+	e.Funcs = c.cfg.TemplateFuncs
+	e.Renderers = c.cfg.TemplateRenderers
+	return e.NewConsole(chrt, valuesToRender)
+}
@@ -43,9 +43,11 @@ import (
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/policy"
 	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
@@ -102,14 +104,33 @@ type Install struct {
 	// (for things like templating). These are ignored if ClientOnly is false
 	KubeVersion *chartutil.KubeVersion
 	APIVersions chartutil.VersionSet
+	// CapabilitiesProfile replaces the capabilities discovered from the cluster,
+	// e.g. with a profile captured by 'helm capabilities dump'. It can only be
+	// used in client only or dry-run mode.
+	CapabilitiesProfile *chartutil.Capabilities
 	// Used by helm template to render charts with .Release.IsUpgrade. Ignored if Dry-Run is false
 	IsUpgrade bool
+	// Used by helm template to render charts with .Release.Previous. Ignored if Dry-Run is false
+	PreviousRelease *chartutil.PreviousRelease
 	// Enable DNS lookups when rendering templates
 	EnableDNS bool
+	// Fail when a named template is defined more than once with different contents
+	StrictTemplateNames bool
+	// RenderLimits bounds the output of the templates
+	RenderLimits engine.RenderLimits
+	// RenderTimeout, when set, is the maximum time spent rendering the templates
+	RenderTimeout time.Duration
+	// RenderProfile, when set, records the time spent rendering each template
+	RenderProfile *engine.RenderProfile
 	// Used by helm template to add the release as part of OutputDir path
 	// OutputDir/<ReleaseName>
 	UseReleaseName bool
 	PostRenderer   postrender.PostRenderer
+	// Policies, when set, are evaluated against the rendered objects before
+	// they are created. A violated policy of the deny mode fails the install.
+	Policies *policy.Set
+	// PolicyWarnings receives the violations of the policies of the warn mode.
+	PolicyWarnings io.Writer
 	// Lock to control raceconditions when the process receives a SIGTERM
 	Lock sync.Mutex
 }
@@ -239,6 +260,11 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		return nil, errors.New("Hiding Kubernetes secrets requires a dry-run mode")
 	}
 
+	// A capabilities profile does not describe the cluster being installed to.
+	if !i.isDryRun() && i.CapabilitiesProfile != nil {
+		return nil, errors.New("a capabilities profile can only be used in dry-run mode")
+	}
+
 	if err := i.availableName(); err != nil {
 		return nil, err
 	}
@@ -255,6 +281,11 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 	// Pre-install anything in the crd/ directory. We do this before Helm
 	// contacts the upstream server and builds the capabilities object.
 	if crds := chrt.CRDObjects(); !i.ClientOnly && !i.SkipCRDs && len(crds) > 0 {
+		// The policies are checked before anything is created.
+		crdRelease := &release.Release{Name: i.ReleaseName, Namespace: i.Namespace, Chart: chrt, Version: 1, Labels: i.Labels}
+		if err := checkCRDPolicies(i.Policies, crdRelease, crds, i.PolicyWarnings); err != nil {
+			return nil, err
+		}
 		// On dry run, bail here
 		if i.isDryRun() {
 			i.cfg.Log("WARNING: This chart or one of its subcharts contains CRDs. Rendering may fail or contain inaccuracies.")
@@ -267,6 +298,9 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		// Add mock objects in here so it doesn't use Kube API server
 		// NOTE(bacongobbler): used for `helm template`
 		i.cfg.Capabilities = chartutil.DefaultCapabilities.Copy()
+		if i.CapabilitiesProfile != nil {
+			i.cfg.Capabilities = i.CapabilitiesProfile.Copy()
+		}
 		if i.KubeVersion != nil {
 			i.cfg.Capabilities.KubeVersion = *i.KubeVersion
 		}
@@ -280,6 +314,10 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		i.cfg.Log("API Version list given outside of client only mode, this list will be ignored")
 	}
 
+	if !i.ClientOnly && i.CapabilitiesProfile != nil {
+		i.cfg.Capabilities = i.CapabilitiesProfile.Copy()
+	}
+
 	// Make sure if Atomic is set, that wait is set as well. This makes it so
 	// the user doesn't have to specify both
 	i.Wait = i.Wait || i.Atomic
@@ -298,6 +336,9 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		IsInstall: !isUpgrade,
 		IsUpgrade: isUpgrade,
 	}
+	if i.isDryRun() {
+		options.Previous = i.PreviousRelease
+	}
 	valuesToRender, err := chartutil.ToRenderValues(chrt, vals, options, caps)
 	if err != nil {
 		return nil, err
@@ -309,14 +350,35 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 
 	rel := i.createRelease(chrt, vals, i.Labels)
 
+	opts := renderOptions{
+		releaseName:         i.ReleaseName,
+		outputDir:           i.OutputDir,
+		subNotes:            i.SubNotes,
+		useReleaseName:      i.UseReleaseName,
+		includeCRDs:         i.IncludeCRDs,
+		postRenderer:        i.PostRenderer,
+		interactWithRemote:  interactWithRemote,
+		enableDNS:           i.EnableDNS,
+		strictTemplateNames: i.StrictTemplateNames,
+		hideSecret:          i.HideSecret,
+		limits:              i.RenderLimits,
+		timeout:             i.RenderTimeout,
+		profile:             i.RenderProfile,
+		generated:           engine.NewGeneratedValues(nil),
+	}
+	trace := &sourceTracer{cfg: i.cfg, chart: chrt, values: valuesToRender, opts: opts}
+	opts.trace = trace
+
 	var manifestDoc *bytes.Buffer
-	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.IncludeCRDs, i.PostRenderer, interactWithRemote, i.EnableDNS, i.HideSecret)
+	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, opts)
 	// Even for errors, attach this if available
 	if manifestDoc != nil {
 		rel.Manifest = manifestDoc.String()
 	}
+	rel.Generated = opts.generated.Values()
 	// Check error from render
 	if err != nil {
+		err = trace.annotate(err)
 		rel.SetStatus(release.StatusFailed, fmt.Sprintf("failed to render resource: %s", err.Error()))
 		// Return a release with partial data so that the client can show debugging information.
 		return rel, err
@@ -328,7 +390,7 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 	var toBeAdopted kube.ResourceList
 	resources, err := i.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), !i.DisableOpenAPIValidation)
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to build kubernetes objects from release manifest")
+		return nil, trace.annotate(errors.Wrap(err, "unable to build kubernetes objects from release manifest"))
 	}
 
 	// It is safe to use "force" here because these are resources currently rendered by the chart.
@@ -350,6 +412,10 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		}
 	}
 
+	if err := checkPolicies(i.Policies, rel, isUpgrade, i.PolicyWarnings); err != nil {
+		return nil, err
+	}
+
 	// Bail out here if it is a dry run
 	if i.isDryRun() {
 		rel.Info.Description = "Dry run complete"
@@ -400,7 +466,7 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 
 	rel, err = i.performInstallCtx(ctx, rel, toBeAdopted, resources)
 	if err != nil {
-		rel, err = i.failRelease(rel, err)
+		rel, err = i.failRelease(rel, trace.annotate(err))
 	}
 	return rel, err
 }
@@ -18,6 +18,7 @@ package action
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -35,6 +36,7 @@ import (
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/policy"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	helmtime "helm.sh/helm/v3/pkg/time"
@@ -400,6 +402,34 @@ func TestInstallRelease_KubeVersion(t *testing.T) {
 	is.Contains(err.Error(), "chart requires kubeVersion")
 }
 
+func TestInstallRelease_CapabilitiesProfile(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.DryRun = true
+	instAction.CapabilitiesProfile = &chartutil.Capabilities{
+		KubeVersion: chartutil.KubeVersion{Version: "v1.29.0", Major: "1", Minor: "29"},
+		APIVersions: chartutil.VersionSet{"v1", "example.com/v1"},
+	}
+
+	mockChart := buildChart(withKube(">=1.29.0"))
+	mockChart.Templates = append(mockChart.Templates, &chart.File{
+		Name: "templates/capabilities",
+		Data: []byte(`kubeVersion: {{ .Capabilities.KubeVersion }}
+example: {{ .Capabilities.APIVersions.Has "example.com/v1" }}`),
+	})
+
+	res, err := instAction.Run(mockChart, map[string]interface{}{})
+	is.NoError(err)
+	is.Contains(res.Manifest, "kubeVersion: v1.29.0\nexample: true")
+
+	// A profile does not describe the cluster, so it cannot be used for a real install.
+	instAction.DryRun = false
+	instAction.ReleaseName = "should-fail"
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.Error(err)
+	is.Contains(err.Error(), "dry-run")
+}
+
 func TestInstallRelease_Wait(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -798,3 +828,55 @@ func TestInstallWithSystemLabels(t *testing.T) {
 
 	is.Equal(fmt.Errorf("user suplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels()), err)
 }
+
+func TestInstallRelease_Policies(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	policies, err := policy.NewSet(
+		policy.Policy{Name: "no-test-cm", Match: policy.Match{Kinds: []string{"ConfigMap"}}, Validation: "object.metadata.name != 'test-cm'"},
+		policy.Policy{Name: "labels", Mode: policy.Warn, Match: policy.Match{Kinds: []string{"ConfigMap"}}, Validation: "has(object.metadata.labels)"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings strings.Builder
+	instAction.Policies = policies
+	instAction.PolicyWarnings = &warnings
+
+	_, err = instAction.Run(buildChart(), nil)
+	var denied *policy.DeniedError
+	if !errors.As(err, &denied) || len(denied.Violations) != 1 || denied.Violations[0].Object != "ConfigMap/test-cm" {
+		t.Fatalf("expected the install to be denied by no-test-cm, got %v", err)
+	}
+	is.Equal("WARNING: policy labels: ConfigMap/test-cm (hello/templates/hooks): failed has(object.metadata.labels)\n", warnings.String())
+
+	_, err = instAction.cfg.Releases.Get(instAction.ReleaseName, 1)
+	is.Error(err, "expected no release to be stored")
+}
+
+func TestInstallRelease_CRDPolicies(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	var created strings.Builder
+	instAction.cfg.KubeClient = &kubefake.FailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: &created}}
+	policies, err := policy.NewSet(
+		policy.Policy{Name: "no-widgets", Match: policy.Match{Kinds: []string{"CustomResourceDefinition"}}, Validation: "object.spec.names.kind != 'Widget'"},
+		policy.Policy{Name: "small-releases", Scope: policy.ReleaseScope, Validation: "size(objects) > 1"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instAction.Policies = policies
+
+	ch := buildChart()
+	ch.Files = append(ch.Files, &chart.File{
+		Name: "crds/widgets.yaml",
+		Data: []byte("apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: widgets.example.com\nspec:\n  names:\n    kind: Widget\n"),
+	})
+	_, err = instAction.Run(ch, nil)
+	var denied *policy.DeniedError
+	if !errors.As(err, &denied) || len(denied.Violations) != 1 || denied.Violations[0].String() != "no-widgets: CustomResourceDefinition/widgets.example.com (hello/crds/widgets.yaml): failed object.spec.names.kind != 'Widget'" {
+		t.Fatalf("expected the install to be denied by no-widgets, got %v", err)
+	}
+	is.Empty(created.String(), "expected the CRDs not to be created")
+}
@@ -17,13 +17,17 @@ limitations under the License.
 package action
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/kubeschema"
 	"helm.sh/helm/v3/pkg/lint"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
@@ -37,6 +41,27 @@ type Lint struct {
 	WithSubcharts bool
 	Quiet         bool
 	KubeVersion   *chartutil.KubeVersion
+	// CapabilitiesProfile, when set, replaces the default capabilities used to render the templates.
+	CapabilitiesProfile *chartutil.Capabilities
+	// LookupClientProvider, when set, backs the 'lookup' template function.
+	LookupClientProvider engine.ClientProvider
+	// TemplateFuncs, when set, provides additional template functions.
+	TemplateFuncs *engine.FuncRegistry
+	// TemplateRenderers provides additional renderers for the templates of
+	// the charts selecting them in their Chart.yaml.
+	TemplateRenderers map[string]engine.Renderer
+	// RenderLimits bounds the output of the templates.
+	RenderLimits engine.RenderLimits
+	// RenderTimeout, when set, is the maximum time spent rendering the templates.
+	RenderTimeout time.Duration
+	// Config, when set, configures the lint rules instead of the lint
+	// configuration files of the charts.
+	Config *support.Config
+	// SchemaValidator, when set, validates the rendered resources against
+	// its schemas and those of the CustomResourceDefinitions of the charts.
+	SchemaValidator *kubeschema.Validator
+	// Packs are the packs of opt-in lint rules to enable, e.g. "policy".
+	Packs []string
 }
 
 // LintResult is the result of Lint
@@ -44,6 +69,9 @@ type LintResult struct {
 	TotalChartsLinted int
 	Messages          []support.Message
 	Errors            []error
+	// Charts are the results of linting each chart, with the path of the
+	// chart, in the order of the paths given to Run.
+	Charts []lint.ChartReport
 }
 
 // NewLint creates a new Lint object with the given configuration.
@@ -59,11 +87,13 @@ func (l *Lint) Run(paths []string, vals map[string]interface{}) *LintResult {
 	}
 	result := &LintResult{}
 	for _, path := range paths {
-		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion)
+		linter, err := lintChart(path, vals, l.Namespace, l.lintOptions()...)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
+			result.Charts = append(result.Charts, lint.ChartReport{Chart: path, Err: err})
 			continue
 		}
+		result.Charts = append(result.Charts, lint.ChartReport{Chart: path, Messages: linter.Messages})
 
 		result.Messages = append(result.Messages, linter.Messages...)
 		result.TotalChartsLinted++
@@ -76,6 +106,80 @@ func (l *Lint) Run(paths []string, vals map[string]interface{}) *LintResult {
 	return result
 }
 
+// LintCombination is a combination of values and Kubernetes version a chart
+// is linted with by RunMatrix.
+type LintCombination struct {
+	// Name identifies the combination in the messages, e.g. "prod@1.27".
+	Name   string
+	Values map[string]interface{}
+	// KubeVersion, when set, replaces that of the Lint action.
+	KubeVersion *chartutil.KubeVersion
+	// SchemaValidator, when set, replaces that of the Lint action, e.g. to
+	// validate against the schemas of KubeVersion.
+	SchemaValidator *kubeschema.Validator
+}
+
+// RunMatrix lints each chart with each combination of values and Kubernetes
+// version. The messages of a chart reported by several combinations are
+// reported once, in the order they are first reported, with the names of
+// the combinations reporting them as their Combinations.
+func (l *Lint) RunMatrix(paths []string, combinations []LintCombination) *LintResult {
+	lowestTolerance := support.ErrorSev
+	if l.Strict {
+		lowestTolerance = support.WarningSev
+	}
+	result := &LintResult{}
+	for _, path := range paths {
+		report := lint.ChartReport{Chart: path}
+		index := map[string]int{}
+		for _, c := range combinations {
+			client := *l
+			if c.KubeVersion != nil {
+				client.KubeVersion = c.KubeVersion
+			}
+			if c.SchemaValidator != nil {
+				client.SchemaValidator = c.SchemaValidator
+			}
+			linter, err := lintChart(path, c.Values, client.Namespace, client.lintOptions()...)
+			if err != nil {
+				// The chart cannot be loaded whatever the combination.
+				report = lint.ChartReport{Chart: path, Err: err}
+				break
+			}
+			for _, msg := range linter.Messages {
+				key := matrixMessageKey(msg)
+				if i, ok := index[key]; ok {
+					report.Messages[i].Combinations = append(report.Messages[i].Combinations, c.Name)
+					continue
+				}
+				index[key] = len(report.Messages)
+				msg.Combinations = []string{c.Name}
+				report.Messages = append(report.Messages, msg)
+			}
+		}
+		result.Charts = append(result.Charts, report)
+		if report.Err != nil {
+			result.Errors = append(result.Errors, report.Err)
+			continue
+		}
+
+		result.Messages = append(result.Messages, report.Messages...)
+		result.TotalChartsLinted++
+		for _, msg := range report.Messages {
+			if msg.Severity >= lowestTolerance {
+				result.Errors = append(result.Errors, msg.Err)
+			}
+		}
+	}
+	return result
+}
+
+// matrixMessageKey identifies the messages reported by several combinations
+// of a lint matrix as the same finding.
+func matrixMessageKey(msg support.Message) string {
+	return fmt.Sprintf("%d\x00%s\x00%s\x00%d\x00%d\x00%s", msg.Severity, msg.RuleID, msg.Path, msg.Line, msg.Column, msg.Err)
+}
+
 // HasWarningsOrErrors checks is LintResult has any warnings or errors
 func HasWarningsOrErrors(result *LintResult) bool {
 	for _, msg := range result.Messages {
@@ -86,7 +190,37 @@ func HasWarningsOrErrors(result *LintResult) bool {
 	return len(result.Errors) > 0
 }
 
-func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) (support.Linter, error) {
+// lintOptions returns the linter options corresponding to the configuration of l.
+func (l *Lint) lintOptions() []lint.LinterOption {
+	options := []lint.LinterOption{lint.WithKubeVersion(l.KubeVersion)}
+	if l.CapabilitiesProfile != nil {
+		options = append(options, lint.WithCapabilities(l.CapabilitiesProfile))
+	}
+	if l.LookupClientProvider != nil {
+		options = append(options, lint.WithLookupClientProvider(l.LookupClientProvider))
+	}
+	if l.TemplateFuncs != nil {
+		options = append(options, lint.WithTemplateFuncs(l.TemplateFuncs))
+	}
+	if l.TemplateRenderers != nil {
+		options = append(options, lint.WithTemplateRenderers(l.TemplateRenderers))
+	}
+	if l.RenderLimits != (engine.RenderLimits{}) || l.RenderTimeout > 0 {
+		options = append(options, lint.WithRenderLimits(l.RenderLimits, l.RenderTimeout))
+	}
+	if l.Config != nil {
+		options = append(options, lint.WithConfig(l.Config))
+	}
+	if l.SchemaValidator != nil {
+		options = append(options, lint.WithSchemaValidator(l.SchemaValidator))
+	}
+	if len(l.Packs) > 0 {
+		options = append(options, lint.WithPacks(l.Packs...))
+	}
+	return options
+}
+
+func lintChart(path string, vals map[string]interface{}, namespace string, options ...lint.LinterOption) (support.Linter, error) {
 	var chartPath string
 	linter := support.Linter{}
 
@@ -125,5 +259,5 @@ func lintChart(path string, vals map[string]interface{}, namespace string, kubeV
 		return linter, errors.Wrap(err, "unable to check Chart.yaml file in chart")
 	}
 
-	return lint.AllWithKubeVersion(chartPath, vals, namespace, kubeVersion), nil
+	return lint.AllWithOptions(chartPath, vals, namespace, options...), nil
 }
@@ -17,7 +17,10 @@ limitations under the License.
 package action
 
 import (
+	"strings"
 	"testing"
+
+	"helm.sh/helm/v3/pkg/engine"
 )
 
 var (
@@ -27,6 +30,7 @@ var (
 	chart2MultipleChartLint = "testdata/charts/multiplecharts-lint-chart-2"
 	corruptedTgzChart       = "testdata/charts/corrupted-compressed-chart.tgz"
 	chartWithNoTemplatesDir = "testdata/charts/chart-with-no-templates-dir"
+	chartWithCustomFuncs    = "testdata/charts/chart-with-custom-funcs"
 )
 
 func TestLintChart(t *testing.T) {
@@ -77,7 +81,7 @@ func TestLintChart(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := lintChart(tt.chartPath, map[string]interface{}{}, namespace, nil)
+			_, err := lintChart(tt.chartPath, map[string]interface{}{}, namespace)
 			switch {
 			case err != nil && !tt.err:
 				t.Errorf("%s", err)
@@ -130,6 +134,20 @@ func TestLint_MultipleCharts(t *testing.T) {
 	}
 }
 
+func TestLint_ChartReports(t *testing.T) {
+	testCharts := []string{chartWithNoTemplatesDir, "non-existent-chart.tgz"}
+	result := NewLint().Run(testCharts, values)
+	if len(result.Charts) != 2 {
+		t.Fatalf("expected 2 chart reports, got %d", len(result.Charts))
+	}
+	if r := result.Charts[0]; r.Chart != chartWithNoTemplatesDir || r.Err != nil || len(r.Messages) != len(result.Messages) {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if r := result.Charts[1]; r.Chart != "non-existent-chart.tgz" || r.Err == nil {
+		t.Errorf("expected the error of the chart in its report, got %+v", r)
+	}
+}
+
 func TestLint_EmptyResultErrors(t *testing.T) {
 	testCharts := []string{chart2MultipleChartLint}
 	testLint := NewLint()
@@ -157,3 +175,68 @@ func TestLint_ChartWithWarnings(t *testing.T) {
 		}
 	})
 }
+
+func TestLint_ChartWithCustomFuncs(t *testing.T) {
+	testCharts := []string{chartWithCustomFuncs}
+
+	t.Run("should fail without the custom functions", func(t *testing.T) {
+		testLint := NewLint()
+		if result := testLint.Run(testCharts, values); len(result.Errors) == 0 {
+			t.Error("expected an error for the undefined function, got none")
+		}
+	})
+
+	t.Run("should pass with the custom functions", func(t *testing.T) {
+		funcs := engine.NewFuncRegistry()
+		funcs.MustRegister("owner", func(team string) string { return "team-" + team })
+		testLint := NewLint()
+		testLint.TemplateFuncs = funcs
+		if result := testLint.Run(testCharts, values); len(result.Errors) > 0 {
+			t.Error(result.Errors)
+		}
+	})
+}
+
+func TestLint_RunMatrix(t *testing.T) {
+	combinations := []LintCombination{
+		{Name: "default", Values: values},
+		{Name: "negative", Values: map[string]interface{}{"age": -1}},
+		{Name: "negative-again", Values: map[string]interface{}{"age": -1}},
+	}
+	result := NewLint().RunMatrix([]string{"testdata/charts/chart-with-schema", "non-existent-chart.tgz"}, combinations)
+	if result.TotalChartsLinted != 1 || len(result.Charts) != 2 {
+		t.Fatalf("expected 1 chart linted and 2 chart reports, got %d and %d", result.TotalChartsLinted, len(result.Charts))
+	}
+	if len(result.Errors) != 3 {
+		t.Fatalf("expected the schema errors of the values and of the templates, and the error of the missing chart, got %v", result.Errors)
+	}
+
+	combinationsOf := map[string]string{}
+	for _, msg := range result.Messages {
+		if _, ok := combinationsOf[msg.Err.Error()]; ok {
+			t.Errorf("expected the message %q to be reported once", msg.Err)
+		}
+		combinationsOf[msg.Err.Error()] = strings.Join(msg.Combinations, ",")
+	}
+	for text, want := range map[string]string{
+		"icon is recommended":                     "default,negative,negative-again",
+		"age: Must be greater than or equal to 0": "negative,negative-again",
+	} {
+		found := false
+		for err, got := range combinationsOf {
+			if strings.Contains(err, text) {
+				found = true
+				if got != want {
+					t.Errorf("expected %q to be reported by %s, got %s", text, want, got)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("expected a message %q, got %v", text, result.Messages)
+		}
+	}
+
+	if r := result.Charts[1]; r.Err == nil || len(r.Messages) != 0 {
+		t.Errorf("expected the error of the missing chart in its report, got %+v", r)
+	}
+}
@@ -0,0 +1,311 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// APIMapping maps a removed API version of a kind to the API version
+// replacing it.
+type APIMapping struct {
+	Kind                 string `json:"kind"`
+	DeprecatedAPIVersion string `json:"deprecatedAPIVersion"`
+	NewAPIVersion        string `json:"newAPIVersion"`
+	// RemovedInVersion, when set, is the version of Kubernetes the
+	// deprecated API version is removed in, e.g. "v1.25". The mapping then
+	// applies only from this version.
+	RemovedInVersion string `json:"removedInVersion,omitempty"`
+}
+
+// apiMappingFile is the content of a file of API mappings.
+type apiMappingFile struct {
+	Mappings []APIMapping `json:"mappings"`
+}
+
+// DefaultAPIMappings returns the mappings of the APIs removed from
+// Kubernetes with a replacement of the same kind, as known to Helm.
+func DefaultAPIMappings() []APIMapping {
+	var mappings []APIMapping
+	for _, api := range rules.RemovedAPIs() {
+		mappings = append(mappings, APIMapping{
+			Kind:                 api.Deprecated.Kind,
+			DeprecatedAPIVersion: api.Deprecated.GroupVersion().String(),
+			NewAPIVersion:        api.Replacement.GroupVersion().String(),
+			RemovedInVersion:     fmt.Sprintf("v%d.%d", api.RemovedMajor, api.RemovedMinor),
+		})
+	}
+	return mappings
+}
+
+// LoadAPIMappings reads a file of API mappings, e.g.
+//
+//	mappings:
+//	  - kind: PodDisruptionBudget
+//	    deprecatedAPIVersion: policy/v1beta1
+//	    newAPIVersion: policy/v1
+//	    removedInVersion: v1.25
+func LoadAPIMappings(path string) ([]APIMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file apiMappingFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, errors.Wrapf(err, "reading API mapping file %s", path)
+	}
+	for i, m := range file.Mappings {
+		if m.Kind == "" || m.DeprecatedAPIVersion == "" || m.NewAPIVersion == "" {
+			return nil, errors.Errorf("API mapping file %s: mapping %d: kind, deprecatedAPIVersion and newAPIVersion are required", path, i+1)
+		}
+		if m.RemovedInVersion != "" {
+			if _, err := semver.NewVersion(m.RemovedInVersion); err != nil {
+				return nil, errors.Wrapf(err, "API mapping file %s: mapping %d: invalid removedInVersion %q", path, i+1, m.RemovedInVersion)
+			}
+		}
+	}
+	return file.Mappings, nil
+}
+
+// APIChange is the change of the API version of a resource of a release.
+type APIChange struct {
+	Kind     string
+	Name     string
+	From, To string
+}
+
+func (c APIChange) String() string {
+	return fmt.Sprintf("%s/%s: %s -> %s", c.Kind, c.Name, c.From, c.To)
+}
+
+// MapKubeAPIs is the action for rewriting the API versions removed from
+// Kubernetes in the manifest and the hooks of the last deployed revision of
+// a release, so that the release can be upgraded once the cluster no longer
+// serves them.
+//
+// It provides the implementation of 'helm mapkubeapis'.
+type MapKubeAPIs struct {
+	cfg *Configuration
+
+	// DryRun computes the changes without storing them.
+	DryRun bool
+	// Mappings, when set, replaces the default mappings of DefaultAPIMappings.
+	Mappings []APIMapping
+	// KubeVersion, when set, replaces the version of the cluster: the API
+	// versions removed in this version or before are mapped.
+	KubeVersion *chartutil.KubeVersion
+}
+
+// NewMapKubeAPIs creates a new MapKubeAPIs object with the given configuration.
+func NewMapKubeAPIs(cfg *Configuration) *MapKubeAPIs {
+	return &MapKubeAPIs{
+		cfg: cfg,
+	}
+}
+
+// Run rewrites the API versions of the manifest and the hooks of the last
+// deployed revision of the release. The revision is updated in place, with
+// the changes appended to its description, unless DryRun is set or nothing
+// changes. It returns the
+// revision and the changes.
+func (m *MapKubeAPIs) Run(name string) (*release.Release, []APIChange, error) {
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return nil, nil, errors.Errorf("mapkubeapis: Release name is invalid: %s", name)
+	}
+
+	kubeVersion := m.KubeVersion
+	if kubeVersion == nil {
+		caps, err := m.cfg.getCapabilities()
+		if err != nil {
+			return nil, nil, err
+		}
+		kubeVersion = &caps.KubeVersion
+	}
+	mappings := m.Mappings
+	if mappings == nil {
+		mappings = DefaultAPIMappings()
+	}
+	mappings, err := applicableAPIMappings(mappings, kubeVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rel, err := m.cfg.Releases.Deployed(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest, changes, err := mapManifestAPIs(rel.Manifest, mappings)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "mapping the APIs of release %s", name)
+	}
+	// Copy the release and its hooks, which some storage drivers share with
+	// their callers.
+	mapped := *rel
+	mapped.Manifest = manifest
+	mapped.Hooks = make([]*release.Hook, len(rel.Hooks))
+	for i, h := range rel.Hooks {
+		hookManifest, hookChanges, err := mapManifestAPIs(h.Manifest, mappings)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "mapping the APIs of hook %s of release %s", h.Name, name)
+		}
+		hook := *h
+		hook.Manifest = hookManifest
+		mapped.Hooks[i] = &hook
+		changes = append(changes, hookChanges...)
+	}
+	if len(changes) == 0 {
+		m.cfg.Log("no API of release %s to map", name)
+		return rel, nil, nil
+	}
+
+	info := *rel.Info
+	mapped.Info = &info
+	description := fmt.Sprintf("Mapped removed APIs: %s", describeAPIChanges(changes))
+	if info.Description != "" {
+		description = fmt.Sprintf("%s; %s", info.Description, description)
+	}
+	mapped.Info.Description = description
+	if m.DryRun {
+		m.cfg.Log("dry run for %s", name)
+		return &mapped, changes, nil
+	}
+	m.cfg.Log("updating the manifest of release %s, revision %d", name, rel.Version)
+	if err := m.cfg.Releases.Update(&mapped); err != nil {
+		return nil, nil, err
+	}
+	return &mapped, changes, nil
+}
+
+// applicableAPIMappings returns the mappings which apply to a version of
+// Kubernetes.
+func applicableAPIMappings(mappings []APIMapping, kubeVersion *chartutil.KubeVersion) ([]APIMapping, error) {
+	version, err := semver.NewVersion(kubeVersion.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid Kubernetes version %q", kubeVersion.Version)
+	}
+	var applicable []APIMapping
+	for _, mapping := range mappings {
+		if mapping.RemovedInVersion != "" {
+			removed, err := semver.NewVersion(mapping.RemovedInVersion)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid removedInVersion %q of %s %s", mapping.RemovedInVersion, mapping.DeprecatedAPIVersion, mapping.Kind)
+			}
+			if version.Major() < removed.Major() || version.Major() == removed.Major() && version.Minor() < removed.Minor() {
+				continue
+			}
+		}
+		applicable = append(applicable, mapping)
+	}
+	return applicable, nil
+}
+
+// topLevelAPIVersion matches the apiVersion line of a resource.
+var topLevelAPIVersion = regexp.MustCompile(`^apiVersion:\s*["']?([^"'\s#]+)["']?\s*(#.*)?$`)
+
+// mapManifestAPIs rewrites the apiVersion lines of the resources of a
+// manifest by the mappings, keeping the other lines as they are. A
+// replacement which is itself mapped is mapped in turn.
+func mapManifestAPIs(manifest string, mappings []APIMapping) (string, []APIChange, error) {
+	lines := strings.SplitAfter(manifest, "\n")
+	var changes []APIChange
+	start := 0
+	for end := 0; end <= len(lines); end++ {
+		if end < len(lines) && strings.TrimSpace(lines[end]) != "---" {
+			continue
+		}
+		change, err := mapResourceAPI(lines[start:end], mappings)
+		if err != nil {
+			return "", nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+		start = end + 1
+	}
+	return strings.Join(lines, ""), changes, nil
+}
+
+// mapResourceAPI rewrites the apiVersion line of the resource of the lines
+// of a document, if a mapping applies to it.
+func mapResourceAPI(lines []string, mappings []APIMapping) (*APIChange, error) {
+	var resource struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines, "")), &resource); err != nil {
+		return nil, err
+	}
+	to := resource.APIVersion
+	for range mappings {
+		mapped := false
+		for _, mapping := range mappings {
+			if mapping.Kind == resource.Kind && mapping.DeprecatedAPIVersion == to {
+				to = mapping.NewAPIVersion
+				mapped = true
+				break
+			}
+		}
+		if !mapped {
+			break
+		}
+	}
+	if to == resource.APIVersion {
+		return nil, nil
+	}
+
+	for i, line := range lines {
+		m := topLevelAPIVersion.FindStringSubmatchIndex(strings.TrimRight(line, "\r\n"))
+		if m == nil || line[m[2]:m[3]] != resource.APIVersion {
+			continue
+		}
+		lines[i] = line[:m[2]] + to + line[m[3]:]
+		return &APIChange{
+			Kind: resource.Kind,
+			Name: resource.Metadata.Name,
+			From: resource.APIVersion,
+			To:   to,
+		}, nil
+	}
+	return nil, errors.Errorf("no apiVersion line in %s %s", resource.Kind, resource.Metadata.Name)
+}
+
+// describeAPIChanges describes the API changes of a release, once for each
+// mapping.
+func describeAPIChanges(changes []APIChange) string {
+	var descriptions []string
+	for _, c := range changes {
+		d := fmt.Sprintf("%s %s to %s", c.Kind, c.From, c.To)
+		if !contains(descriptions, d) {
+			descriptions = append(descriptions, d)
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,178 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+var manifestWithRemovedAPIs = `---
+# Source: hello/templates/pdb.yaml
+apiVersion: policy/v1beta1  # removed in 1.25
+kind: PodDisruptionBudget
+metadata:
+  name: hello
+spec:
+  minAvailable: 1
+---
+# Source: hello/templates/hpa.yaml
+apiVersion: "autoscaling/v2beta2"
+kind: HorizontalPodAutoscaler
+metadata:
+  name: hello
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1beta2
+    kind: Deployment
+    name: hello
+  maxReplicas: 3
+---
+# Source: hello/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello
+`
+
+func mapKubeAPIsAction(t *testing.T) *MapKubeAPIs {
+	t.Helper()
+	config := actionConfigFixture(t)
+	rel := releaseStub()
+	rel.Manifest = manifestWithRemovedAPIs
+	// The manifest of the test hook of the stub is not valid YAML.
+	rel.Hooks[1].Manifest = "apiVersion: v1\nkind: Pod\nmetadata:\n  name: finding-nemo\n"
+	if err := config.Releases.Create(rel); err != nil {
+		t.Fatal(err)
+	}
+	return NewMapKubeAPIs(config)
+}
+
+func TestMapKubeAPIs(t *testing.T) {
+	is := assert.New(t)
+	mapAction := mapKubeAPIsAction(t)
+	mapAction.KubeVersion = &chartutil.KubeVersion{Version: "v1.25.0", Major: "1", Minor: "25"}
+
+	rel, changes, err := mapAction.Run("angry-panda")
+	is.NoError(err)
+	is.Equal([]APIChange{{Kind: "PodDisruptionBudget", Name: "hello", From: "policy/v1beta1", To: "policy/v1"}}, changes)
+	is.Contains(rel.Manifest, "\napiVersion: policy/v1  # removed in 1.25\nkind: PodDisruptionBudget\n")
+	is.Contains(rel.Manifest, "\napiVersion: \"autoscaling/v2beta2\"\n")
+	is.Equal("Named Release Stub; Mapped removed APIs: PodDisruptionBudget policy/v1beta1 to policy/v1", rel.Info.Description)
+
+	stored, err := mapAction.cfg.Releases.Get("angry-panda", 1)
+	is.NoError(err)
+	is.Equal(rel.Manifest, stored.Manifest)
+	is.Equal(rel.Info.Description, stored.Info.Description)
+
+	// The manifest is mapped already.
+	_, changes, err = mapAction.Run("angry-panda")
+	is.NoError(err)
+	is.Empty(changes)
+}
+
+func TestMapKubeAPIs_DryRun(t *testing.T) {
+	is := assert.New(t)
+	mapAction := mapKubeAPIsAction(t)
+	mapAction.KubeVersion = &chartutil.KubeVersion{Version: "v1.26.0", Major: "1", Minor: "26"}
+	mapAction.DryRun = true
+
+	rel, changes, err := mapAction.Run("angry-panda")
+	is.NoError(err)
+	is.Len(changes, 2)
+	is.Equal("HorizontalPodAutoscaler/hello: autoscaling/v2beta2 -> autoscaling/v2", changes[1].String())
+	// The apiVersion of the scale target is not that of a resource.
+	is.Contains(rel.Manifest, "\napiVersion: \"autoscaling/v2\"\n")
+	is.Contains(rel.Manifest, "    apiVersion: apps/v1beta2\n")
+
+	stored, err := mapAction.cfg.Releases.Get("angry-panda", 1)
+	is.NoError(err)
+	is.Equal(manifestWithRemovedAPIs, stored.Manifest)
+}
+
+func TestMapKubeAPIs_DeployedRevision(t *testing.T) {
+	is := assert.New(t)
+	mapAction := mapKubeAPIsAction(t)
+	mapAction.KubeVersion = &chartutil.KubeVersion{Version: "v1.25.0", Major: "1", Minor: "25"}
+	deployed, err := mapAction.cfg.Releases.Get("angry-panda", 1)
+	is.NoError(err)
+	deployed.Hooks[0].Manifest = "apiVersion: policy/v1beta1\nkind: PodDisruptionBudget\nmetadata:\n  name: hook\n"
+	failed := namedReleaseStub("angry-panda", release.StatusFailed)
+	failed.Version = 2
+	is.NoError(mapAction.cfg.Releases.Create(failed))
+
+	rel, changes, err := mapAction.Run("angry-panda")
+	is.NoError(err)
+	is.Equal(1, rel.Version)
+	is.Equal([]APIChange{
+		{Kind: "PodDisruptionBudget", Name: "hello", From: "policy/v1beta1", To: "policy/v1"},
+		{Kind: "PodDisruptionBudget", Name: "hook", From: "policy/v1beta1", To: "policy/v1"},
+	}, changes)
+	is.Equal("apiVersion: policy/v1\nkind: PodDisruptionBudget\nmetadata:\n  name: hook\n", rel.Hooks[0].Manifest)
+
+	stored, err := mapAction.cfg.Releases.Get("angry-panda", 1)
+	is.NoError(err)
+	is.Equal(rel.Hooks[0].Manifest, stored.Hooks[0].Manifest)
+	last, err := mapAction.cfg.Releases.Get("angry-panda", 2)
+	is.NoError(err)
+	is.Equal(failed.Manifest, last.Manifest)
+}
+
+func TestMapKubeAPIs_Mappings(t *testing.T) {
+	is := assert.New(t)
+	file := filepath.Join(t.TempDir(), "mappings.yaml")
+	is.NoError(os.WriteFile(file, []byte(`mappings:
+  - kind: Deployment
+    deprecatedAPIVersion: apps/v1
+    newAPIVersion: apps/v2
+  - kind: Deployment
+    deprecatedAPIVersion: apps/v2
+    newAPIVersion: apps/v3
+  - kind: PodDisruptionBudget
+    deprecatedAPIVersion: policy/v1beta1
+    newAPIVersion: policy/v1
+    removedInVersion: v1.99
+`), 0644))
+	mappings, err := LoadAPIMappings(file)
+	is.NoError(err)
+
+	mapAction := mapKubeAPIsAction(t)
+	mapAction.Mappings = mappings
+	_, changes, err := mapAction.Run("angry-panda")
+	is.NoError(err)
+	is.Equal([]APIChange{{Kind: "Deployment", Name: "hello", From: "apps/v1", To: "apps/v3"}}, changes)
+
+	is.NoError(os.WriteFile(file, []byte("mappings:\n  - kind: Deployment\n    newAPIVersion: apps/v2\n"), 0644))
+	_, err = LoadAPIMappings(file)
+	is.ErrorContains(err, "mapping 1: kind, deprecatedAPIVersion and newAPIVersion are required")
+}
+
+func TestDefaultAPIMappings(t *testing.T) {
+	expected := APIMapping{
+		Kind:                 "PodDisruptionBudget",
+		DeprecatedAPIVersion: "policy/v1beta1",
+		NewAPIVersion:        "policy/v1",
+		RemovedInVersion:     "v1.25",
+	}
+	assert.Contains(t, DefaultAPIMappings(), expected)
+}
@@ -20,15 +20,18 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strings"
 	"syscall"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/pkg/errors"
 	"golang.org/x/term"
 
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/unittest"
 )
 
 // Package is the action for packaging a chart.
@@ -79,6 +82,8 @@ func (p *Package) Run(path string, _ map[string]interface{}) (string, error) {
 		}
 	}
 
+	removeUnitTests(ch)
+
 	var dest string
 	if p.Destination == "." {
 		// Save to the current working directory.
@@ -103,6 +108,24 @@ func (p *Package) Run(path string, _ map[string]interface{}) (string, error) {
 	return name, err
 }
 
+// removeUnitTests removes the tests directory from the charts with unit
+// tests, which are only run against the chart sources. The tests directory of
+// the other charts is kept, as their templates may read its files.
+func removeUnitTests(ch *chart.Chart) {
+	if unittest.HasUnitTests(ch.Files) {
+		files := ch.Files[:0]
+		for _, f := range ch.Files {
+			if !strings.HasPrefix(f.Name, chartutil.UnitTestsDir+"/") {
+				files = append(files, f)
+			}
+		}
+		ch.Files = files
+	}
+	for _, dep := range ch.Dependencies() {
+		removeUnitTests(dep)
+	}
+}
+
 // validateVersion Verify that version is a Version, and error out if it is not.
 func validateVersion(ver string) error {
 	if _, err := semver.NewVersion(ver); err != nil {
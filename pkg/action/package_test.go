@@ -19,11 +19,13 @@ package action
 import (
 	"os"
 	"path"
+	"reflect"
 	"testing"
 
 	"github.com/Masterminds/semver/v3"
 
 	"helm.sh/helm/v3/internal/test/ensure"
+	"helm.sh/helm/v3/pkg/chart"
 )
 
 func TestPassphraseFileFetcher(t *testing.T) {
@@ -119,3 +121,36 @@ func TestValidateVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoveUnitTests(t *testing.T) {
+	files := func(names ...string) []*chart.File {
+		var files []*chart.File
+		for _, name := range names {
+			files = append(files, &chart.File{Name: name})
+		}
+		return files
+	}
+	names := func(ch *chart.Chart) []string {
+		var names []string
+		for _, f := range ch.Files {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	withSuites := &chart.Chart{Files: files("README.md", "tests/values-prod.yaml", "tests/deployment_test.yaml")}
+	withSnapshots := &chart.Chart{Files: files("tests/__snapshot__/template/default.yaml", "files/config.yaml")}
+	withoutUnitTests := &chart.Chart{Files: files("tests/data.txt", "files/tests/x_test.yaml")}
+	withSuites.AddDependency(withSnapshots, withoutUnitTests)
+
+	removeUnitTests(withSuites)
+	if got, want := names(withSuites), []string{"README.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the files %v, got %v", want, got)
+	}
+	if got, want := names(withSnapshots), []string{"files/config.yaml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the files %v of the subchart with snapshots, got %v", want, got)
+	}
+	if got, want := names(withoutUnitTests), []string{"tests/data.txt", "files/tests/x_test.yaml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the files %v of the subchart without unit tests, got %v", want, got)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/policy"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// checkPolicies evaluates policies against the rendered objects of a release.
+// It writes the violations of the policies of the warn mode to warnings, and
+// returns a *policy.DeniedError for those of the deny mode.
+func checkPolicies(policies *policy.Set, rel *release.Release, isUpgrade bool, warnings io.Writer) error {
+	if policies == nil {
+		return nil
+	}
+	report, err := policies.Evaluate(rel, isUpgrade)
+	return reportPolicies(report, err, warnings)
+}
+
+// checkCRDPolicies evaluates policies against the CRDs of the chart of a
+// release, before they are installed, as checkPolicies does.
+func checkCRDPolicies(policies *policy.Set, rel *release.Release, crds []chart.CRD, warnings io.Writer) error {
+	if policies == nil {
+		return nil
+	}
+	report, err := policies.EvaluateCRDs(rel, crds)
+	return reportPolicies(report, err, warnings)
+}
+
+func reportPolicies(report *policy.Report, err error, warnings io.Writer) error {
+	if err != nil {
+		return errors.Wrap(err, "unable to evaluate the policies")
+	}
+	if warnings != nil {
+		for _, v := range report.Warnings() {
+			fmt.Fprintf(warnings, "WARNING: policy %s\n", v)
+		}
+	}
+	return report.Err()
+}
@@ -0,0 +1,151 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// objectHead is the head of a manifest, with the fields identifying its object.
+type objectHead struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// manifestHeads returns the heads of the objects of a manifest. The documents
+// which are not objects are skipped.
+func manifestHeads(manifest string) []objectHead {
+	var heads []objectHead
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var head objectHead
+		if err := yaml.Unmarshal([]byte(doc), &head); err != nil || head.Kind == "" || head.Metadata.Name == "" {
+			continue
+		}
+		heads = append(heads, head)
+	}
+	return heads
+}
+
+// releaseHeads returns the heads of the objects of the manifest and of the
+// hooks of a release.
+func releaseHeads(rel *release.Release) []objectHead {
+	heads := manifestHeads(rel.Manifest)
+	for _, h := range rel.Hooks {
+		heads = append(heads, manifestHeads(h.Manifest)...)
+	}
+	return heads
+}
+
+// findOrphans returns the live objects owned by a release which are not in
+// its manifest or its hooks, and whose resource policy is not to keep them.
+//
+// The objects are those of the kinds of the objects of all the revisions of
+// the release, labelled as managed by Helm and annotated with the name and
+// namespace of the release.
+func findOrphans(cfg *Configuration, rel *release.Release) (kube.ResourceList, error) {
+	kubeClient, ok := cfg.KubeClient.(kube.InterfaceList)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceList")
+	}
+
+	history, err := cfg.Releases.History(rel.Name)
+	if err != nil {
+		return nil, err
+	}
+	kinds := map[schema.GroupVersionKind]bool{}
+	for _, r := range append(history, rel) {
+		for _, head := range releaseHeads(r) {
+			kinds[schema.FromAPIVersionAndKind(head.APIVersion, head.Kind)] = true
+		}
+	}
+	gvks := make([]schema.GroupVersionKind, 0, len(kinds))
+	for gvk := range kinds {
+		gvks = append(gvks, gvk)
+	}
+	sort.Slice(gvks, func(i, j int) bool { return gvks[i].String() < gvks[j].String() })
+
+	live, err := kubeClient.List(gvks, appManagedByLabel+"="+appManagedByHelm)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list the objects of the release")
+	}
+
+	// Objects without a namespace in the manifest are in the namespace of the
+	// release, unless they are cluster-scoped.
+	namespaced := map[string]bool{}
+	clusterScoped := map[string]bool{}
+	for _, head := range releaseHeads(rel) {
+		gk := schema.FromAPIVersionAndKind(head.APIVersion, head.Kind).GroupKind()
+		namespace := head.Metadata.Namespace
+		if namespace == "" {
+			namespace = rel.Namespace
+			clusterScoped[orphanKey(gk, "", head.Metadata.Name)] = true
+		}
+		namespaced[orphanKey(gk, namespace, head.Metadata.Name)] = true
+	}
+
+	var orphans kube.ResourceList
+	for _, info := range live {
+		gk := info.Object.GetObjectKind().GroupVersionKind().GroupKind()
+		key := orphanKey(gk, info.Namespace, info.Name)
+		if info.Namespace == "" && clusterScoped[key] || info.Namespace != "" && namespaced[key] {
+			continue
+		}
+		if checkOwnership(info.Object, rel.Name, rel.Namespace) != nil {
+			continue
+		}
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			return nil, err
+		}
+		if policy := accessor.GetAnnotations()[kube.ResourcePolicyAnno]; strings.ToLower(strings.TrimSpace(policy)) == kube.KeepPolicy {
+			cfg.Log("skipping %s: it has the %s resource policy", orphanName(info), kube.KeepPolicy)
+			continue
+		}
+		orphans = append(orphans, info)
+	}
+	return orphans, nil
+}
+
+func orphanKey(gk schema.GroupKind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", gk, namespace, name)
+}
+
+// orphanName returns the kind, and the namespace and name, of an object,
+// e.g. "Deployment default/web".
+func orphanName(info *resource.Info) string {
+	kind := info.Object.GetObjectKind().GroupVersionKind().Kind
+	if info.Namespace == "" {
+		return fmt.Sprintf("%s %s", kind, info.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", kind, info.Namespace, info.Name)
+}
@@ -141,6 +141,7 @@ func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Rele
 		Namespace: currentRelease.Namespace,
 		Chart:     previousRelease.Chart,
 		Config:    previousRelease.Config,
+		Generated: previousRelease.Generated,
 		Info: &release.Info{
 			FirstDeployed: currentRelease.Info.FirstDeployed,
 			LastDeployed:  helmtime.Now(),
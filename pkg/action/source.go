@@ -0,0 +1,180 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+var (
+	// YAML parse error on mychart/templates/deployment.yaml: error converting YAML to JSON: ...
+	yamlParseErrorRegex = regexp.MustCompile(`YAML parse error on (\S+):`)
+	// ValidationError(Deployment.spec): unknown field "replica" in io.k8s.api.apps.v1.DeploymentSpec
+	validationErrorRegex = regexp.MustCompile(`ValidationError\((\w+)\.?([^)\s]*)\): (?:unknown field "([^"]+)")?`)
+	// Deployment.apps "web" is invalid: spec.template.spec.containers[0].image: Required value
+	invalidObjectRegex = regexp.MustCompile(`(\w+)(?:\.[\w.-]+)? "([^"]+)" is invalid: (.*)`)
+	invalidFieldRegex  = regexp.MustCompile(`(?:^|\[|, )([a-zA-Z][\w-]*(?:\[[^\]]+\])*(?:\.[\w-]+(?:\[[^\]]+\])*)*): `)
+	// Deployment in version "v1" cannot be handled as a Deployment: strict decoding error: unknown field "spec.replica"
+	strictDecodingRegex = regexp.MustCompile(`(\w+) in version "[^"]*" cannot be handled as a \w+: strict decoding error: (.*)`)
+	unknownFieldRegex   = regexp.MustCompile(`unknown field "([^"]+)"`)
+)
+
+// sourceTracer traces the errors reported for the manifests of a render back
+// to the template lines they come from.
+//
+// Recording a source map slows every render down, so the templates are only
+// rendered again with one once an error which refers to the manifests has to
+// be traced. The zero value traces nothing.
+type sourceTracer struct {
+	cfg    *Configuration
+	chart  *chart.Chart
+	values chartutil.Values
+	opts   renderOptions
+	// files is the output of the first render, which renderResources records.
+	files map[string]string
+}
+
+// record keeps the output of the first render of the templates.
+func (t *sourceTracer) record(files map[string]string) {
+	if t == nil {
+		return
+	}
+	t.files = maps.Clone(files)
+}
+
+// annotate renders the templates again with a source map, and annotates the
+// error with it. Only the errors about the rendered manifests are traced:
+// the errors of the render itself, e.g. of its deadline, are returned as they
+// are. So are the errors of a render whose output differs from the first
+// one, e.g. as 'lookup' queries the resources applied in between.
+func (t *sourceTracer) annotate(err error) error {
+	var annotated *sourceError
+	if err == nil || t.chart == nil || t.files == nil || errors.As(err, &annotated) || !isTraceable(err) {
+		return err
+	}
+	opts := t.opts
+	opts.sourceMap = engine.NewSourceMap()
+	opts.profile = nil
+	// The templates reuse the generated values of the first render, to render
+	// the same manifests.
+	opts.generated = engine.NewGeneratedValues(t.opts.generated.Values())
+	files, rerr := t.cfg.renderTemplates(t.chart, t.values, opts)
+	if rerr != nil || !maps.Equal(files, t.files) {
+		return err
+	}
+	return annotateSourceError(err, opts.sourceMap)
+}
+
+// isTraceable reports whether an error refers to the manifests in a way
+// annotateSourceError can trace back to the templates.
+func isTraceable(err error) bool {
+	msg := err.Error()
+	for _, re := range []*regexp.Regexp{yamlParseErrorRegex, validationErrorRegex, invalidObjectRegex, strictDecodingRegex} {
+		if re.MatchString(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// annotateSourceError appends the template locations of the manifests and
+// fields an error refers to, as recorded in the source map of the rendered
+// chart. Errors that cannot be traced back to a template are returned as is.
+func annotateSourceError(err error, sourceMap *engine.SourceMap) error {
+	var annotated *sourceError
+	if err == nil || sourceMap == nil || errors.As(err, &annotated) {
+		return err
+	}
+
+	var notes []string
+	seen := make(map[string]bool)
+	note := func(what string, locs ...engine.SourceLocation) {
+		for _, loc := range locs {
+			n := fmt.Sprintf("%s (%s)", loc, what)
+			if !seen[n] {
+				seen[n] = true
+				notes = append(notes, n)
+			}
+		}
+	}
+	locateFields := func(kind, name string, fields ...string) {
+		object := kind
+		if name != "" {
+			object += " " + name
+		}
+		for _, field := range fields {
+			note(strings.TrimSpace(object+" "+field), sourceMap.LocateField("", kind, name, field)...)
+		}
+	}
+
+	msg := err.Error()
+	for _, m := range yamlParseErrorRegex.FindAllStringSubmatch(msg, -1) {
+		if loc, ok := sourceMap.LocateSyntaxError(m[1]); ok {
+			note("YAML syntax error", loc)
+		}
+	}
+	for _, m := range validationErrorRegex.FindAllStringSubmatch(msg, -1) {
+		field := m[2]
+		if m[3] != "" {
+			field = strings.TrimPrefix(field+"."+m[3], ".")
+		}
+		locateFields(m[1], "", field)
+	}
+	for _, m := range invalidObjectRegex.FindAllStringSubmatch(msg, -1) {
+		var fields []string
+		for _, f := range invalidFieldRegex.FindAllStringSubmatch(m[3], -1) {
+			fields = append(fields, f[1])
+		}
+		if len(fields) == 0 {
+			fields = []string{""}
+		}
+		locateFields(m[1], m[2], fields...)
+	}
+	for _, m := range strictDecodingRegex.FindAllStringSubmatch(msg, -1) {
+		for _, f := range unknownFieldRegex.FindAllStringSubmatch(m[2], -1) {
+			locateFields(m[1], "", f[1])
+		}
+	}
+
+	if len(notes) == 0 {
+		return err
+	}
+	return &sourceError{err: err, notes: notes}
+}
+
+// sourceError is an error annotated with template locations.
+type sourceError struct {
+	err   error
+	notes []string
+}
+
+func (e *sourceError) Error() string {
+	return e.err.Error() + "\n  at " + strings.Join(e.notes, "\n  at ")
+}
+
+func (e *sourceError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,173 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+func renderSourceMap(t *testing.T) *engine.SourceMap {
+	t.Helper()
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "web", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte("{{- define \"web.image\" -}}\nimage: {{ .Values.image }}\n{{- end }}\n")},
+			{Name: "templates/deployment.yaml", Data: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replica: 1
+  template:
+    spec:
+      containers:
+      - name: web
+        {{- include "web.image" . | nindent 8 }}
+`)},
+		},
+	}
+	vals, err := chartutil.ToRenderValues(ch, map[string]interface{}{"image": ""}, chartutil.ReleaseOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceMap := engine.NewSourceMap()
+	e := engine.Engine{SourceMap: sourceMap}
+	if _, err := e.Render(ch, vals); err != nil {
+		t.Fatal(err)
+	}
+	return sourceMap
+}
+
+func TestAnnotateSourceError(t *testing.T) {
+	sourceMap := renderSourceMap(t)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "openapi validation",
+			err:  errors.New(`error validating data: ValidationError(Deployment.spec): unknown field "replica" in io.k8s.api.apps.v1.DeploymentSpec`),
+			want: "at web/templates/deployment.yaml:6 (Deployment spec.replica)",
+		},
+		{
+			name: "invalid object",
+			err:  errors.New(`Deployment.apps "web" is invalid: spec.template.spec.containers[0].image: Required value`),
+			want: "at web/templates/_helpers.tpl:2 (included from web/templates/deployment.yaml:11) (Deployment web spec.template.spec.containers[0].image)",
+		},
+		{
+			name: "strict decoding",
+			err:  errors.New(`Deployment in version "v1" cannot be handled as a Deployment: strict decoding error: unknown field "spec.replica"`),
+			want: "at web/templates/deployment.yaml:6 (Deployment spec.replica)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := annotateSourceError(tt.err, sourceMap)
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %q to contain %q", err, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected the annotated error to wrap the original error")
+			}
+		})
+	}
+}
+
+func TestAnnotateSourceErrorUntraced(t *testing.T) {
+	sourceMap := renderSourceMap(t)
+
+	err := errors.New("connection refused")
+	if got := annotateSourceError(err, sourceMap); got != err {
+		t.Errorf("expected untraced error to be returned as is, got %q", got)
+	}
+	if got := annotateSourceError(err, nil); got != err {
+		t.Errorf("expected error without source map to be returned as is, got %q", got)
+	}
+}
+
+func TestInstallTracesErrorsToTemplates(t *testing.T) {
+	instAction := installAction(t)
+	ch := buildChart()
+	ch.Templates = append(ch.Templates, &chart.File{
+		Name: "templates/broken.yaml",
+		Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\n data: {}\n"),
+	})
+
+	_, err := instAction.Run(ch, map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected an error for an invalid manifest")
+	}
+	if want := "at hello/templates/broken.yaml:4 (YAML syntax error)"; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q to contain %q", err, want)
+	}
+}
+
+func TestSourceTracerRenders(t *testing.T) {
+	renders := 0
+	stable := true
+	cfg := actionConfigFixture(t)
+	cfg.TemplateFuncs = engine.NewFuncRegistry()
+	cfg.TemplateFuncs.MustRegister("count", func() int {
+		renders++
+		if stable {
+			return 1
+		}
+		return renders
+	})
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "web", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/cm.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\ndata:\n  count: \"{{ count }}\"\n")},
+		},
+	}
+	vals, err := chartutil.ToRenderValues(ch, map[string]interface{}{}, chartutil.ReleaseOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := &sourceTracer{cfg: cfg, chart: ch, values: vals, opts: renderOptions{generated: engine.NewGeneratedValues(nil)}}
+	trace.opts.trace = trace
+	if _, _, _, err := cfg.renderResources(ch, vals, trace.opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// The errors of the render itself are not traced.
+	deadline := errors.Wrap(context.DeadlineExceeded, "rendering web/templates/cm.yaml was interrupted")
+	if got := trace.annotate(deadline); got != deadline || renders != 1 {
+		t.Errorf("expected the error to be returned as is without rendering again, got %q after %d renders", got, renders)
+	}
+
+	invalid := errors.New(`ConfigMap "web" is invalid: data.count: Invalid value`)
+	if got := trace.annotate(invalid); !strings.Contains(got.Error(), "at web/templates/cm.yaml:6 (ConfigMap web data.count)") || renders != 2 {
+		t.Errorf("expected the error to be traced, got %q after %d renders", got, renders)
+	}
+
+	// A render with another output cannot be traced.
+	stable = false
+	if got := trace.annotate(invalid); got != invalid || renders != 3 {
+		t.Errorf("expected the error to be returned as is, got %q after %d renders", got, renders)
+	}
+}
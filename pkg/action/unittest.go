@@ -0,0 +1,56 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/unittest"
+)
+
+// UnitTest is the action for running the unit tests of charts.
+//
+// It provides the implementation of 'helm unittest'. The tests render the
+// templates locally and do not contact a cluster.
+type UnitTest struct {
+	// KubeVersions, when set, overrides the Kubernetes versions the tests are run for.
+	KubeVersions []string
+	// UpdateSnapshots replaces the snapshots by the rendered documents.
+	UpdateSnapshots bool
+}
+
+// NewUnitTest creates a new UnitTest object.
+func NewUnitTest() *UnitTest {
+	return &UnitTest{}
+}
+
+// Run runs the test suites of the charts at the given paths.
+func (u *UnitTest) Run(paths []string) ([]*unittest.Result, error) {
+	runner := &unittest.Runner{
+		KubeVersions:    u.KubeVersions,
+		UpdateSnapshots: u.UpdateSnapshots,
+	}
+	var results []*unittest.Result
+	for _, path := range paths {
+		chartResults, err := runner.Run(path)
+		if err != nil {
+			return results, errors.Wrapf(err, "unable to run the tests of %s", path)
+		}
+		results = append(results, chartResults...)
+	}
+	return results, nil
+}
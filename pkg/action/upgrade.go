@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"time"
@@ -30,7 +31,9 @@ import (
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/policy"
 	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
@@ -107,6 +110,16 @@ type Upgrade struct {
 	// If this is non-nil, then after templates are rendered, they will be sent to the
 	// post renderer before sending to the Kubernetes API server.
 	PostRenderer postrender.PostRenderer
+	// Policies, when set, are evaluated against the rendered objects before
+	// they are updated. A violated policy of the deny mode fails the upgrade.
+	Policies *policy.Set
+	// PolicyWarnings receives the violations of the policies of the warn mode.
+	PolicyWarnings io.Writer
+	// Prune deletes the live objects owned by the release which are no
+	// longer in its manifest, unless their resource policy is to keep them.
+	Prune bool
+	// PruneOutput receives the objects pruned, or to be pruned in a dry run.
+	PruneOutput io.Writer
 	// DisableOpenAPIValidation controls whether OpenAPI validation is enforced.
 	DisableOpenAPIValidation bool
 	// Get missing dependencies
@@ -115,6 +128,15 @@ type Upgrade struct {
 	Lock sync.Mutex
 	// Enable DNS lookups when rendering templates
 	EnableDNS bool
+	// Fail when a named template is defined more than once with different contents
+	StrictTemplateNames bool
+	// RenderLimits bounds the output of the templates
+	RenderLimits engine.RenderLimits
+	// RenderTimeout, when set, is the maximum time spent rendering the templates
+	RenderTimeout time.Duration
+	// Regenerate lists the keys of the 'generated' template function whose
+	// values are generated again instead of reusing those of the current release
+	Regenerate []string
 }
 
 type resultMessage struct {
@@ -158,9 +180,10 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, chart *chart.
 	}
 
 	u.cfg.Log("preparing upgrade for %s", name)
-	currentRelease, upgradedRelease, err := u.prepareUpgrade(name, chart, vals)
+	var trace sourceTracer
+	currentRelease, upgradedRelease, err := u.prepareUpgrade(name, chart, vals, &trace)
 	if err != nil {
-		return nil, err
+		return nil, trace.annotate(err)
 	}
 
 	u.cfg.Releases.MaxHistory = u.MaxHistory
@@ -168,7 +191,7 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, chart *chart.
 	u.cfg.Log("performing update for %s", name)
 	res, err := u.performUpgrade(ctx, currentRelease, upgradedRelease)
 	if err != nil {
-		return res, err
+		return res, trace.annotate(err)
 	}
 
 	// Do not update for dry runs
@@ -190,8 +213,9 @@ func (u *Upgrade) isDryRun() bool {
 	return false
 }
 
-// prepareUpgrade builds an upgraded release for an upgrade operation.
-func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, *release.Release, error) {
+// prepareUpgrade builds an upgraded release for an upgrade operation, and sets
+// up trace to trace errors back to the templates it renders.
+func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[string]interface{}, trace *sourceTracer) (*release.Release, *release.Release, error) {
 	if chart == nil {
 		return nil, nil, errMissingChart
 	}
@@ -247,11 +271,16 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 	// the release object.
 	revision := lastRelease.Version + 1
 
+	previous, err := previousRelease(currentRelease)
+	if err != nil {
+		return nil, nil, err
+	}
 	options := chartutil.ReleaseOptions{
 		Name:      name,
 		Namespace: currentRelease.Namespace,
 		Revision:  revision,
 		IsUpgrade: true,
+		Previous:  previous,
 	}
 
 	caps, err := u.cfg.getCapabilities()
@@ -269,7 +298,20 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 		interactWithRemote = true
 	}
 
-	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, false, u.PostRenderer, interactWithRemote, u.EnableDNS, u.HideSecret)
+	opts := renderOptions{
+		subNotes:            u.SubNotes,
+		postRenderer:        u.PostRenderer,
+		interactWithRemote:  interactWithRemote,
+		enableDNS:           u.EnableDNS,
+		strictTemplateNames: u.StrictTemplateNames,
+		hideSecret:          u.HideSecret,
+		limits:              u.RenderLimits,
+		timeout:             u.RenderTimeout,
+		generated:           engine.NewGeneratedValues(currentRelease.Generated, u.Regenerate...),
+	}
+	*trace = sourceTracer{cfg: u.cfg, chart: chart, values: valuesToRender, opts: opts}
+	opts.trace = trace
+	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, opts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -284,6 +326,7 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 		Namespace: currentRelease.Namespace,
 		Chart:     chart,
 		Config:    vals,
+		Generated: opts.generated.Values(),
 		Info: &release.Info{
 			FirstDeployed: currentRelease.Info.FirstDeployed,
 			LastDeployed:  Timestamper(),
@@ -352,9 +395,20 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 		return nil
 	})
 
+	if err := checkPolicies(u.Policies, upgradedRelease, true, u.PolicyWarnings); err != nil {
+		return nil, err
+	}
+
 	// Run if it is a dry run
 	if u.isDryRun() {
 		u.cfg.Log("dry run for %s", upgradedRelease.Name)
+		if u.Prune {
+			orphans, err := findOrphans(u.cfg, upgradedRelease)
+			if err != nil {
+				return nil, err
+			}
+			u.reportPruned(orphans, "would prune")
+		}
 		if len(u.Description) > 0 {
 			upgradedRelease.Info.Description = u.Description
 		} else {
@@ -461,6 +515,13 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 		}
 	}
 
+	if u.Prune {
+		if err := u.prune(upgradedRelease); err != nil {
+			u.reportToPerformUpgrade(c, upgradedRelease, results.Created, fmt.Errorf("pruning failed: %s", err))
+			return
+		}
+	}
+
 	originalRelease.Info.Status = release.StatusSuperseded
 	u.cfg.recordRelease(originalRelease)
 
@@ -473,6 +534,34 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 	u.reportToPerformUpgrade(c, upgradedRelease, nil, nil)
 }
 
+// prune deletes the live objects owned by the release which are not in its
+// manifest.
+func (u *Upgrade) prune(rel *release.Release) error {
+	orphans, err := findOrphans(u.cfg, rel)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		return nil
+	}
+	u.cfg.Log("pruning %d resource(s) of release %s", len(orphans), rel.Name)
+	if _, errs := u.cfg.KubeClient.Delete(orphans); len(errs) > 0 {
+		return errors.Errorf("unable to delete %d resource(s): %s", len(orphans), joinErrors(errs))
+	}
+	u.reportPruned(orphans, "pruned")
+	return nil
+}
+
+// reportPruned writes the objects pruned to the PruneOutput.
+func (u *Upgrade) reportPruned(orphans kube.ResourceList, verb string) {
+	if u.PruneOutput == nil {
+		return
+	}
+	for _, info := range orphans {
+		fmt.Fprintf(u.PruneOutput, "%s %s\n", verb, orphanName(info))
+	}
+}
+
 func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, err error) (*release.Release, error) {
 	msg := fmt.Sprintf("Upgrade %q failed: %s", rel.Name, err)
 	u.cfg.Log("warning: %s", msg)
@@ -635,3 +724,22 @@ func mergeCustomLabels(current, desired map[string]string) map[string]string {
 	}
 	return labels
 }
+
+// previousRelease describes rel to the templates of the revision replacing it.
+func previousRelease(rel *release.Release) (*chartutil.PreviousRelease, error) {
+	previous := &chartutil.PreviousRelease{
+		Revision: rel.Version,
+		Values:   rel.Config,
+	}
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return previous, nil
+	}
+	previous.ChartVersion = rel.Chart.Metadata.Version
+	previous.AppVersion = rel.Chart.Metadata.AppVersion
+	computed, err := chartutil.CoalesceValues(rel.Chart, rel.Config)
+	if err != nil {
+		return previous, errors.Wrapf(err, "failed to compute the values of revision %d", rel.Version)
+	}
+	previous.ComputedValues = computed
+	return previous, nil
+}
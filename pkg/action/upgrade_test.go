@@ -17,6 +17,7 @@ limitations under the License.
 package action
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"reflect"
@@ -28,8 +29,12 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/resource"
 
+	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/policy"
 	"helm.sh/helm/v3/pkg/release"
 	helmtime "helm.sh/helm/v3/pkg/time"
 )
@@ -586,3 +591,185 @@ func TestUpgradeRelease_DryRun(t *testing.T) {
 	done()
 	req.Error(err)
 }
+
+func TestUpgradeRelease_GeneratedValues(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	generatedChart := func() *chart.Chart {
+		ch := buildChart()
+		ch.Templates = append(ch.Templates, &chart.File{
+			Name: "templates/password",
+			Data: []byte(`password: {{ generated "password" (randAlphaNum 16) }}`),
+		})
+		return ch
+	}
+
+	instAction := installAction(t)
+	rel, err := instAction.Run(generatedChart(), map[string]interface{}{})
+	req.NoError(err)
+	password, ok := rel.Generated["password"].(string)
+	req.True(ok, "expected a generated password, got %v", rel.Generated)
+	is.Contains(rel.Manifest, "password: "+password)
+
+	upAction := NewUpgrade(instAction.cfg)
+	upAction.Namespace = instAction.Namespace
+	rel, err = upAction.Run(rel.Name, generatedChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(password, rel.Generated["password"])
+	is.Contains(rel.Manifest, "password: "+password)
+
+	upAction.Regenerate = []string{"password"}
+	rel, err = upAction.Run(rel.Name, generatedChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.NotEqual(password, rel.Generated["password"])
+	is.NotContains(rel.Manifest, "password: "+password)
+}
+
+func TestUpgradeRelease_PreviousRelease(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	previousChart := func(version string) *chart.Chart {
+		ch := buildChart(withValues(map[string]interface{}{"claim": "default", "size": "1Gi"}))
+		ch.Metadata.Version = version
+		ch.Templates = append(ch.Templates, &chart.File{
+			Name: "templates/previous",
+			Data: []byte(`previous: "{{ if .Release.Previous }}{{ .Release.Previous.Revision }} {{ .Release.Previous.ChartVersion }} {{ .Release.Previous.Values.claim }} {{ .Release.Previous.ComputedValues.size }}{{ else }}none{{ end }}"`),
+		})
+		return ch
+	}
+
+	instAction := installAction(t)
+	rel, err := instAction.Run(previousChart("2.1.0"), map[string]interface{}{"claim": "old"})
+	req.NoError(err)
+	is.Contains(rel.Manifest, `previous: "none"`)
+
+	upAction := NewUpgrade(instAction.cfg)
+	upAction.Namespace = instAction.Namespace
+	rel, err = upAction.Run(rel.Name, previousChart("3.0.0"), map[string]interface{}{"claim": "new"})
+	req.NoError(err)
+	is.Contains(rel.Manifest, `previous: "1 2.1.0 old 1Gi"`)
+
+	// A rollback applies the manifest stored with the revision rolled back to.
+	rollAction := NewRollback(instAction.cfg)
+	rollAction.Version = 1
+	req.NoError(rollAction.Run(rel.Name))
+	rel, err = instAction.cfg.Releases.Last(rel.Name)
+	req.NoError(err)
+	is.Equal(3, rel.Version)
+	is.Equal("2.1.0", rel.Chart.Metadata.Version)
+	is.Contains(rel.Manifest, `previous: "none"`)
+}
+
+func TestUpgradeRelease_Policies(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "denied"
+	rel.Info.Status = release.StatusDeployed
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	policies, err := policy.NewSet(policy.Policy{Name: "installs-only", Scope: policy.ReleaseScope, Validation: "!release.isUpgrade"})
+	req.NoError(err)
+	upAction.Policies = policies
+
+	_, err = upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "denied by 1 policy violation(s):\n- installs-only: failed !release.isUpgrade")
+
+	lastRelease, err := upAction.cfg.Releases.Last(rel.Name)
+	req.NoError(err)
+	is.Equal(1, lastRelease.Version)
+	is.Equal(release.StatusDeployed, lastRelease.Info.Status)
+}
+
+func liveObject(apiVersion, kind, name, releaseName string, annotations map[string]string) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace("spaced")
+	obj.SetLabels(map[string]string{appManagedByLabel: appManagedByHelm})
+	annos := map[string]string{
+		helmReleaseNameAnnotation:      releaseName,
+		helmReleaseNamespaceAnnotation: "spaced",
+	}
+	for k, v := range annotations {
+		annos[k] = v
+	}
+	obj.SetAnnotations(annos)
+	return &resource.Info{Name: name, Namespace: "spaced", Object: obj}
+}
+
+func pruneAction(t *testing.T) (*Upgrade, *release.Release) {
+	t.Helper()
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "pruned"
+	rel.Namespace = "spaced"
+	rel.Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: current\n" +
+		"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: old\n" +
+		"---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"
+	require.NoError(t, upAction.cfg.Releases.Create(rel))
+
+	upAction.cfg.KubeClient.(*kubefake.FailingKubeClient).ListResources = kube.ResourceList{
+		liveObject("v1", "ConfigMap", "current", "pruned", nil),
+		liveObject("v1", "ConfigMap", "old", "pruned", nil),
+		liveObject("v1", "ConfigMap", "kept", "pruned", map[string]string{kube.ResourcePolicyAnno: "keep"}),
+		liveObject("v1", "ConfigMap", "other", "other-release", nil),
+		liveObject("apps/v1", "Deployment", "web", "pruned", nil),
+		// Secrets are not in any revision of the release.
+		liveObject("v1", "Secret", "stray", "pruned", nil),
+	}
+	upAction.Prune = true
+	return upAction, rel
+}
+
+func currentChart() *chart.Chart {
+	return buildChart(func(opts *chartOptions) {
+		opts.Templates = []*chart.File{
+			{Name: "templates/current.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: current\n")},
+		}
+	})
+}
+
+func TestUpgradeRelease_Prune(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction, rel := pruneAction(t)
+	var out bytes.Buffer
+	upAction.PruneOutput = &out
+
+	res, err := upAction.Run(rel.Name, currentChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(release.StatusDeployed, res.Info.Status)
+	is.Equal("pruned ConfigMap spaced/old\npruned Deployment spaced/web\n", out.String())
+
+	upAction.cfg.KubeClient.(*kubefake.FailingKubeClient).ListError = fmt.Errorf("forbidden")
+	res, err = upAction.Run(rel.Name, currentChart(), map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "pruning failed: unable to list the objects of the release: forbidden")
+	is.Equal(release.StatusFailed, res.Info.Status)
+}
+
+func TestUpgradeRelease_PruneDryRun(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction, rel := pruneAction(t)
+	upAction.DryRun = true
+	var out bytes.Buffer
+	upAction.PruneOutput = &out
+
+	_, err := upAction.Run(rel.Name, currentChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal("would prune ConfigMap spaced/old\nwould prune Deployment spaced/web\n", out.String())
+
+	lastRelease, err := upAction.cfg.Releases.Last(rel.Name)
+	req.NoError(err)
+	is.Equal(1, lastRelease.Version)
+}
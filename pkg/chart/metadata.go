@@ -81,6 +81,12 @@ type Metadata struct {
 	Dependencies []*Dependency `json:"dependencies,omitempty"`
 	// Specifies the chart type: application or library
 	Type string `json:"type,omitempty"`
+	// Renderer is the name of the renderer of the templates of the chart,
+	// e.g. "starlark". Go templates are used when it is empty.
+	Renderer string `json:"renderer,omitempty"`
+	// TemplateNamespace is the prefix of the named templates a library chart
+	// exports, e.g. "common" for "common.labels".
+	TemplateNamespace string `json:"templateNamespace,omitempty"`
 }
 
 // Validate checks the metadata for known issues and sanitizes string
@@ -98,6 +104,7 @@ func (md *Metadata) Validate() error {
 	md.Tags = sanitizeString(md.Tags)
 	md.AppVersion = sanitizeString(md.AppVersion)
 	md.KubeVersion = sanitizeString(md.KubeVersion)
+	md.TemplateNamespace = sanitizeString(md.TemplateNamespace)
 	for i := range md.Sources {
 		md.Sources[i] = sanitizeString(md.Sources[i])
 	}
@@ -125,6 +132,9 @@ func (md *Metadata) Validate() error {
 	if !isValidChartType(md.Type) {
 		return ValidationError("chart.metadata.type must be application or library")
 	}
+	if md.TemplateNamespace != "" && md.Type != "library" {
+		return ValidationError("chart.metadata.templateNamespace is only supported by library charts")
+	}
 
 	for _, m := range md.Maintainers {
 		if err := m.Validate(); err != nil {
@@ -177,6 +177,16 @@ func TestValidate(t *testing.T) {
 			&Metadata{APIVersion: "v2", Name: "test", Version: "1.2.3.4"},
 			ValidationError("chart.metadata.version \"1.2.3.4\" is invalid"),
 		},
+		{
+			"template namespace of an application chart",
+			&Metadata{APIVersion: "v2", Name: "test", Version: "1.0", Type: "application", TemplateNamespace: "test"},
+			ValidationError("chart.metadata.templateNamespace is only supported by library charts"),
+		},
+		{
+			"template namespace of a library chart",
+			&Metadata{APIVersion: "v2", Name: "test", Version: "1.0", Type: "library", TemplateNamespace: "test"},
+			nil,
+		},
 	}
 
 	for _, tt := range tests {
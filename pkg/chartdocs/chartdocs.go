@@ -0,0 +1,272 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package chartdocs generates the documentation of a chart, such as its README,
+from its metadata and from the comments of its values.yaml file.
+
+The description of a value is the comment above its key, or the comment at
+the end of its line:
+
+	image:
+	  # The image repository
+	  repository: nginx
+	  tag: "" # Defaults to the appVersion of the chart
+
+Only the last paragraph of the comment above a key is used, so that
+commented-out values separated from the key by a blank line are not part of
+its description.
+*/
+package chartdocs // import "helm.sh/helm/v3/pkg/chartdocs"
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
+	yamlv3 "sigs.k8s.io/yaml/goyaml.v3"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// DefaultTemplate is the template of the README of a chart.
+const DefaultTemplate = `# {{ .Chart.Name }}
+{{- with .Chart.Description }}
+
+{{ . }}
+{{- end }}
+
+**Version:** {{ .Chart.Version }}
+{{- with .Chart.AppVersion }} **App version:** {{ . }}{{ end }}
+{{- with .Chart.KubeVersion }} **Kubernetes:** ` + "`{{ . }}`" + `{{ end }}
+{{- with .Chart.Home }}
+
+**Homepage:** <{{ . }}>
+{{- end }}
+{{- with .Chart.Maintainers }}
+
+## Maintainers
+
+| Name | Email | URL |
+|------|-------|-----|
+{{- range . }}
+| {{ .Name | escape }} | {{ .Email | escape }} | {{ .URL | escape }} |
+{{- end }}
+{{- end }}
+{{- with .Chart.Dependencies }}
+
+## Dependencies
+
+| Name | Version | Repository |
+|------|---------|------------|
+{{- range . }}
+| {{ .Name | escape }} | {{ .Version | escape }} | {{ .Repository | escape }} |
+{{- end }}
+{{- end }}
+{{- with .Values }}
+
+## Values
+
+| Key | Type | Default | Description |
+|-----|------|---------|-------------|
+{{- range . }}
+| {{ .Key | escape }} | {{ .Type }} | {{ .Default | code }} | {{ .Description | escape }} |
+{{- end }}
+{{- end }}
+`
+
+// Value is a value of values.yaml. The keys of the values of maps are the
+// path to the value, e.g. "image.tag". Non-empty maps are not values: their
+// values are.
+type Value struct {
+	Key string
+	// Type is the YAML type of the value: string, int, float, bool, null,
+	// list or object.
+	Type string
+	// Default is the value, as JSON.
+	Default     string
+	Description string
+}
+
+// Data is the data the template of the documentation is executed with.
+type Data struct {
+	Chart  *chart.Metadata
+	Values []Value
+}
+
+// ParseValues returns the values of a values.yaml file, in the order of the
+// file, with the description of their comments.
+func ParseValues(data []byte) ([]Value, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing values")
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := resolve(doc.Content[0])
+	if root.Kind == yamlv3.ScalarNode && root.Tag == "!!null" {
+		return nil, nil
+	}
+	if root.Kind != yamlv3.MappingNode {
+		return nil, errors.New("parsing values: the values are not a map")
+	}
+	var values []Value
+	if err := appendValues(&values, "", root); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// appendValues appends the values of a mapping node, whose keys start with
+// prefix.
+func appendValues(values *[]Value, prefix string, mapping *yamlv3.Node) error {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, node := mapping.Content[i], mapping.Content[i+1]
+		name := prefix + key.Value
+		node = resolve(node)
+		if node.Kind == yamlv3.MappingNode && len(node.Content) > 0 {
+			if err := appendValues(values, name+".", node); err != nil {
+				return err
+			}
+			continue
+		}
+		value := Value{Key: name, Type: valueType(node), Description: description(key, node)}
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return errors.Wrapf(err, "parsing value %s", name)
+		}
+		var d bytes.Buffer
+		enc := json.NewEncoder(&d)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return errors.Wrapf(err, "parsing value %s", name)
+		}
+		value.Default = strings.TrimSuffix(d.String(), "\n")
+		*values = append(*values, value)
+	}
+	return nil
+}
+
+// resolve returns the node an alias refers to.
+func resolve(node *yamlv3.Node) *yamlv3.Node {
+	for node.Kind == yamlv3.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func valueType(node *yamlv3.Node) string {
+	switch node.Kind {
+	case yamlv3.SequenceNode:
+		return "list"
+	case yamlv3.MappingNode:
+		return "object"
+	}
+	switch node.Tag {
+	case "!!int":
+		return "int"
+	case "!!float":
+		return "float"
+	case "!!bool":
+		return "bool"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+// description returns the description of a value from the last paragraph of
+// the comment above its key, or from the comment at the end of its line.
+func description(key, node *yamlv3.Node) string {
+	comment := key.HeadComment
+	if comment != "" {
+		paragraphs := strings.Split(comment, "\n\n")
+		comment = paragraphs[len(paragraphs)-1]
+	} else if node.LineComment != "" {
+		comment = node.LineComment
+	} else {
+		comment = key.LineComment
+	}
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+// Render writes the documentation of a chart, executing the template with
+// the metadata of the chart and its values. The template has the functions
+// of Sprig, 'escape', which escapes the pipes and the newlines of a table
+// cell, and 'code', which formats text as inline code.
+func Render(out io.Writer, c *chart.Chart, tmpl string) error {
+	var values []Value
+	for _, f := range c.Raw {
+		if f.Name == chartutil.ValuesfileName {
+			v, err := ParseValues(f.Data)
+			if err != nil {
+				return err
+			}
+			values = v
+			break
+		}
+	}
+
+	t, err := template.New("docs").Funcs(sprig.TxtFuncMap()).Funcs(template.FuncMap{
+		"escape": escape,
+		"code":   code,
+	}).Parse(tmpl)
+	if err != nil {
+		return errors.Wrap(err, "parsing the documentation template")
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, Data{Chart: c.Metadata, Values: values}); err != nil {
+		return errors.Wrap(err, "executing the documentation template")
+	}
+	_, err = out.Write(buf.Bytes())
+	return err
+}
+
+// escape escapes text for a cell of a Markdown table.
+func escape(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// code formats text as inline code in a cell of a Markdown table, with
+// enough backticks to hold those of the text.
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	padding := ""
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		padding = " "
+	}
+	return fence + padding + escape(s) + padding + fence
+}
@@ -0,0 +1,161 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartdocs
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const testValues = `# The number of replicas
+replicaCount: 1
+
+image:
+  # The image repository
+  repository: nginx
+  tag: "" # Defaults to the appVersion of the chart
+  pullPolicy: IfNotPresent
+
+# ingress:
+#   enabled: true
+
+# Annotations of the pods,
+# e.g. prometheus.io/scrape: "true"
+podAnnotations: {}
+resources: &resources
+  limits:
+    cpu: 100m
+sidecarResources: *resources
+tolerations: []
+ratio: 0.5
+enabled: true
+nodeName: ~
+args: ["--port", "8080|8443"]
+`
+
+func TestParseValues(t *testing.T) {
+	values, err := ParseValues([]byte(testValues))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Value{
+		{Key: "replicaCount", Type: "int", Default: "1", Description: "The number of replicas"},
+		{Key: "image.repository", Type: "string", Default: `"nginx"`, Description: "The image repository"},
+		{Key: "image.tag", Type: "string", Default: `""`, Description: "Defaults to the appVersion of the chart"},
+		{Key: "image.pullPolicy", Type: "string", Default: `"IfNotPresent"`},
+		{Key: "podAnnotations", Type: "object", Default: "{}", Description: `Annotations of the pods, e.g. prometheus.io/scrape: "true"`},
+		{Key: "resources.limits.cpu", Type: "string", Default: `"100m"`},
+		{Key: "sidecarResources.limits.cpu", Type: "string", Default: `"100m"`},
+		{Key: "tolerations", Type: "list", Default: "[]"},
+		{Key: "ratio", Type: "float", Default: "0.5"},
+		{Key: "enabled", Type: "bool", Default: "true"},
+		{Key: "nodeName", Type: "null", Default: "null"},
+		{Key: "args", Type: "list", Default: `["--port","8080|8443"]`},
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("expected\n%+v\ngot\n%+v", expect, values)
+	}
+
+	if values, err := ParseValues(nil); err != nil || values != nil {
+		t.Errorf("expected no values for an empty file, got %v, %v", values, err)
+	}
+	if _, err := ParseValues([]byte("- a\n")); err == nil {
+		t.Error("expected an error for values which are not a map")
+	}
+}
+
+func TestRender(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:        "hello",
+			Description: "A chart saying hello",
+			Version:     "1.2.0",
+			AppVersion:  "2.0",
+			KubeVersion: ">=1.25.0-0",
+			Maintainers: []*chart.Maintainer{{Name: "Jane", Email: "jane@example.com"}},
+			Dependencies: []*chart.Dependency{
+				{Name: "redis", Version: "17.x.x", Repository: "https://charts.example.com"},
+			},
+		},
+		Raw: []*chart.File{
+			{Name: "Chart.yaml", Data: []byte("name: hello\n")},
+			{Name: "values.yaml", Data: []byte("# The greeting\ngreeting: hello\nargs: [\"a|b\"]\n")},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := Render(&out, c, DefaultTemplate); err != nil {
+		t.Fatal(err)
+	}
+	expect := "# hello\n" +
+		"\n" +
+		"A chart saying hello\n" +
+		"\n" +
+		"**Version:** 1.2.0 **App version:** 2.0 **Kubernetes:** `>=1.25.0-0`\n" +
+		"\n" +
+		"## Maintainers\n" +
+		"\n" +
+		"| Name | Email | URL |\n" +
+		"|------|-------|-----|\n" +
+		"| Jane | jane@example.com |  |\n" +
+		"\n" +
+		"## Dependencies\n" +
+		"\n" +
+		"| Name | Version | Repository |\n" +
+		"|------|---------|------------|\n" +
+		"| redis | 17.x.x | https://charts.example.com |\n" +
+		"\n" +
+		"## Values\n" +
+		"\n" +
+		"| Key | Type | Default | Description |\n" +
+		"|-----|------|---------|-------------|\n" +
+		"| greeting | string | `\"hello\"` | The greeting |\n" +
+		"| args | list | `[\"a\\|b\"]` |  |\n"
+	if out.String() != expect {
+		t.Errorf("expected\n%s\ngot\n%s", expect, out.String())
+	}
+
+	out.Reset()
+	if err := Render(&out, c, "{{ .Chart.Name | upper }}: {{ len .Values }} values\n"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "HELLO: 2 values\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := Render(&out, c, "{{ .Chart.Name "); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestCode(t *testing.T) {
+	for in, expect := range map[string]string{
+		"":         "",
+		`"a"`:      "`\"a\"`",
+		"a`b":      "``a`b``",
+		"`a`":      "`` `a` ``",
+		`"a|b"`:    "`\"a\\|b\"`",
+		"\"a\nb\"": "`\"a<br>b\"`",
+	} {
+		if got := code(in); got != expect {
+			t.Errorf("code(%q): expected %q, got %q", in, expect, got)
+		}
+	}
+}
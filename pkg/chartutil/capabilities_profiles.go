@@ -0,0 +1,190 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// builtinProfilePrefix is the prefix of the names of the built-in capabilities profiles.
+const builtinProfilePrefix = "kubernetes-"
+
+// builtinProfileMinors are the minor versions of Kubernetes 1.x for which a
+// built-in capabilities profile is provided.
+var builtinProfileMinors = []int{27, 28, 29, 30}
+
+// capabilitiesFile is the serialized form of Capabilities.
+//
+// The Helm version is deliberately not part of it, as it always describes the
+// running Helm binary.
+type capabilitiesFile struct {
+	KubeVersion string     `json:"kubeVersion"`
+	APIVersions VersionSet `json:"apiVersions"`
+}
+
+// MarshalCapabilities serializes the Kubernetes version and API versions of
+// the given capabilities to YAML.
+func MarshalCapabilities(caps *Capabilities) ([]byte, error) {
+	apiVersions := make(VersionSet, len(caps.APIVersions))
+	copy(apiVersions, caps.APIVersions)
+	sort.Strings(apiVersions)
+	return yaml.Marshal(capabilitiesFile{
+		KubeVersion: caps.KubeVersion.Version,
+		APIVersions: apiVersions,
+	})
+}
+
+// ParseCapabilities parses capabilities serialized by MarshalCapabilities.
+//
+// If no API versions are listed, DefaultVersionSet is used.
+func ParseCapabilities(data []byte) (*Capabilities, error) {
+	var f capabilitiesFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, err
+	}
+	if f.KubeVersion == "" {
+		return nil, errors.New("kubeVersion is required")
+	}
+	kubeVersion, err := ParseKubeVersion(f.KubeVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid kubeVersion %q", f.KubeVersion)
+	}
+	apiVersions := f.APIVersions
+	if len(apiVersions) == 0 {
+		apiVersions = DefaultVersionSet
+	}
+	return &Capabilities{
+		KubeVersion: *kubeVersion,
+		APIVersions: apiVersions,
+		HelmVersion: DefaultCapabilities.HelmVersion,
+	}, nil
+}
+
+// LoadCapabilities loads capabilities from a file or, if no such file exists,
+// from the built-in profile of that name.
+func LoadCapabilities(nameOrPath string) (*Capabilities, error) {
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if caps, ok := BuiltinCapabilities(nameOrPath); ok {
+				return caps, nil
+			}
+			return nil, errors.Errorf("capabilities file %q not found and no built-in profile of that name exists (available: %s)", nameOrPath, strings.Join(BuiltinCapabilitiesNames(), ", "))
+		}
+		return nil, err
+	}
+	caps, err := ParseCapabilities(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot load capabilities file %s", nameOrPath)
+	}
+	return caps, nil
+}
+
+// BuiltinCapabilitiesNames returns the names of the built-in capabilities profiles.
+func BuiltinCapabilitiesNames() []string {
+	names := make([]string, 0, len(builtinProfileMinors))
+	for _, minor := range builtinProfileMinors {
+		names = append(names, fmt.Sprintf("%s1.%d", builtinProfilePrefix, minor))
+	}
+	return names
+}
+
+// BuiltinCapabilities returns the built-in capabilities profile of the given
+// name, e.g. "kubernetes-1.29".
+//
+// The API versions of a profile are derived from the API lifecycle information
+// of the Kubernetes client libraries Helm is built with. They approximate an
+// upstream cluster of that version without any additional API services or CRDs.
+func BuiltinCapabilities(name string) (*Capabilities, bool) {
+	for _, minor := range builtinProfileMinors {
+		if name != fmt.Sprintf("%s1.%d", builtinProfilePrefix, minor) {
+			continue
+		}
+		return &Capabilities{
+			KubeVersion: KubeVersion{
+				Version: fmt.Sprintf("v1.%d.0", minor),
+				Major:   "1",
+				Minor:   fmt.Sprint(minor),
+			},
+			APIVersions: servedVersions(1, minor),
+			HelmVersion: DefaultCapabilities.HelmVersion,
+		}, true
+	}
+	return nil, false
+}
+
+type apiLifecycleIntroduced interface {
+	APILifecycleIntroduced() (major, minor int)
+}
+
+type apiLifecycleRemoved interface {
+	APILifecycleRemoved() (major, minor int)
+}
+
+// servedVersions returns the group versions and group version kinds known to
+// the client scheme that are served by a Kubernetes release.
+func servedVersions(major, minor int) VersionSet {
+	servedBy := func(m, n int) bool {
+		return major > m || (major == m && minor >= n)
+	}
+
+	versionMap := make(map[string]struct{})
+	for gvk := range scheme.Scheme.AllKnownTypes() {
+		if gvk.Version == runtime.APIVersionInternal || !isResourceKind(gvk.Kind) {
+			continue
+		}
+		obj, err := scheme.Scheme.New(gvk)
+		if err != nil {
+			continue
+		}
+		if i, ok := obj.(apiLifecycleIntroduced); ok && !servedBy(i.APILifecycleIntroduced()) {
+			continue
+		}
+		if r, ok := obj.(apiLifecycleRemoved); ok && servedBy(r.APILifecycleRemoved()) {
+			continue
+		}
+		gv := gvk.GroupVersion().String()
+		versionMap[gv] = struct{}{}
+		versionMap[path.Join(gv, gvk.Kind)] = struct{}{}
+	}
+
+	versions := make(VersionSet, 0, len(versionMap))
+	for v := range versionMap {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// isResourceKind reports whether a kind registered in a scheme is likely to be
+// an API resource, as opposed to a list or an options type.
+func isResourceKind(kind string) bool {
+	switch kind {
+	case "WatchEvent", "Status", "APIVersions", "APIGroup", "APIGroupList", "APIResourceList":
+		return false
+	}
+	return !strings.HasSuffix(kind, "List") && !strings.HasSuffix(kind, "Options")
+}
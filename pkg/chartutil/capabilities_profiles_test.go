@@ -0,0 +1,133 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarshalAndParseCapabilities(t *testing.T) {
+	caps := &Capabilities{
+		KubeVersion: KubeVersion{Version: "v1.29.3", Major: "1", Minor: "29"},
+		APIVersions: VersionSet{"v1", "apps/v1/Deployment", "apps/v1"},
+	}
+	data, err := MarshalCapabilities(caps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "apiVersions:\n- apps/v1\n- apps/v1/Deployment\n- v1\nkubeVersion: v1.29.3\n"
+	if string(data) != expect {
+		t.Errorf("Expected %q, got %q", expect, string(data))
+	}
+
+	parsed, err := ParseCapabilities(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.KubeVersion != caps.KubeVersion {
+		t.Errorf("Expected KubeVersion %v, got %v", caps.KubeVersion, parsed.KubeVersion)
+	}
+	if !parsed.APIVersions.Has("apps/v1/Deployment") || len(parsed.APIVersions) != 3 {
+		t.Errorf("Unexpected API versions %v", parsed.APIVersions)
+	}
+	if parsed.HelmVersion != DefaultCapabilities.HelmVersion {
+		t.Errorf("Expected the running Helm version, got %v", parsed.HelmVersion)
+	}
+}
+
+func TestParseCapabilitiesErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"missing kubeVersion": "apiVersions: [v1]\n",
+		"invalid kubeVersion": "kubeVersion: latest\n",
+		"unknown field":       "kubeVersion: v1.29.0\nhelmVersion: v3\n",
+	} {
+		if _, err := ParseCapabilities([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	caps, err := ParseCapabilities([]byte("kubeVersion: 1.28.2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caps.APIVersions.Has("v1") {
+		t.Error("Expected the default version set when no API versions are given")
+	}
+}
+
+func TestLoadCapabilities(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "caps.yaml")
+	if err := os.WriteFile(file, []byte("kubeVersion: v1.26.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	caps, err := LoadCapabilities(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.KubeVersion.Version != "v1.26.1" {
+		t.Errorf("Expected v1.26.1, got %q", caps.KubeVersion.Version)
+	}
+
+	caps, err = LoadCapabilities("kubernetes-1.29")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.KubeVersion.Version != "v1.29.0" {
+		t.Errorf("Expected v1.29.0, got %q", caps.KubeVersion.Version)
+	}
+
+	if _, err := LoadCapabilities("kubernetes-1.0"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
+
+func TestBuiltinCapabilities(t *testing.T) {
+	for _, name := range BuiltinCapabilitiesNames() {
+		caps, ok := BuiltinCapabilities(name)
+		if !ok {
+			t.Fatalf("Expected built-in profile %s", name)
+		}
+		for _, v := range []string{"v1", "v1/Pod", "apps/v1/Deployment", "batch/v1/CronJob"} {
+			if !caps.APIVersions.Has(v) {
+				t.Errorf("%s: expected %s to be served", name, v)
+			}
+		}
+		// Removed in Kubernetes 1.25.
+		if caps.APIVersions.Has("policy/v1beta1/PodDisruptionBudget") {
+			t.Errorf("%s: expected policy/v1beta1 PodDisruptionBudget to be removed", name)
+		}
+		if caps.APIVersions.Has("v1/PodList") || caps.APIVersions.Has("v1/DeleteOptions") {
+			t.Errorf("%s: expected only resource kinds", name)
+		}
+	}
+
+	old, _ := BuiltinCapabilities("kubernetes-1.27")
+	recent, _ := BuiltinCapabilities("kubernetes-1.30")
+	// flowcontrol v1beta2 was removed in Kubernetes 1.29.
+	if !old.APIVersions.Has("flowcontrol.apiserver.k8s.io/v1beta2") {
+		t.Error("Expected flowcontrol.apiserver.k8s.io/v1beta2 in Kubernetes 1.27")
+	}
+	if recent.APIVersions.Has("flowcontrol.apiserver.k8s.io/v1beta2") {
+		t.Error("Expected flowcontrol.apiserver.k8s.io/v1beta2 to be removed in Kubernetes 1.30")
+	}
+
+	if _, ok := BuiltinCapabilities("kubernetes-1.5"); ok {
+		t.Error("Expected no profile for Kubernetes 1.5")
+	}
+}
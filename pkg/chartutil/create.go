@@ -50,6 +50,9 @@ const (
 	ChartsDir = "charts"
 	// TemplatesTestsDir is the relative directory name for tests.
 	TemplatesTestsDir = TemplatesDir + sep + "tests"
+	// UnitTestsDir is the relative directory name for the unit tests of the
+	// templates, which are not packaged with the chart.
+	UnitTestsDir = "tests"
 	// IgnorefileName is the name of the Helm ignore file.
 	IgnorefileName = ".helmignore"
 	// IngressFileName is the name of the example ingress file.
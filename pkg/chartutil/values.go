@@ -129,6 +129,61 @@ type ReleaseOptions struct {
 	Revision  int
 	IsUpgrade bool
 	IsInstall bool
+	// Previous is the revision an upgrade replaces, if any
+	Previous *PreviousRelease
+}
+
+// PreviousRelease describes the revision of a release an upgrade replaces. It
+// is available to templates as .Release.Previous, e.g. for migrations
+// depending on the chart version being upgraded from. .Release.Previous is a
+// map keyed by the field names of PreviousRelease, and is empty on install, so
+// that {{ if .Release.Previous }} is false.
+type PreviousRelease struct {
+	Revision     int    `json:"revision,omitempty"`
+	ChartVersion string `json:"chartVersion,omitempty"`
+	AppVersion   string `json:"appVersion,omitempty"`
+	// Values are the values supplied by the user.
+	Values Values `json:"values,omitempty"`
+	// ComputedValues are the values supplied by the user coalesced with the
+	// default values of the chart.
+	ComputedValues Values `json:"computedValues,omitempty"`
+}
+
+// toMap returns p as exposed to templates, copying its values so that
+// templates cannot modify the values of p.
+func (p *PreviousRelease) toMap() (map[string]interface{}, error) {
+	if p == nil {
+		return map[string]interface{}{}, nil
+	}
+	values, err := copyValues(p.Values)
+	if err != nil {
+		return nil, err
+	}
+	computedValues, err := copyValues(p.ComputedValues)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"Revision":       p.Revision,
+		"ChartVersion":   p.ChartVersion,
+		"AppVersion":     p.AppVersion,
+		"Values":         values,
+		"ComputedValues": computedValues,
+	}, nil
+}
+
+// ReadPreviousReleaseFile reads the description of a previous release from a
+// YAML file.
+func ReadPreviousReleaseFile(filename string) (*PreviousRelease, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := &PreviousRelease{}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse previous release %s", filename)
+	}
+	return p, nil
 }
 
 // ToRenderValues composes the struct from the data coming from the Releases, Charts and Values files
@@ -138,6 +193,10 @@ func ToRenderValues(chrt *chart.Chart, chrtVals map[string]interface{}, options
 	if caps == nil {
 		caps = DefaultCapabilities
 	}
+	previous, err := options.Previous.toMap()
+	if err != nil {
+		return nil, err
+	}
 	top := map[string]interface{}{
 		"Chart":        chrt.Metadata,
 		"Capabilities": caps,
@@ -148,6 +207,7 @@ func ToRenderValues(chrt *chart.Chart, chrtVals map[string]interface{}, options
 			"IsInstall": options.IsInstall,
 			"Revision":  options.Revision,
 			"Service":   "Helm",
+			"Previous":  previous,
 		},
 	}
 
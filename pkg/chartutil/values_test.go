@@ -152,6 +152,46 @@ func TestToRenderValues(t *testing.T) {
 	}
 }
 
+func TestToRenderValuesPreviousRelease(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "test", Version: "3.0.0"},
+	}
+
+	res, err := ToRenderValues(c, map[string]interface{}{}, ReleaseOptions{IsInstall: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous := res["Release"].(map[string]interface{})["Previous"].(map[string]interface{}); len(previous) != 0 {
+		t.Errorf("Expected an empty previous release on install, got %v", previous)
+	}
+
+	o := ReleaseOptions{
+		Revision:  3,
+		IsUpgrade: true,
+		Previous: &PreviousRelease{
+			Revision:       2,
+			ChartVersion:   "2.1.0",
+			AppVersion:     "1.0",
+			Values:         Values{"persistence": map[string]interface{}{"claim": "old"}},
+			ComputedValues: Values{"persistence": map[string]interface{}{"claim": "old", "size": "1Gi"}},
+		},
+	}
+	res, err = ToRenderValues(c, map[string]interface{}{}, o, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := res["Release"].(map[string]interface{})["Previous"].(map[string]interface{})
+	if previous["Revision"] != 2 || previous["ChartVersion"] != "2.1.0" || previous["AppVersion"] != "1.0" {
+		t.Errorf("Expected revision 2 of chart version 2.1.0, got %v", previous)
+	}
+
+	// The values are copied, so that templates cannot modify them.
+	previous["ComputedValues"].(Values)["persistence"].(map[string]interface{})["claim"] = "new"
+	if claim := o.Previous.ComputedValues["persistence"].(map[string]interface{})["claim"]; claim != "old" {
+		t.Errorf("Expected the previous values to be copied, got %v", claim)
+	}
+}
+
 func TestReadValuesFile(t *testing.T) {
 	data, err := ReadValuesFile("./testdata/coleridge.yaml")
 	if err != nil {
@@ -0,0 +1,204 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// consoleResultFunc is the function capturing the value of an expression
+// evaluated by a Console.
+const consoleResultFunc = "__helm_console_result"
+
+// consoleTemplate is the name of the template of the evaluated expressions.
+const consoleTemplate = "console"
+
+// Console evaluates template expressions against a chart, with the functions
+// and named templates available to its templates when it is rendered.
+type Console struct {
+	t      *template.Template
+	vals   chartutil.Values
+	result interface{}
+}
+
+// NewConsole parses the templates of a chart for expressions to be evaluated
+// with the values of the chart as '.'. Values should be prepared as for Render.
+func (e Engine) NewConsole(chrt *chart.Chart, values chartutil.Values) (*Console, error) {
+	tpls := make(map[string]renderable)
+	vals := chartutil.Values(e.recAllTpls(chrt, tpls, values))
+	vals["Template"] = chartutil.Values{
+		"Name":     path.Join(chrt.Name(), "templates", consoleTemplate),
+		"BasePath": path.Join(chrt.Name(), "templates"),
+	}
+
+	c := &Console{t: template.New("gotpl"), vals: vals}
+	if e.Strict {
+		c.t.Option("missingkey=error")
+	} else {
+		c.t.Option("missingkey=zero")
+	}
+	e.initFunMap(c.t, nil)
+	c.t.Funcs(template.FuncMap{consoleResultFunc: func(v interface{}) string {
+		c.result = v
+		return ""
+	}})
+
+	for _, filename := range sortTemplates(tpls) {
+		if tpls[filename].renderer != "" {
+			continue
+		}
+		if _, err := c.t.New(filename).Parse(tpls[filename].tpl); err != nil {
+			return nil, cleanupParseError(filename, err)
+		}
+	}
+	return c, nil
+}
+
+// Eval evaluates an expression, e.g. '.Values.image' or
+// 'include "mychart.labels" .', and returns its value. Input containing '{{'
+// is executed as a template instead, and its output is returned; the named
+// templates it defines are available to the next evaluations.
+func (c *Console) Eval(input string) (interface{}, error) {
+	isTemplate := strings.Contains(input, "{{")
+	text := input
+	if !isTemplate {
+		text = "{{ " + consoleResultFunc + " (" + input + ") }}"
+	}
+	t, err := c.t.New(consoleTemplate).Parse(text)
+	if err != nil {
+		return nil, cleanupParseError(consoleTemplate, err)
+	}
+
+	var buf strings.Builder
+	c.result = nil
+	if err := t.Execute(&buf, c.vals); err != nil {
+		return nil, cleanupExecError(consoleTemplate, err)
+	}
+	// As Render does, remove the "<no value>" Go emits for missing values.
+	if isTemplate {
+		return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+	}
+	if s, ok := c.result.(string); ok {
+		return strings.ReplaceAll(s, "<no value>", ""), nil
+	}
+	return c.result, nil
+}
+
+// TemplateNames returns the names of the named templates, sorted.
+func (c *Console) TemplateNames() []string {
+	var names []string
+	for _, t := range c.t.Templates() {
+		name := t.Name()
+		if t.Tree == nil || name == consoleTemplate || name == t.Tree.ParseName {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Complete returns the completions of the end of line, which replace
+// line[start:]: the names of the named templates after an opening quote,
+// e.g. 'include "mychart.', and the fields of objects after a path, e.g.
+// '.Values.ima'.
+func (c *Console) Complete(line string) (start int, completions []string) {
+	if strings.Count(line, `"`)%2 == 1 {
+		start = strings.LastIndex(line, `"`) + 1
+		for _, name := range c.TemplateNames() {
+			if strings.HasPrefix(name, line[start:]) {
+				completions = append(completions, name)
+			}
+		}
+		return start, completions
+	}
+
+	start = strings.LastIndexFunc(line, func(r rune) bool {
+		return !(r == '.' || r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	}) + 1
+	token := line[start:]
+	if !strings.HasPrefix(token, ".") {
+		return start, nil
+	}
+
+	dot := strings.LastIndex(token, ".")
+	var v interface{} = c.vals
+	for _, field := range strings.Split(token[:dot], ".") {
+		if field == "" {
+			continue
+		}
+		if v = fieldOf(v, field); v == nil {
+			return start, nil
+		}
+	}
+	for _, field := range fieldsOf(v) {
+		if strings.HasPrefix(field, token[dot+1:]) {
+			completions = append(completions, token[:dot+1]+field)
+		}
+	}
+	return start, completions
+}
+
+// fieldOf returns the value of a key of a map or a field of a struct, as
+// evaluated by templates.
+func fieldOf(v interface{}, name string) interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		if f := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key())); f.IsValid() && f.CanInterface() {
+			return f.Interface()
+		}
+	case reflect.Struct:
+		if f := rv.FieldByName(name); f.IsValid() && f.CanInterface() {
+			return f.Interface()
+		}
+	}
+	return nil
+}
+
+// fieldsOf returns the keys of a map or the exported fields of a struct, sorted.
+func fieldsOf(v interface{}) []string {
+	var fields []string
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range rv.MapKeys() {
+			fields = append(fields, key.String())
+		}
+	case reflect.Struct:
+		for _, f := range reflect.VisibleFields(rv.Type()) {
+			if f.IsExported() && !f.Anonymous {
+				fields = append(fields, f.Name)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
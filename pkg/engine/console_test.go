@@ -0,0 +1,108 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func newTestConsole(t *testing.T) *Console {
+	t.Helper()
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "moby.name" }}{{ .Chart.Name }}{{ end }}
+{{ define "moby.labels" }}app: {{ include "moby.name" . }}{{ end }}`)},
+			{Name: "templates/deployment.yaml", Data: []byte(`kind: Deployment`)},
+		},
+		Values: map[string]interface{}{
+			"image":            map[string]interface{}{"repository": "nginx", "tag": "stable"},
+			"imagePullSecrets": []interface{}{},
+			"replicas":         3,
+		},
+	}
+	vals := renderValues(t, c)
+	vals["Values"] = c.Values
+	console, err := Engine{}.NewConsole(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return console
+}
+
+func TestConsoleEval(t *testing.T) {
+	console := newTestConsole(t)
+
+	tests := []struct {
+		input string
+		want  interface{}
+	}{
+		{input: ".Values.replicas", want: 3},
+		{input: ".Values.image", want: map[string]interface{}{"repository": "nginx", "tag": "stable"}},
+		{input: `include "moby.labels" . | fromYaml`, want: map[string]interface{}{"app": "moby"}},
+		{input: `.Template.Name`, want: "moby/templates/console"},
+		{input: `{{ define "moby.tag" }}{{ .Values.image.tag }}{{ end }}tag: {{ include "moby.tag" . }}`, want: "tag: stable"},
+		{input: `include "moby.tag" . | upper`, want: "STABLE"},
+		{input: `{{ define "moby.image" }}{{ .Values.image.repository }}@{{ .Values.image.digest }}{{ end }}`, want: ""},
+		{input: `include "moby.image" .`, want: "nginx@"},
+	}
+	for _, tt := range tests {
+		got, err := console.Eval(tt.input)
+		if err != nil {
+			t.Errorf("%s: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %#v, got %#v", tt.input, tt.want, got)
+		}
+	}
+
+	if _, err := console.Eval(`fail "boom"`); err == nil || !strings.Contains(err.Error(), "execution error at (console:1:") {
+		t.Errorf("expected an execution error, got %v", err)
+	}
+	if _, err := console.Eval(`.Values.(`); err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestConsoleComplete(t *testing.T) {
+	console := newTestConsole(t)
+
+	tests := []struct {
+		line  string
+		start int
+		want  []string
+	}{
+		{line: ".Values.ima", start: 0, want: []string{".Values.image", ".Values.imagePullSecrets"}},
+		{line: "toYaml .Values.image.r", start: 7, want: []string{".Values.image.repository"}},
+		{line: ".Chart.Na", start: 0, want: []string{".Chart.Name"}},
+		{line: ".Rel", start: 0, want: []string{".Release"}},
+		{line: `include "moby.`, start: 9, want: []string{"moby.labels", "moby.name"}},
+		{line: ".Values.missing.", start: 0},
+		{line: "upper", start: 0},
+	}
+	for _, tt := range tests {
+		start, got := console.Complete(tt.line)
+		if start != tt.start || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %d %v, got %d %v", tt.line, tt.start, tt.want, start, got)
+		}
+	}
+}
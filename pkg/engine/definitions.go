@@ -0,0 +1,144 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateDefinitions records where the named templates of a chart and its
+// subcharts are defined.
+//
+// All templates share a single namespace, so when a name is defined more than
+// once, the definition parsed last is used. Templates of subcharts are parsed
+// before the templates of their parents.
+//
+// TemplateDefinitions is attached to an Engine through Engine.Definitions and
+// is filled in by Render.
+type TemplateDefinitions struct {
+	mu   sync.Mutex
+	defs map[string][]templateDefinition
+}
+
+type templateDefinition struct {
+	location SourceLocation
+	body     string
+}
+
+// TemplateCollision is a named template defined more than once with
+// different contents.
+type TemplateCollision struct {
+	// Name is the name of the template.
+	Name string
+	// Locations are the definitions of the template, in the order they are
+	// parsed. The last one is the one in use.
+	Locations []SourceLocation
+}
+
+// String describes the collision, e.g. for a warning or an error.
+func (c TemplateCollision) String() string {
+	locs := make([]string, 0, len(c.Locations))
+	for _, l := range c.Locations {
+		locs = append(locs, l.String())
+	}
+	return fmt.Sprintf("named template %q is defined in %s and %s with different contents, the latter is used",
+		c.Name, strings.Join(locs[:len(locs)-1], ", "), locs[len(locs)-1])
+}
+
+// NewTemplateDefinitions creates an empty TemplateDefinitions.
+func NewTemplateDefinitions() *TemplateDefinitions {
+	return &TemplateDefinitions{defs: make(map[string][]templateDefinition)}
+}
+
+func (d *TemplateDefinitions) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.defs = make(map[string][]templateDefinition)
+}
+
+// collect records the templates defined by a file, which has just been parsed
+// into t.
+func (d *TemplateDefinitions) collect(t *template.Template, filename string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, tmpl := range t.Templates() {
+		tree := tmpl.Tree
+		if tree == nil || tree.ParseName != filename || tmpl.Name() == filename || parse.IsEmptyTree(tree.Root) {
+			continue
+		}
+		location, _ := tree.ErrorContext(tree.Root)
+		d.defs[tmpl.Name()] = append(d.defs[tmpl.Name()], templateDefinition{
+			location: SourceLocation{Template: filename, Line: locationLine(filename, location)},
+			body:     tree.Root.String(),
+		})
+	}
+}
+
+// Names returns the names of the defined templates, sorted.
+func (d *TemplateDefinitions) Names() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	names := make([]string, 0, len(d.defs))
+	for name := range d.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locations returns the definitions of a named template, in the order they
+// are parsed.
+func (d *TemplateDefinitions) Locations(name string) []SourceLocation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	locs := make([]SourceLocation, 0, len(d.defs[name]))
+	for _, def := range d.defs[name] {
+		locs = append(locs, def.location)
+	}
+	return locs
+}
+
+// Collisions returns the named templates defined more than once with
+// different contents, sorted by name. Identical copies of a template, e.g.
+// from a library chart used by several subcharts, are not collisions.
+func (d *TemplateDefinitions) Collisions() []TemplateCollision {
+	var collisions []TemplateCollision
+	for _, name := range d.Names() {
+		d.mu.Lock()
+		defs := d.defs[name]
+		d.mu.Unlock()
+
+		differ := false
+		for _, def := range defs[1:] {
+			differ = differ || def.body != defs[0].body
+		}
+		if !differ {
+			continue
+		}
+		c := TemplateCollision{Name: name}
+		for _, def := range defs {
+			c.Locations = append(c.Locations, def.location)
+		}
+		collisions = append(collisions, c)
+	}
+	return collisions
+}
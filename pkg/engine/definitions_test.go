@@ -0,0 +1,101 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func definitionsChart() *chart.Chart {
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "parent", Version: "1.0.0"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "name" }}parent{{ end }}
+{{ define "labels" }}app: web{{ end }}`)},
+			{Name: "templates/cm.yaml", Data: []byte(`name: {{ include "name" . }}`)},
+		},
+	}
+	for _, name := range []string{"first", "second"} {
+		sub := &chart.Chart{
+			Metadata: &chart.Metadata{Name: name, Version: "1.0.0"},
+			Templates: []*chart.File{
+				{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "labels" }}app: web{{ end }}`)},
+				{Name: "templates/_name.tpl", Data: []byte(`{{ define "name" }}` + name + `{{ end }}`)},
+			},
+		}
+		parent.AddDependency(sub)
+	}
+	return parent
+}
+
+func TestTemplateDefinitions(t *testing.T) {
+	c := definitionsChart()
+	defs := NewTemplateDefinitions()
+	out, err := Engine{Definitions: defs}.Render(c, renderValues(t, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out["parent/templates/cm.yaml"]; got != "name: parent" {
+		t.Errorf("expected the definition of the parent chart to be used, got %q", got)
+	}
+
+	if names := defs.Names(); !reflect.DeepEqual(names, []string{"labels", "name"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+	if locs := defs.Locations("labels"); len(locs) != 3 {
+		t.Errorf("expected 3 definitions of labels, got %v", locs)
+	}
+
+	// Identical copies of "labels" are not collisions.
+	collisions := defs.Collisions()
+	if len(collisions) != 1 || collisions[0].Name != "name" {
+		t.Fatalf("expected a collision of name, got %v", collisions)
+	}
+	want := `named template "name" is defined in parent/charts/second/templates/_name.tpl:1, parent/charts/first/templates/_name.tpl:1 and parent/templates/_helpers.tpl:1 with different contents, the latter is used`
+	if got := collisions[0].String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestStrictTemplateNames(t *testing.T) {
+	c := definitionsChart()
+	_, err := Engine{StrictTemplateNames: true}.Render(c, renderValues(t, c))
+	if err == nil || !strings.Contains(err.Error(), `named template "name" is defined in`) {
+		t.Errorf("expected a collision error, got %v", err)
+	}
+}
+
+func TestTemplateCollisionsAreSilent(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	c := definitionsChart()
+	if _, err := (Engine{}).Render(c, renderValues(t, c)); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() > 0 {
+		t.Errorf("expected collisions not to be reported unless requested, got %q", logs.String())
+	}
+}
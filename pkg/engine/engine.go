@@ -17,7 +17,9 @@ limitations under the License.
 package engine
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"path"
 	"path/filepath"
@@ -44,6 +46,35 @@ type Engine struct {
 	clientProvider *ClientProvider
 	// EnableDNS tells the engine to allow DNS lookups when rendering templates
 	EnableDNS bool
+	// Profile, when set, records the time spent executing each template
+	Profile *RenderProfile
+	// SourceMap, when set, records the template lines each rendered line comes from
+	SourceMap *SourceMap
+	// Funcs, when set, provides additional template functions
+	Funcs *FuncRegistry
+	// Renderers provides additional renderers for templates which are not Go
+	// templates, keyed by the name charts select them with in Chart.yaml
+	Renderers map[string]Renderer
+	// RendererExtensions selects renderers by template file extension, e.g. ".star"
+	RendererExtensions map[string]string
+	// Parallelism is the number of charts whose templates are executed
+	// concurrently. It defaults to GOMAXPROCS; 1 executes them sequentially.
+	// Charts whose templates modify values, or use 'generated' or 'tpl', are
+	// always executed sequentially, as the result depends on the order.
+	Parallelism int
+	// Definitions, when set, records where named templates are defined
+	Definitions *TemplateDefinitions
+	// If StrictTemplateNames is enabled, rendering fails if a named template
+	// is defined more than once with different contents. Otherwise collisions
+	// are only recorded in Definitions, if set.
+	StrictTemplateNames bool
+	// Limits bounds the output of the templates
+	Limits RenderLimits
+	// Generated, when set, provides the values of the 'generated' function
+	// generated by previous revisions, and records the ones it returns
+	Generated *GeneratedValues
+	// budget enforces Limits and the deadline of the current render
+	budget *renderBudget
 }
 
 // New creates a new instance of Engine using the passed in rest config.
@@ -54,6 +85,17 @@ func New(config *rest.Config) Engine {
 	}
 }
 
+// NewWithClientProvider creates a new instance of Engine that uses the passed
+// in ClientProvider to back the 'lookup' function.
+//
+// If the provider is a FixtureClientProvider, 'lookup' is also backed by it
+// in LintMode.
+func NewWithClientProvider(clientProvider ClientProvider) Engine {
+	return Engine{
+		clientProvider: &clientProvider,
+	}
+}
+
 // Render takes a chart, optional values, and value overrides, and attempts to render the Go templates.
 //
 // Render can be called repeatedly on the same engine.
@@ -74,7 +116,17 @@ func New(config *rest.Config) Engine {
 // section contains a value named "bar", that value will be passed on to the
 // bar chart during render time.
 func (e Engine) Render(chrt *chart.Chart, values chartutil.Values) (map[string]string, error) {
-	tmap := allTemplates(chrt, values)
+	return e.RenderContext(context.Background(), chrt, values)
+}
+
+// RenderContext renders the templates like Render, and fails once ctx is
+// cancelled or past its deadline, or once the output exceeds e.Limits.
+func (e Engine) RenderContext(ctx context.Context, chrt *chart.Chart, values chartutil.Values) (map[string]string, error) {
+	if err := e.checkRenderers(chrt); err != nil {
+		return nil, err
+	}
+	e.budget = newRenderBudget(ctx, e.Limits)
+	tmap := e.allTemplates(chrt, values)
 	return e.render(tmap)
 }
 
@@ -112,6 +164,8 @@ type renderable struct {
 	vals chartutil.Values
 	// namespace prefix to the templates of the current chart
 	basePath string
+	// renderer is the name of the renderer of the template, or empty for Go templates.
+	renderer string
 }
 
 const warnStartDelim = "HELM_ERR_START"
@@ -126,8 +180,11 @@ func warnWrap(warn string) string {
 
 // 'include' needs to be defined in the scope of a 'tpl' template as
 // well as regular file-loaded templates.
-func includeFun(t *template.Template, includedNames map[string]int) func(string, interface{}) (string, error) {
+func includeFun(t *template.Template, includedNames map[string]int, profile *RenderProfile, sources *sourceCollector, budget *renderBudget) func(string, interface{}) (string, error) {
 	return func(name string, data interface{}) (string, error) {
+		if err := budget.check(name); err != nil {
+			return "", err
+		}
 		var buf strings.Builder
 		if v, ok := includedNames[name]; ok {
 			if v > recursionMaxNums {
@@ -137,16 +194,27 @@ func includeFun(t *template.Template, includedNames map[string]int) func(string,
 		} else {
 			includedNames[name] = 1
 		}
-		err := t.ExecuteTemplate(&buf, name, data)
+		done := profile.begin(ProfileInclude, name)
+		end := sources.push(true)
+		err := t.ExecuteTemplate(budget.writer(name, &buf), name, data)
+		out := sources.included(end(), buf.String())
+		done(len(out))
 		includedNames[name]--
-		return buf.String(), err
+		return out, err
 	}
 }
 
 // As does 'tpl', so that nested calls to 'tpl' see the templates
-// defined by their enclosing contexts.
-func tplFun(parent *template.Template, includedNames map[string]int, strict bool) func(string, interface{}) (string, error) {
+// defined by their enclosing contexts. depth is the nesting level of the
+// calls.
+func tplFun(parent *template.Template, includedNames map[string]int, strict bool, profile *RenderProfile, sources *sourceCollector, budget *renderBudget, depth int) func(string, interface{}) (string, error) {
 	return func(tpl string, vals interface{}) (string, error) {
+		if err := budget.checkTplDepth(depth); err != nil {
+			return "", err
+		}
+		if err := budget.check("tpl"); err != nil {
+			return "", err
+		}
 		t, err := parent.Clone()
 		if err != nil {
 			return "", errors.Wrapf(err, "cannot clone template")
@@ -164,8 +232,8 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 		// Re-inject 'include' so that it can close over our clone of t;
 		// this lets any 'define's inside tpl be 'include'd.
 		t.Funcs(template.FuncMap{
-			"include": includeFun(t, includedNames),
-			"tpl":     tplFun(t, includedNames, strict),
+			"include": includeFun(t, includedNames, profile, sources, budget),
+			"tpl":     tplFun(t, includedNames, strict, profile, sources, budget, depth+1),
 		})
 
 		// We need a .New template, as template text which is just blanks
@@ -178,9 +246,17 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 		if err != nil {
 			return "", errors.Wrapf(err, "cannot parse template %q", tpl)
 		}
+		budget.instrument(t)
 
 		var buf strings.Builder
-		if err := t.Execute(&buf, vals); err != nil {
+		done := profile.begin(ProfileTpl, "")
+		// The output of tpl cannot be traced back to a template file, so it
+		// is attributed to the calling template.
+		end := sources.push(false)
+		err = t.Execute(budget.writer("tpl", &buf), vals)
+		end()
+		done(buf.Len())
+		if err != nil {
 			return "", errors.Wrapf(err, "error during tpl function execution for %q", tpl)
 		}
 
@@ -190,13 +266,19 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 }
 
 // initFunMap creates the Engine's FuncMap and adds context-specific functions.
-func (e Engine) initFunMap(t *template.Template) {
+func (e Engine) initFunMap(t *template.Template, sources *sourceCollector) {
 	funcMap := funcMap()
 	includedNames := make(map[string]int)
 
 	// Add the template-rendering functions here so we can close over t.
-	funcMap["include"] = includeFun(t, includedNames)
-	funcMap["tpl"] = tplFun(t, includedNames, e.Strict)
+	funcMap["include"] = includeFun(t, includedNames, e.Profile, sources, e.budget)
+	funcMap["tpl"] = tplFun(t, includedNames, e.Strict, e.Profile, sources, e.budget, 1)
+	if sources != nil {
+		funcMap[sourceMarkerFunc] = sources.marker
+	}
+	funcMap[budgetCheckFunc] = func(name string) (string, error) {
+		return "", e.budget.check(name)
+	}
 
 	// Add the `required` function here so we can use lintMode
 	funcMap["required"] = func(warn string, val interface{}) (interface{}, error) {
@@ -220,6 +302,8 @@ func (e Engine) initFunMap(t *template.Template) {
 		return val, nil
 	}
 
+	funcMap["generated"] = e.Generated.get
+
 	// Override sprig fail function for linting and wrapping message
 	funcMap["fail"] = func(msg string) (string, error) {
 		if e.LintMode {
@@ -231,9 +315,12 @@ func (e Engine) initFunMap(t *template.Template) {
 	}
 
 	// If we are not linting and have a cluster connection, provide a Kubernetes-backed
-	// implementation.
-	if !e.LintMode && e.clientProvider != nil {
-		funcMap["lookup"] = newLookupFunction(*e.clientProvider)
+	// implementation. Offline fixtures never talk to a cluster, so they are used
+	// when linting as well.
+	if e.clientProvider != nil {
+		if _, offline := (*e.clientProvider).(*FixtureClientProvider); offline || !e.LintMode {
+			funcMap["lookup"] = newLookupFunction(*e.clientProvider)
+		}
 	}
 
 	// When DNS lookups are not enabled override the sprig function and return
@@ -244,6 +331,10 @@ func (e Engine) initFunMap(t *template.Template) {
 		}
 	}
 
+	// Functions registered by embedders come last so that they can replace
+	// any function but the protected ones.
+	e.Funcs.addTo(funcMap)
+
 	t.Funcs(funcMap)
 }
 
@@ -270,41 +361,122 @@ func (e Engine) render(tpls map[string]renderable) (rendered map[string]string,
 		t.Option("missingkey=zero")
 	}
 
-	e.initFunMap(t)
+	var sources *sourceCollector
+	if e.SourceMap != nil {
+		sources = &sourceCollector{}
+	}
+	e.initFunMap(t, sources)
 
 	// We want to parse the templates in a predictable order. The order favors
 	// higher-level (in file system) templates over deeply nested templates.
 	keys := sortTemplates(tpls)
 
+	// Named templates are only tracked when collisions are reported: parent
+	// charts may override the named templates of their subcharts.
+	defs := e.Definitions
+	if defs == nil && e.StrictTemplateNames {
+		defs = NewTemplateDefinitions()
+	} else if defs != nil {
+		defs.reset()
+	}
 	for _, filename := range keys {
 		r := tpls[filename]
+		if r.renderer != "" {
+			continue
+		}
 		if _, err := t.New(filename).Parse(r.tpl); err != nil {
 			return map[string]string{}, cleanupParseError(filename, err)
 		}
+		if defs != nil && (strings.Contains(r.tpl, "define") || strings.Contains(r.tpl, "block")) {
+			defs.collect(t, filename)
+		}
+	}
+	if e.StrictTemplateNames {
+		if collisions := defs.Collisions(); len(collisions) > 0 {
+			return map[string]string{}, errors.New(collisions[0].String())
+		}
+	}
+	if sources != nil {
+		sources.instrument(t)
+	}
+	// After the source markers, which the checks must not be preceded by.
+	e.budget.instrument(t)
+
+	results, err := e.execute(t, sources, tpls, keys)
+	if err != nil {
+		return map[string]string{}, err
 	}
 
-	rendered = make(map[string]string, len(keys))
+	rendered = make(map[string]string, len(results))
 	for _, filename := range keys {
-		// Don't render partials. We don't care out the direct output of partials.
-		// They are only included from other templates.
-		if strings.HasPrefix(path.Base(filename), "_") {
+		res, ok := results[filename]
+		if !ok {
 			continue
 		}
-		// At render time, add information about the template that is being rendered.
-		vals := tpls[filename].vals
-		vals["Template"] = chartutil.Values{"Name": filename, "BasePath": tpls[filename].basePath}
-		var buf strings.Builder
-		if err := t.ExecuteTemplate(&buf, filename, vals); err != nil {
-			return map[string]string{}, cleanupExecError(filename, err)
+		rendered[filename] = res.out
+		if e.SourceMap != nil {
+			e.SourceMap.add(filename, res.out, res.lines)
 		}
+	}
+	return rendered, nil
+}
 
-		// Work around the issue where Go will emit "<no value>" even if Options(missing=zero)
-		// is set. Since missing=error will never get here, we do not need to handle
-		// the Strict case.
-		rendered[filename] = strings.ReplaceAll(buf.String(), "<no value>", "")
+// renderResult is the output of a template.
+type renderResult struct {
+	out   string
+	lines []SourceLocation
+	err   error
+}
+
+// executeTemplate renders a single template, using vals as its '.'.
+func (w *renderWorker) executeTemplate(filename string, r renderable, vals chartutil.Values) renderResult {
+	if err := w.e.budget.check(filename); err != nil {
+		return renderResult{err: err}
+	}
+	// At render time, add information about the template that is being rendered.
+	vals["Template"] = chartutil.Values{"Name": filename, "BasePath": r.basePath}
+	if r.renderer != "" {
+		out, err := w.e.renderWith(r.renderer, filename, r.tpl, vals)
+		return renderResult{out: out, err: err}
+	}
+	var buf strings.Builder
+	done := w.e.Profile.begin(ProfileTemplate, filename)
+	end := w.sources.push(true)
+	err := w.t.ExecuteTemplate(w.e.budget.templateWriter(filename, &buf), filename, vals)
+	frame := end()
+	out := buf.String()
+	var lines []SourceLocation
+	if w.sources != nil {
+		out, lines = w.sources.resolve(frame, out)
+	}
+	done(len(out))
+	if err != nil {
+		return renderResult{err: cleanupExecError(filename, err)}
 	}
 
-	return rendered, nil
+	// Work around the issue where Go will emit "<no value>" even if Options(missing=zero)
+	// is set. Since missing=error will never get here, we do not need to handle
+	// the Strict case.
+	return renderResult{out: strings.ReplaceAll(out, "<no value>", ""), lines: lines}
+}
+
+// renderWith renders a template with a renderer other than Go templates.
+func (e Engine) renderWith(renderer, filename, tpl string, vals chartutil.Values) (string, error) {
+	r, err := e.renderer(renderer)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot render %s", filename)
+	}
+	done := e.Profile.begin(ProfileTemplate, filename)
+	out, err := r.Render(e.budget.context(), filename, []byte(tpl), vals)
+	done(len(out))
+	if err != nil {
+		return out, err
+	}
+	// Renderers write their output at once, so it is checked afterwards.
+	if _, err := io.WriteString(e.budget.templateWriter(filename, io.Discard), out); err != nil {
+		return "", err
+	}
+	return out, nil
 }
 
 func cleanupParseError(filename string, err error) error {
@@ -371,9 +543,9 @@ func (p byPathLen) Less(i, j int) bool {
 // allTemplates returns all templates for a chart and its dependencies.
 //
 // As it goes, it also prepares the values in a scope-sensitive manner.
-func allTemplates(c *chart.Chart, vals chartutil.Values) map[string]renderable {
+func (e Engine) allTemplates(c *chart.Chart, vals chartutil.Values) map[string]renderable {
 	templates := make(map[string]renderable)
-	recAllTpls(c, templates, vals)
+	e.recAllTpls(c, templates, vals)
 	return templates
 }
 
@@ -381,7 +553,7 @@ func allTemplates(c *chart.Chart, vals chartutil.Values) map[string]renderable {
 //
 // As it recurses, it also sets the values to be appropriate for the template
 // scope.
-func recAllTpls(c *chart.Chart, templates map[string]renderable, vals chartutil.Values) map[string]interface{} {
+func (e Engine) recAllTpls(c *chart.Chart, templates map[string]renderable, vals chartutil.Values) map[string]interface{} {
 	subCharts := make(map[string]interface{})
 	chartMetaData := struct {
 		chart.Metadata
@@ -406,7 +578,7 @@ func recAllTpls(c *chart.Chart, templates map[string]renderable, vals chartutil.
 	}
 
 	for _, child := range c.Dependencies() {
-		subCharts[child.Name()] = recAllTpls(child, templates, next)
+		subCharts[child.Name()] = e.recAllTpls(child, templates, next)
 	}
 
 	newParentID := c.ChartFullPath()
@@ -421,6 +593,7 @@ func recAllTpls(c *chart.Chart, templates map[string]renderable, vals chartutil.
 			tpl:      string(t.Data),
 			vals:     next,
 			basePath: path.Join(newParentID, "templates"),
+			renderer: e.rendererFor(c, t.Name),
 		}
 	}
 
@@ -0,0 +1,114 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"sort"
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// protectedFuncs are the template functions implemented or guarded by the
+// engine itself, which cannot be overridden by a FuncRegistry: 'fail' behaves
+// differently when linting, and 'getHostByName' only resolves names when DNS
+// lookups are enabled.
+var protectedFuncs = map[string]bool{
+	"include":        true,
+	"tpl":            true,
+	"required":       true,
+	"fail":           true,
+	"lookup":         true,
+	"getHostByName":  true,
+	"generated":      true,
+	sourceMarkerFunc: true,
+	budgetCheckFunc:  true,
+}
+
+// FuncRegistry holds additional template functions registered by programs
+// embedding the engine.
+//
+// Registered functions are available to all templates rendered by an Engine
+// whose Funcs field is set to the registry. They take precedence over the
+// Sprig and Helm functions of the same name, except for 'include', 'tpl',
+// 'required', 'fail', 'lookup', 'getHostByName' and 'generated', which cannot
+// be registered.
+type FuncRegistry struct {
+	mu    sync.RWMutex
+	funcs template.FuncMap
+}
+
+// NewFuncRegistry creates an empty FuncRegistry.
+func NewFuncRegistry() *FuncRegistry {
+	return &FuncRegistry{funcs: template.FuncMap{}}
+}
+
+// Register adds a template function to the registry, replacing any function
+// previously registered under the same name.
+//
+// The function must follow the rules of text/template: it returns a single
+// value, or a value and an error.
+func (r *FuncRegistry) Register(name string, fn interface{}) (err error) {
+	if protectedFuncs[name] {
+		return errors.Errorf("template function %q is built in and cannot be overridden", name)
+	}
+	// text/template panics on invalid names and functions, so let it validate
+	// them for us.
+	defer func() {
+		if p := recover(); p != nil {
+			err = errors.Errorf("invalid template function %q: %v", name, p)
+		}
+	}()
+	template.New(name).Funcs(template.FuncMap{name: fn})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[name] = fn
+	return nil
+}
+
+// MustRegister is like Register but panics if the function cannot be registered.
+func (r *FuncRegistry) MustRegister(name string, fn interface{}) {
+	if err := r.Register(name, fn); err != nil {
+		panic(err)
+	}
+}
+
+// Names returns the names of the registered functions, sorted.
+func (r *FuncRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addTo copies the registered functions to a FuncMap.
+func (r *FuncRegistry) addTo(funcMap template.FuncMap) {
+	if r == nil {
+		return
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, fn := range r.funcs {
+		funcMap[name] = fn
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestFuncRegistryRegister(t *testing.T) {
+	r := NewFuncRegistry()
+
+	for _, name := range []string{"include", "tpl", "required", "fail", "lookup", "getHostByName", "generated"} {
+		err := r.Register(name, func() string { return "" })
+		if err == nil || !strings.Contains(err.Error(), "cannot be overridden") {
+			t.Errorf("expected registering %q to fail, got %v", name, err)
+		}
+	}
+
+	if err := r.Register("bad-name", func() string { return "" }); err == nil {
+		t.Error("expected an error for an invalid function name")
+	}
+	if err := r.Register("notAFunc", "value"); err == nil {
+		t.Error("expected an error for a value which is not a function")
+	}
+
+	r.MustRegister("shout", strings.ToUpper)
+	r.MustRegister("b64enc", func(s string) string { return "custom:" + s })
+	if got, want := r.Names(), []string{"b64enc", "shout"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected names %v, got %v", want, got)
+	}
+}
+
+func TestRenderWithFuncRegistry(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "greeting" }}{{ shout "hello" }}{{ end }}`)},
+			{Name: "templates/test1", Data: []byte(`{{ include "greeting" . }} {{ b64enc "world" }}`)},
+			{Name: "templates/test2", Data: []byte(`{{ tpl "{{ shout .Values.who }}" . }}`)},
+		},
+		Values: map[string]interface{}{"who": "moby"},
+	}
+
+	funcs := NewFuncRegistry()
+	funcs.MustRegister("shout", strings.ToUpper)
+	funcs.MustRegister("b64enc", func(s string) string { return "custom:" + s })
+
+	vals, err := chartutil.ToRenderValues(c, map[string]interface{}{}, chartutil.ReleaseOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Engine{Funcs: funcs}.Render(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"moby/templates/test1": "HELLO custom:world",
+		"moby/templates/test2": "MOBY",
+	}
+	for name, want := range expect {
+		if got := out[name]; got != want {
+			t.Errorf("expected %q for %s, got %q", want, name, got)
+		}
+	}
+}
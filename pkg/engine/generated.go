@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"sync"
+)
+
+// GeneratedValues holds the values of the 'generated' template function, so
+// that values generated when a release is installed, such as random
+// passwords, are reused when it is upgraded:
+//
+//	password: {{ generated "db-password" (randAlphaNum 32) | b64enc }}
+//
+// The first call with a given key returns the value it is passed, unless a
+// value was generated for the key by a previous revision of the release. All
+// the calls with the key return the same value. Keys are shared by the chart
+// and its subcharts.
+//
+// GeneratedValues is attached to an Engine through Engine.Generated. Without
+// it, 'generated' returns the value it is passed.
+type GeneratedValues struct {
+	mu       sync.Mutex
+	previous map[string]interface{}
+	values   map[string]interface{}
+}
+
+// NewGeneratedValues creates GeneratedValues reusing the values of a previous
+// revision, except for the keys to regenerate.
+func NewGeneratedValues(previous map[string]interface{}, regenerate ...string) *GeneratedValues {
+	g := &GeneratedValues{
+		previous: make(map[string]interface{}, len(previous)),
+		values:   make(map[string]interface{}),
+	}
+	for k, v := range previous {
+		g.previous[k] = v
+	}
+	for _, k := range regenerate {
+		delete(g.previous, k)
+	}
+	return g
+}
+
+// get returns the value of key, which is value unless a value was generated
+// for key before.
+func (g *GeneratedValues) get(key string, value interface{}) interface{} {
+	if g == nil {
+		return value
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if v, ok := g.values[key]; ok {
+		return v
+	}
+	if v, ok := g.previous[key]; ok {
+		value = v
+	}
+	g.values[key] = value
+	return value
+}
+
+// Values returns the values to store with the release: the values used by
+// the templates, and the values of the previous revision they did not use, so
+// that they are not lost when a template is disabled for a while.
+func (g *GeneratedValues) Values() map[string]interface{} {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.previous) == 0 && len(g.values) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(g.previous)+len(g.values))
+	for k, v := range g.previous {
+		values[k] = v
+	}
+	for k, v := range g.values {
+		values[k] = v
+	}
+	return values
+}
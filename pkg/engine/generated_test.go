@@ -0,0 +1,74 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestGeneratedValues(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/a", Data: []byte(`{{ generated "password" "first" }} {{ generated "user" "admin" }}`)},
+			{Name: "templates/b", Data: []byte(`{{ generated "password" "second" }} {{ generated "token" "new" }}`)},
+		},
+	}
+
+	render := func(g *GeneratedValues) map[string]string {
+		t.Helper()
+		out, err := Engine{Generated: g}.Render(c, renderValues(t, c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	// Without GeneratedValues, the values are passed through.
+	out := render(nil)
+	if out["moby/templates/a"] != "first admin" || out["moby/templates/b"] != "second new" {
+		t.Errorf("expected the values to be passed through, got %v", out)
+	}
+
+	// The first value of a key, in the order the templates are executed, is
+	// used by all the templates.
+	g := NewGeneratedValues(nil)
+	out = render(g)
+	if out["moby/templates/a"] != "second admin" || out["moby/templates/b"] != "second new" {
+		t.Errorf("expected the first value of password to be used, got %v", out)
+	}
+	want := map[string]interface{}{"password": "second", "user": "admin", "token": "new"}
+	if got := g.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Previous values are reused, except for the regenerated keys, and kept
+	// when they are not used.
+	previous := map[string]interface{}{"password": "previous", "user": "root", "unused": "kept"}
+	g = NewGeneratedValues(previous, "user")
+	out = render(g)
+	if out["moby/templates/a"] != "previous admin" || out["moby/templates/b"] != "previous new" {
+		t.Errorf("expected the previous password and a regenerated user, got %v", out)
+	}
+	want = map[string]interface{}{"password": "previous", "user": "admin", "token": "new", "unused": "kept"}
+	if got := g.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
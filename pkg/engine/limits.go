@@ -0,0 +1,205 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+)
+
+// RenderLimits bounds the output of a render, so that a faulty chart, e.g.
+// with a recursive 'include' or a huge 'range', fails instead of exhausting
+// the memory. A zero value means no limit.
+type RenderLimits struct {
+	// MaxOutputBytes is the maximum size of all the rendered templates.
+	MaxOutputBytes int64
+	// MaxTemplateOutputBytes is the maximum size of a rendered template, and
+	// of the output of each 'include' and 'tpl' call.
+	MaxTemplateOutputBytes int64
+	// MaxTplDepth is the maximum number of nested 'tpl' calls.
+	MaxTplDepth int
+}
+
+// renderBudget enforces the limits and the deadline of a render. A nil
+// renderBudget enforces nothing.
+type renderBudget struct {
+	ctx    context.Context
+	limits RenderLimits
+	// total is the size of the output of the templates so far, updated
+	// atomically as templates may execute concurrently.
+	total atomic.Int64
+}
+
+func newRenderBudget(ctx context.Context, limits RenderLimits) *renderBudget {
+	if ctx.Done() == nil && limits == (RenderLimits{}) {
+		return nil
+	}
+	return &renderBudget{ctx: ctx, limits: limits}
+}
+
+// check returns an error once the render is cancelled or past its deadline.
+func (b *renderBudget) check(name string) error {
+	if b == nil {
+		return nil
+	}
+	if err := b.ctx.Err(); err != nil {
+		return errors.Wrapf(err, "rendering %s was interrupted", name)
+	}
+	return nil
+}
+
+// checkTplDepth returns an error if 'tpl' calls nested depth levels deep
+// exceed the limit.
+func (b *renderBudget) checkTplDepth(depth int) error {
+	if b == nil || b.limits.MaxTplDepth <= 0 || depth <= b.limits.MaxTplDepth {
+		return nil
+	}
+	return errors.Errorf("tpl calls are nested more than %d levels deep", b.limits.MaxTplDepth)
+}
+
+// context returns the context of the render.
+func (b *renderBudget) context() context.Context {
+	if b == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+// budgetCheckFunc is the name of the template function checking the budget
+// while templates execute.
+const budgetCheckFunc = "_helmCheckBudget"
+
+// instrument inserts a check of the budget at the start of each template and
+// of each iteration of its range loops, so that templates executing for a
+// long time without output, e.g. a large range, stop once the render is
+// interrupted. Templates instrumented already are left as they are.
+func (b *renderBudget) instrument(t *template.Template) {
+	if b == nil || b.ctx.Done() == nil {
+		return
+	}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+			continue
+		}
+		instrumentBudgetList(tmpl.Tree, tmpl.Tree.Root)
+	}
+}
+
+func instrumentBudgetList(tree *parse.Tree, list *parse.ListNode) {
+	if list == nil || len(list.Nodes) > 0 && isBudgetCheck(list.Nodes[0]) {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.IfNode:
+			instrumentBudgetList(tree, n.List)
+			instrumentBudgetList(tree, n.ElseList)
+		case *parse.RangeNode:
+			instrumentBudgetList(tree, n.List)
+			instrumentBudgetList(tree, n.ElseList)
+		case *parse.WithNode:
+			instrumentBudgetList(tree, n.List)
+			instrumentBudgetList(tree, n.ElseList)
+		}
+	}
+	list.Nodes = append([]parse.Node{newBudgetCheckNode(tree, list.Position())}, list.Nodes...)
+}
+
+// newBudgetCheckNode creates the action node {{ _helmCheckBudget "name" }}.
+func newBudgetCheckNode(tree *parse.Tree, pos parse.Pos) parse.Node {
+	ident := parse.NewIdentifier(budgetCheckFunc).SetTree(tree).SetPos(pos)
+	name := &parse.StringNode{NodeType: parse.NodeString, Pos: pos, Quoted: strconv.Quote(tree.ParseName), Text: tree.ParseName}
+	return &parse.ActionNode{
+		NodeType: parse.NodeAction,
+		Pos:      pos,
+		Pipe: &parse.PipeNode{
+			NodeType: parse.NodePipe,
+			Pos:      pos,
+			Cmds:     []*parse.CommandNode{{NodeType: parse.NodeCommand, Pos: pos, Args: []parse.Node{ident, name}}},
+		},
+	}
+}
+
+func isBudgetCheck(node parse.Node) bool {
+	action, ok := node.(*parse.ActionNode)
+	if !ok || action.Pipe == nil || len(action.Pipe.Cmds) != 1 || len(action.Pipe.Cmds[0].Args) == 0 {
+		return false
+	}
+	ident, ok := action.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == budgetCheckFunc
+}
+
+// writer wraps the buffer the output of an 'include' or 'tpl' call is written
+// to. Writing fails, which stops the execution, once the render is
+// interrupted or the output exceeds the limit.
+func (b *renderBudget) writer(name string, w io.Writer) io.Writer {
+	if b == nil {
+		return w
+	}
+	// A single template cannot exceed the limit of all the templates either.
+	max := b.limits.MaxTemplateOutputBytes
+	if total := b.limits.MaxOutputBytes; total > 0 && (max <= 0 || total < max) {
+		max = total
+	}
+	return &limitWriter{b: b, name: name, w: w, max: max}
+}
+
+// templateWriter is like writer, for the output of a template, which also
+// counts towards the limit of all the templates.
+func (b *renderBudget) templateWriter(name string, w io.Writer) io.Writer {
+	if b == nil {
+		return w
+	}
+	lw := b.writer(name, w).(*limitWriter)
+	lw.total = true
+	return lw
+}
+
+type limitWriter struct {
+	b     *renderBudget
+	name  string
+	w     io.Writer
+	n     int64
+	max   int64
+	total bool
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if err := l.b.check(l.name); err != nil {
+		return 0, err
+	}
+	// The source markers are removed from the output afterwards.
+	if isSourceMarker(p) {
+		return l.w.Write(p)
+	}
+	l.n += int64(len(p))
+	if l.max > 0 && l.n > l.max {
+		return 0, errors.Errorf("output of %s exceeds the limit of %d bytes", l.name, l.max)
+	}
+	if l.total {
+		if max := l.b.limits.MaxOutputBytes; max > 0 && l.b.total.Add(int64(len(p))) > max {
+			return 0, errors.Errorf("rendered templates exceed the limit of %d bytes in total at %s", max, l.name)
+		}
+	}
+	return l.w.Write(p)
+}
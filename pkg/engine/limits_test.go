@@ -0,0 +1,181 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func limitsChart(templates ...string) *chart.Chart {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Values:   map[string]interface{}{"tpl": `{{ tpl .Values.tpl . }}`},
+	}
+	for i, tpl := range templates {
+		c.Templates = append(c.Templates, &chart.File{Name: "templates/test" + string(rune('a'+i)), Data: []byte(tpl)})
+	}
+	return c
+}
+
+func TestRenderLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates []string
+		limits    RenderLimits
+		want      string
+	}{
+		{
+			name:      "template output",
+			templates: []string{`{{ range until 1000 }}line{{ end }}`},
+			limits:    RenderLimits{MaxTemplateOutputBytes: 100},
+			want:      "output of moby/templates/testa exceeds the limit of 100 bytes",
+		},
+		{
+			name:      "include output",
+			templates: []string{`{{ define "big" }}{{ range until 1000 }}line{{ end }}{{ end }}{{ include "big" . | len }}`},
+			limits:    RenderLimits{MaxTemplateOutputBytes: 100},
+			want:      "output of big exceeds the limit of 100 bytes",
+		},
+		{
+			name:      "total output",
+			templates: []string{strings.Repeat("a", 60), strings.Repeat("b", 60)},
+			limits:    RenderLimits{MaxOutputBytes: 100},
+			want:      "rendered templates exceed the limit of 100 bytes in total at moby/templates/testa",
+		},
+		{
+			name:      "tpl depth",
+			templates: []string{`{{ tpl .Values.tpl . }}`},
+			limits:    RenderLimits{MaxTplDepth: 10},
+			want:      "tpl calls are nested more than 10 levels deep",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := limitsChart(tt.templates...)
+			vals := renderValues(t, c)
+			vals["Values"] = c.Values
+			_, err := Engine{Limits: tt.limits}.Render(c, vals)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRenderWithinLimits(t *testing.T) {
+	c := limitsChart(strings.Repeat("a", 60), `{{ tpl "{{ .Release.Name }}" . }}`)
+	limits := RenderLimits{MaxOutputBytes: 100, MaxTemplateOutputBytes: 60, MaxTplDepth: 1}
+	out, err := Engine{Limits: limits}.Render(c, renderValues(t, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out["moby/templates/testb"]; got != "moby" {
+		t.Errorf("expected moby, got %q", got)
+	}
+}
+
+func TestRenderContextDeadline(t *testing.T) {
+	c := limitsChart(`{{ define "inner" }}{{ range until 1000 }}{{ end }}{{ end }}{{ range until 1000000 }}{{ include "inner" . }}{{ end }}`)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := Engine{}.RenderContext(ctx, c, renderValues(t, c))
+	if err == nil || !strings.Contains(err.Error(), "was interrupted: context deadline exceeded") {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the render to stop at the deadline, took %s", elapsed)
+	}
+}
+
+func TestRenderContextDeadlineWithoutOutput(t *testing.T) {
+	tests := map[string]string{
+		"range":     `{{ range until 100000 }}{{ range until 100000 }}{{ end }}{{ end }}`,
+		"recursion": `{{ define "x" }}{{ template "x" . }}{{ template "x" . }}{{ end }}{{ template "x" . }}`,
+		"tpl":       `{{ tpl "{{ range until 100000 }}{{ range until 100000 }}{{ end }}{{ end }}" . }}`,
+	}
+	for name, tpl := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := limitsChart(tpl)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			profile := NewRenderProfile()
+			start := time.Now()
+			_, err := Engine{Profile: profile}.RenderContext(ctx, c, renderValues(t, c))
+			if err == nil || !strings.Contains(err.Error(), "was interrupted: context deadline exceeded") {
+				t.Errorf("expected a deadline error, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("expected the render to stop at the deadline, took %s", elapsed)
+			}
+			// The template has returned, and its profile frames are ended.
+			if profile.caller() != "" {
+				t.Errorf("expected the profile stack to be empty, got %s on top", profile.caller())
+			}
+		})
+	}
+}
+
+func TestRenderContextDeadlineStarlark(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3", Renderer: StarlarkRenderer},
+		Templates: []*chart.File{
+			{Name: "templates/loop.star", Data: []byte("def loop():\n    for i in range(1000000000000):\n        pass\n\nloop()\n")},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := Engine{}.RenderContext(ctx, c, renderValues(t, c))
+	if err == nil || !strings.Contains(err.Error(), "rendering moby/templates/loop.star was interrupted: context deadline exceeded") {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the render to stop at the deadline, took %s", elapsed)
+	}
+}
+
+func TestRenderTotalLimitStopsExecution(t *testing.T) {
+	// The templates are executed in reverse order: testa stops at the
+	// total, before reaching the limit of a single template.
+	c := limitsChart(`{{ range until 1000 }}line{{ end }}`, strings.Repeat("b", 60))
+	limits := RenderLimits{MaxOutputBytes: 100}
+	_, err := Engine{Limits: limits}.Render(c, renderValues(t, c))
+	if err == nil || !strings.Contains(err.Error(), "rendered templates exceed the limit of 100 bytes in total at moby/templates/testa") {
+		t.Errorf("expected a total limit error, got %v", err)
+	}
+}
+
+func TestRenderLimitsWithSourceMap(t *testing.T) {
+	c := limitsChart("a: 1\nb: 2\nc: 3\n")
+	limits := RenderLimits{MaxTemplateOutputBytes: 15}
+	out, err := Engine{Limits: limits, SourceMap: NewSourceMap()}.Render(c, renderValues(t, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out["moby/templates/testa"]; got != "a: 1\nb: 2\nc: 3\n" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
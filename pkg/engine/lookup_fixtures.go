@@ -0,0 +1,253 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// FixtureClientProvider is a ClientProvider backed by an in-memory set of
+// objects instead of a Kubernetes cluster.
+//
+// It allows templates using 'lookup' to be rendered offline, for example by
+// 'helm template' and 'helm lint' or in unit tests. A kind is treated as
+// namespaced if any of its fixtures sets metadata.namespace. Namespaced
+// fixtures without a namespace are placed in the "default" namespace.
+type FixtureClientProvider struct {
+	objects    []*unstructured.Unstructured
+	namespaced map[schema.GroupVersionKind]bool
+}
+
+var _ ClientProvider = &FixtureClientProvider{}
+
+// NewFixtureClientProvider creates a FixtureClientProvider serving the given objects.
+func NewFixtureClientProvider(objs ...*unstructured.Unstructured) (*FixtureClientProvider, error) {
+	p := &FixtureClientProvider{
+		namespaced: make(map[schema.GroupVersionKind]bool),
+	}
+	for _, obj := range objs {
+		if obj.GetNamespace() != "" {
+			p.namespaced[obj.GroupVersionKind()] = true
+		}
+	}
+
+	seen := make(map[string]bool, len(objs))
+	for _, obj := range objs {
+		obj = obj.DeepCopy()
+		gvk := obj.GroupVersionKind()
+		if p.namespaced[gvk] && obj.GetNamespace() == "" {
+			obj.SetNamespace("default")
+		}
+		id := strings.Join([]string{gvk.String(), obj.GetNamespace(), obj.GetName()}, "/")
+		if seen[id] {
+			return nil, errors.Errorf("duplicate lookup fixture %s %q in namespace %q", gvk.Kind, obj.GetName(), obj.GetNamespace())
+		}
+		seen[id] = true
+		p.objects = append(p.objects, obj)
+	}
+	return p, nil
+}
+
+// LoadLookupFixtures reads all YAML and JSON manifests below dir and returns
+// a FixtureClientProvider serving the objects they contain.
+//
+// Files may contain multiple documents separated by '---'. Objects of a kind
+// ending in "List" are expanded into their items.
+func LoadLookupFixtures(dir string) (*FixtureClientProvider, error) {
+	var objs []*unstructured.Unstructured
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fileObjs, err := decodeFixtures(data)
+		if err != nil {
+			return errors.Wrapf(err, "unable to load lookup fixtures from %s", path)
+		}
+		objs = append(objs, fileObjs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewFixtureClientProvider(objs...)
+}
+
+// decodeFixtures decodes every object found in a stream of YAML or JSON documents.
+func decodeFixtures(data []byte) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var doc map[string]interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: doc}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, err
+			}
+			for i := range list.Items {
+				item := list.Items[i]
+				if err := validateFixture(&item); err != nil {
+					return nil, err
+				}
+				objs = append(objs, &item)
+			}
+			continue
+		}
+		if err := validateFixture(obj); err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+func validateFixture(obj *unstructured.Unstructured) error {
+	if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+		return errors.New("object is missing apiVersion or kind")
+	}
+	if obj.GetName() == "" {
+		return errors.Errorf("%s object is missing metadata.name", obj.GetKind())
+	}
+	return nil
+}
+
+// GetClientFor returns a client over the fixtures of the given apiVersion and kind.
+func (p *FixtureClientProvider) GetClientFor(apiVersion, kind string) (dynamic.NamespaceableResourceInterface, bool, error) {
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return &fixtureResource{provider: p, gvk: gvk, gvr: gvr}, p.namespaced[gvk], nil
+}
+
+// fixtureResource is a read-only client over the fixtures of a kind, in a
+// namespace or in all namespaces.
+type fixtureResource struct {
+	provider  *FixtureClientProvider
+	gvk       schema.GroupVersionKind
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+var _ dynamic.NamespaceableResourceInterface = &fixtureResource{}
+
+func (r *fixtureResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &fixtureResource{provider: r.provider, gvk: r.gvk, gvr: r.gvr, namespace: namespace}
+}
+
+func (r *fixtureResource) Get(_ context.Context, name string, _ metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if len(subresources) > 0 {
+		return nil, r.notSupported("get")
+	}
+	for _, obj := range r.provider.objects {
+		if obj.GroupVersionKind() == r.gvk && obj.GetNamespace() == r.namespace && obj.GetName() == name {
+			return obj.DeepCopy(), nil
+		}
+	}
+	return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+}
+
+// List returns the fixtures of the kind in the namespace of the client, or in
+// all namespaces if it has none.
+func (r *fixtureResource) List(_ context.Context, _ metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion(r.gvk.GroupVersion().String())
+	list.SetKind(r.gvk.Kind + "List")
+	for _, obj := range r.provider.objects {
+		if obj.GroupVersionKind() == r.gvk && (r.namespace == "" || obj.GetNamespace() == r.namespace) {
+			list.Items = append(list.Items, *obj.DeepCopy())
+		}
+	}
+	return list, nil
+}
+
+func (r *fixtureResource) notSupported(verb string) error {
+	return apierrors.NewMethodNotSupported(r.gvr.GroupResource(), verb)
+}
+
+func (r *fixtureResource) Create(context.Context, *unstructured.Unstructured, metav1.CreateOptions, ...string) (*unstructured.Unstructured, error) {
+	return nil, r.notSupported("create")
+}
+
+func (r *fixtureResource) Update(context.Context, *unstructured.Unstructured, metav1.UpdateOptions, ...string) (*unstructured.Unstructured, error) {
+	return nil, r.notSupported("update")
+}
+
+func (r *fixtureResource) UpdateStatus(context.Context, *unstructured.Unstructured, metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return nil, r.notSupported("update")
+}
+
+func (r *fixtureResource) Delete(context.Context, string, metav1.DeleteOptions, ...string) error {
+	return r.notSupported("delete")
+}
+
+func (r *fixtureResource) DeleteCollection(context.Context, metav1.DeleteOptions, metav1.ListOptions) error {
+	return r.notSupported("deletecollection")
+}
+
+func (r *fixtureResource) Watch(context.Context, metav1.ListOptions) (watch.Interface, error) {
+	return nil, r.notSupported("watch")
+}
+
+func (r *fixtureResource) Patch(context.Context, string, types.PatchType, []byte, metav1.PatchOptions, ...string) (*unstructured.Unstructured, error) {
+	return nil, r.notSupported("patch")
+}
+
+func (r *fixtureResource) Apply(context.Context, string, *unstructured.Unstructured, metav1.ApplyOptions, ...string) (*unstructured.Unstructured, error) {
+	return nil, r.notSupported("apply")
+}
+
+func (r *fixtureResource) ApplyStatus(context.Context, string, *unstructured.Unstructured, metav1.ApplyOptions) (*unstructured.Unstructured, error) {
+	return nil, r.notSupported("apply")
+}
@@ -0,0 +1,162 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+const testFixtures = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: prod
+data:
+  password: c2VjcmV0
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: staging
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+`
+
+const testListFixtures = `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}}
+  ]
+}`
+
+func TestLoadLookupFixtures(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secrets.yaml"), []byte(testFixtures), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested", "list.json"), []byte(testListFixtures), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := LoadLookupFixtures(dir)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %s", err)
+	}
+
+	cases := map[string]struct {
+		template string
+		output   string
+	}{
+		"secret-single": {
+			template: `{{ (lookup "v1" "Secret" "prod" "db").data.password | b64dec }}`,
+			output:   "secret",
+		},
+		"secret-other-namespace": {
+			template: `{{ (lookup "v1" "Secret" "dev" "db") }}`,
+			output:   "map[]",
+		},
+		"secret-all-namespaces": {
+			template: `{{ (lookup "v1" "Secret" "" "").items | len }}`,
+			output:   "2",
+		},
+		"namespace-single": {
+			template: `{{ (lookup "v1" "Namespace" "" "prod").metadata.name }}`,
+			output:   "prod",
+		},
+		"configmap-from-list": {
+			template: `{{ (lookup "v1" "ConfigMap" "default" "settings").metadata.name }}`,
+			output:   "settings",
+		},
+		"no-fixtures-for-kind": {
+			template: `{{ (lookup "apps/v1" "Deployment" "" "").items | len }}`,
+			output:   "0",
+		},
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:    "moby",
+			Version: "1.2.3",
+		},
+		Values: map[string]interface{}{},
+	}
+	for name, exp := range cases {
+		c.Templates = append(c.Templates, &chart.File{
+			Name: path.Join("templates", name),
+			Data: []byte(exp.template),
+		})
+	}
+
+	v, err := chartutil.CoalesceValues(c, map[string]interface{}{"Values": map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("Failed to coalesce values: %s", err)
+	}
+
+	for _, lintMode := range []bool{false, true} {
+		e := NewWithClientProvider(provider)
+		e.LintMode = lintMode
+		out, err := e.Render(c, v)
+		if err != nil {
+			t.Fatalf("Failed to render templates: %s", err)
+		}
+		for name, want := range cases {
+			key := path.Join("moby/templates", name)
+			if out[key] != want.output {
+				t.Errorf("%s (lint mode %t): expected %q, got %q", name, lintMode, want.output, out[key])
+			}
+		}
+	}
+}
+
+func TestLoadLookupFixturesErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing name": "apiVersion: v1\nkind: Secret\n",
+		"missing kind": "apiVersion: v1\nmetadata:\n  name: db\n",
+		"duplicate":    "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "fixtures.yaml"), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadLookupFixtures(dir); err == nil {
+				t.Errorf("expected an error loading fixtures")
+			} else if name != "duplicate" && !strings.Contains(err.Error(), "fixtures.yaml") {
+				t.Errorf("expected error to reference the file, got %q", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// renderWorker executes templates. The 'include' and 'tpl' functions keep
+// state while a template executes, so each worker has its own copy of the
+// parsed templates bound to its own functions.
+type renderWorker struct {
+	e       Engine
+	t       *template.Template
+	sources *sourceCollector
+}
+
+// renderGroup is the templates of a chart, which share their values and are
+// therefore executed in order by the same worker.
+type renderGroup struct {
+	keys []string
+	vals chartutil.Values
+}
+
+// execute renders all the templates but partials, and returns their results
+// keyed by name.
+//
+// The templates of each chart are executed in order, while charts are executed
+// concurrently by up to Engine.Parallelism workers, unless the templates call
+// functions whose effects depend on the execution order. The first error in
+// the order of keys is returned, which is the one a sequential execution would
+// have stopped at.
+func (e Engine) execute(t *template.Template, sources *sourceCollector, tpls map[string]renderable, keys []string) (map[string]renderResult, error) {
+	var rendered []string
+	for _, filename := range keys {
+		// Don't render partials. We don't care out the direct output of partials.
+		// They are only included from other templates.
+		if !strings.HasPrefix(path.Base(filename), "_") {
+			rendered = append(rendered, filename)
+		}
+	}
+
+	groups := groupTemplates(tpls, rendered)
+	workers := e.Parallelism
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+	// The profile records a single call stack, so profiled renders are
+	// always sequential.
+	if workers > 1 && e.Profile == nil && !usesOrderedFuncs(t) {
+		if results, ok := e.executeParallel(t, sources, tpls, groups, workers); ok {
+			return results, firstError(results, rendered)
+		}
+	}
+
+	// Sequential execution, in the order of keys, on the shared values.
+	w := &renderWorker{e: e, t: t, sources: sources}
+	results := make(map[string]renderResult, len(rendered))
+	for _, filename := range rendered {
+		res := w.executeTemplate(filename, tpls[filename], tpls[filename].vals)
+		results[filename] = res
+		if res.err != nil {
+			return results, res.err
+		}
+	}
+	return results, nil
+}
+
+// executeParallel executes the groups of templates concurrently, each on a
+// deep copy of its values. It returns false if the templates could not be
+// prepared for concurrent execution, before executing any of them.
+func (e Engine) executeParallel(t *template.Template, sources *sourceCollector, tpls map[string]renderable, groups []renderGroup, workers int) (map[string]renderResult, bool) {
+	// Clone the templates up front, as cloning is not safe while they execute.
+	pool := make(chan *renderWorker, workers)
+	for i := 0; i < workers; i++ {
+		clone, err := t.Clone()
+		if err != nil {
+			return nil, false
+		}
+		w := &renderWorker{e: e, t: clone, sources: sources.fork()}
+		e.initFunMap(clone, w.sources)
+		pool <- w
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]renderResult)
+	)
+	for _, g := range groups {
+		wg.Add(1)
+		go func(g renderGroup) {
+			defer wg.Done()
+			w := <-pool
+			defer func() { pool <- w }()
+
+			vals := copyRenderValues(g.vals)
+			groupResults := make(map[string]renderResult, len(g.keys))
+			for _, filename := range g.keys {
+				res := w.executeTemplate(filename, tpls[filename], vals)
+				groupResults[filename] = res
+				if res.err != nil {
+					break
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for filename, res := range groupResults {
+				results[filename] = res
+			}
+		}(g)
+	}
+	wg.Wait()
+	return results, true
+}
+
+// orderedFuncs are the template functions whose effects depend on the order
+// the templates are executed in: they modify values the templates of other
+// charts may see, or, for 'generated', return the value of the first call
+// with a key. The templates 'tpl' executes are not known in advance, and may
+// call them.
+var orderedFuncs = map[string]bool{
+	"set":                true,
+	"unset":              true,
+	"merge":              true,
+	"mergeOverwrite":     true,
+	"mustMerge":          true,
+	"mustMergeOverwrite": true,
+	"generated":          true,
+	"tpl":                true,
+}
+
+// usesOrderedFuncs reports whether any of the templates, including partials
+// and named templates, calls one of the orderedFuncs.
+func usesOrderedFuncs(t *template.Template) bool {
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil && nodeUsesOrderedFuncs(tt.Tree.Root) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeUsesOrderedFuncs(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Nodes {
+			if nodeUsesOrderedFuncs(c) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return nodeUsesOrderedFuncs(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Cmds {
+			if nodeUsesOrderedFuncs(c) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if nodeUsesOrderedFuncs(arg) {
+				return true
+			}
+		}
+	case *parse.ChainNode:
+		return nodeUsesOrderedFuncs(n.Node)
+	case *parse.IdentifierNode:
+		return orderedFuncs[n.Ident]
+	case *parse.IfNode:
+		return branchUsesOrderedFuncs(&n.BranchNode)
+	case *parse.RangeNode:
+		return branchUsesOrderedFuncs(&n.BranchNode)
+	case *parse.WithNode:
+		return branchUsesOrderedFuncs(&n.BranchNode)
+	case *parse.TemplateNode:
+		return nodeUsesOrderedFuncs(n.Pipe)
+	}
+	return false
+}
+
+func branchUsesOrderedFuncs(n *parse.BranchNode) bool {
+	return nodeUsesOrderedFuncs(n.Pipe) || nodeUsesOrderedFuncs(n.List) || nodeUsesOrderedFuncs(n.ElseList)
+}
+
+// groupTemplates groups the templates by chart, keeping the order of keys.
+func groupTemplates(tpls map[string]renderable, keys []string) []renderGroup {
+	var groups []renderGroup
+	index := make(map[string]int)
+	for _, filename := range keys {
+		basePath := tpls[filename].basePath
+		i, ok := index[basePath]
+		if !ok {
+			i = len(groups)
+			index[basePath] = i
+			groups = append(groups, renderGroup{vals: tpls[filename].vals})
+		}
+		groups[i].keys = append(groups[i].keys, filename)
+	}
+	return groups
+}
+
+// firstError returns the error of the first template in the order of keys.
+func firstError(results map[string]renderResult, keys []string) error {
+	for _, filename := range keys {
+		if res, ok := results[filename]; ok && res.err != nil {
+			return res.err
+		}
+	}
+	return nil
+}
+
+// copyRenderValues copies the values passed to the templates of a chart. The
+// maps and lists templates can modify are copied deeply, other objects, such as
+// files, are shared.
+func copyRenderValues(vals chartutil.Values) chartutil.Values {
+	return copyValue(vals).(chartutil.Values)
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case chartutil.Values:
+		c := make(chartutil.Values, len(v))
+		for k, e := range v {
+			c[k] = copyValue(e)
+		}
+		return c
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			c[k] = copyValue(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			c[i] = copyValue(e)
+		}
+		return c
+	}
+	return v
+}
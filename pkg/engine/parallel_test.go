@@ -0,0 +1,234 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// umbrellaChart creates a chart with n subcharts, each including a named
+// template of the parent chart.
+func umbrellaChart(n int, subchartTemplate string) *chart.Chart {
+	parent := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "umbrella", Version: "1.0.0"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "umbrella.labels" }}app: {{ .Chart.Name }}
+release: {{ .Release.Name }}{{ end }}`)},
+			{Name: "templates/configmap.yaml", Data: []byte(`kind: ConfigMap
+data:
+{{- range $name, $sub := .Values }}
+  {{ $name }}: {{ $sub.replicas | default "none" | quote }}
+{{- end }}`)},
+		},
+		Values: map[string]interface{}{},
+	}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("sub%02d", i)
+		sub := &chart.Chart{
+			Metadata: &chart.Metadata{Name: name, Version: "1.0.0"},
+			Templates: []*chart.File{
+				{Name: "templates/deployment.yaml", Data: []byte(subchartTemplate)},
+				{Name: "templates/service.yaml", Data: []byte(`kind: Service
+metadata:
+  name: {{ .Chart.Name }}
+  labels:
+    {{- include "umbrella.labels" . | nindent 4 }}
+spec:
+  port: {{ .Values.port }}`)},
+			},
+			Values: map[string]interface{}{"replicas": i, "port": 8000 + i},
+		}
+		parent.AddDependency(sub)
+		parent.Values[name] = sub.Values
+	}
+	return parent
+}
+
+const parallelDeployment = `kind: Deployment
+metadata:
+  name: {{ .Chart.Name }}
+  labels:
+    {{- include "umbrella.labels" . | nindent 4 }}
+spec:
+  replicas: {{ .Values.replicas }}`
+
+func renderUmbrella(t *testing.T, c *chart.Chart, parallelism int) (map[string]string, *SourceMap, error) {
+	t.Helper()
+	vals, err := chartutil.ToRenderValues(c, c.Values, chartutil.ReleaseOptions{Name: "moby"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceMap := NewSourceMap()
+	out, err := Engine{Parallelism: parallelism, SourceMap: sourceMap}.Render(c, vals)
+	return out, sourceMap, err
+}
+
+func TestParallelRenderIsIdentical(t *testing.T) {
+	c := umbrellaChart(20, parallelDeployment)
+
+	want, wantMap, err := renderUmbrella(t, c, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, parallelism := range []int{0, 4, 32} {
+		got, gotMap, err := renderUmbrella(t, c, parallelism)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("parallelism %d: expected the output of a sequential render", parallelism)
+		}
+		for _, name := range wantMap.Files() {
+			wf, _ := wantMap.file(name)
+			gf, _ := gotMap.file(name)
+			if !reflect.DeepEqual(wf, gf) {
+				t.Errorf("parallelism %d: expected the source map of a sequential render for %s", parallelism, name)
+			}
+		}
+	}
+
+	loc, ok := wantMap.Lookup("umbrella/charts/sub07/templates/service.yaml", 5)
+	if !ok || loc.Template != "umbrella/templates/_helpers.tpl" || loc.Line != 1 {
+		t.Errorf("expected the labels of sub07 to come from the helpers of the parent chart, got %v", loc)
+	}
+}
+
+func TestParallelRenderErrorOrder(t *testing.T) {
+	c := umbrellaChart(10, `kind: Deployment
+spec:
+  replicas: {{ if ge (int .Values.replicas) 3 }}{{ fail (printf "%s failed" .Chart.Name) }}{{ end }}`)
+
+	_, _, want := renderUmbrella(t, c, 1)
+	if want == nil {
+		t.Fatal("expected an error")
+	}
+	for i := 0; i < 10; i++ {
+		_, _, err := renderUmbrella(t, c, 8)
+		if err == nil || err.Error() != want.Error() {
+			t.Fatalf("expected error %q, got %v", want, err)
+		}
+	}
+}
+
+func TestParallelRenderModifiedValues(t *testing.T) {
+	// Subcharts are rendered before their parent, which sees the values they set.
+	c := umbrellaChart(6, `{{- $_ := set .Values "replicas" "set-by-subchart" -}}
+kind: Deployment`)
+
+	want, _, err := renderUmbrella(t, c, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm := want["umbrella/templates/configmap.yaml"]; !strings.Contains(cm, `sub03: "set-by-subchart"`) {
+		t.Fatalf("expected the parent chart to see the values set by its subcharts, got %q", cm)
+	}
+
+	c = umbrellaChart(6, `{{- $_ := set .Values "replicas" "set-by-subchart" -}}
+kind: Deployment`)
+	got, _, err := renderUmbrella(t, c, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected the output of a sequential render, got %v", got)
+	}
+}
+
+func TestParallelRenderExecutesOnce(t *testing.T) {
+	// Templates modifying values are executed once, sequentially, and not
+	// rendered concurrently first.
+	c := umbrellaChart(6, `{{- $_ := set .Values "replicas" (count) -}}
+kind: Deployment`)
+	vals, err := chartutil.ToRenderValues(c, c.Values, chartutil.ReleaseOptions{Name: "moby"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int32
+	funcs := NewFuncRegistry()
+	funcs.MustRegister("count", func() int32 { return atomic.AddInt32(&calls, 1) })
+	if _, err := (Engine{Parallelism: 4, Funcs: funcs}).Render(c, vals); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 6 {
+		t.Errorf("expected the templates of the 6 subcharts to be executed once, got %d calls", calls)
+	}
+}
+
+func TestParallelRenderGenerated(t *testing.T) {
+	// The value of a key shared by subcharts does not depend on the
+	// parallelism.
+	c := umbrellaChart(8, `kind: Secret
+data:
+  password: {{ generated "password" .Chart.Name }}`)
+	render := func(parallelism int) (map[string]string, interface{}) {
+		vals, err := chartutil.ToRenderValues(c, c.Values, chartutil.ReleaseOptions{Name: "moby"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated := NewGeneratedValues(nil)
+		out, err := Engine{Parallelism: parallelism, Generated: generated}.Render(c, vals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out, generated.Values()["password"]
+	}
+
+	want, wantPassword := render(1)
+	for i := 0; i < 10; i++ {
+		got, password := render(8)
+		if !reflect.DeepEqual(want, got) || password != wantPassword {
+			t.Fatalf("expected the output and the password %v of a sequential render, got %v", wantPassword, password)
+		}
+	}
+}
+
+func TestUsesOrderedFuncs(t *testing.T) {
+	tests := map[string]bool{
+		`{{ .Values.name | quote }}`:                                             false,
+		`{{ include "x" . }}{{ define "x" }}{{ .Values.name }}{{ end }}`:         false,
+		`{{ $_ := set .Values "name" "x" }}`:                                     true,
+		`{{ if .Values.on }}{{ else }}{{ $_ := unset .Values "name" }}{{ end }}`: true,
+		`{{ range .Values.list }}{{ merge $ . }}{{ end }}`:                       true,
+		`{{ define "x" }}{{ generated "key" "value" }}{{ end }}`:                 true,
+		`{{ with .Values.config }}{{ tpl . $ }}{{ end }}`:                        true,
+		`{{ template "x" (mergeOverwrite dict .) }}{{ define "x" }}{{ end }}`:    true,
+		`{{ (set .Values "a" 1).a }}`:                                            true,
+		`{{ $x := dict }}{{ (merge $x .Values).foo }}`:                           true,
+		`{{ (.Values.config).name }}`:                                            false,
+	}
+	for text, want := range tests {
+		tpl, err := template.New("t").Funcs(funcMap()).Funcs(template.FuncMap{
+			"include":   func(string, interface{}) string { return "" },
+			"tpl":       func(string, interface{}) string { return "" },
+			"generated": func(string, interface{}) interface{} { return nil },
+		}).Parse(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := usesOrderedFuncs(tpl); got != want {
+			t.Errorf("%s: expected %t, got %t", text, want, got)
+		}
+	}
+}
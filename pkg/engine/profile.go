@@ -0,0 +1,236 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of template executions recorded by a RenderProfile.
+const (
+	ProfileTemplate = "template"
+	ProfileInclude  = "include"
+	ProfileTpl      = "tpl"
+)
+
+// RenderProfile records how long the execution of templates, 'include' and
+// 'tpl' calls takes while rendering a chart.
+//
+// A RenderProfile is attached to an Engine through Engine.Profile. A nil
+// *RenderProfile records nothing.
+type RenderProfile struct {
+	mu     sync.Mutex
+	start  time.Time
+	stack  []*profileFrame
+	stats  map[profileKey]*ProfileStat
+	calls  map[ProfileCall]int
+	events []traceEvent
+}
+
+// ProfileStat holds the aggregated measurements of a template, named template
+// or 'tpl' call site.
+type ProfileStat struct {
+	// Kind is one of ProfileTemplate, ProfileInclude or ProfileTpl.
+	Kind string
+	// Name is the template name. For 'tpl' it is the template calling 'tpl'.
+	Name string
+	// Calls is the number of times the template was executed.
+	Calls int
+	// Total is the wall time spent executing the template, including nested calls.
+	Total time.Duration
+	// Self is the wall time spent executing the template, excluding nested calls.
+	Self time.Duration
+	// OutputBytes is the total size of the output of all executions.
+	OutputBytes int
+	// MaxOutputBytes is the size of the largest output of a single execution.
+	MaxOutputBytes int
+}
+
+// ProfileCall is an edge of the call graph recorded by a RenderProfile.
+type ProfileCall struct {
+	// Caller is the name of the calling template.
+	Caller string
+	// Callee is the name of the called template, prefixed with "tpl:" for 'tpl' calls.
+	Callee string
+}
+
+type profileKey struct {
+	kind, name string
+}
+
+type profileFrame struct {
+	key      profileKey
+	start    time.Time
+	children time.Duration
+}
+
+// traceEvent is a complete event of the Chrome trace event format.
+type traceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat"`
+	Phase     string         `json:"ph"`
+	Timestamp int64          `json:"ts"`
+	Duration  int64          `json:"dur"`
+	PID       int            `json:"pid"`
+	TID       int            `json:"tid"`
+	Args      map[string]int `json:"args,omitempty"`
+}
+
+// NewRenderProfile creates an empty RenderProfile.
+func NewRenderProfile() *RenderProfile {
+	return &RenderProfile{
+		start: time.Now(),
+		stats: make(map[profileKey]*ProfileStat),
+		calls: make(map[ProfileCall]int),
+	}
+}
+
+// begin records the start of the execution of a template and returns the
+// function recording its end, given the size of its output.
+func (p *RenderProfile) begin(kind, name string) func(outputBytes int) {
+	if p == nil {
+		return func(int) {}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := profileKey{kind: kind, name: name}
+	if kind == ProfileTpl {
+		// 'tpl' templates have no name of their own, attribute them to the caller.
+		key.name = p.caller()
+	}
+	if kind != ProfileTemplate {
+		callee := key.name
+		if kind == ProfileTpl {
+			callee = "tpl:" + callee
+		}
+		p.calls[ProfileCall{Caller: p.caller(), Callee: callee}]++
+	}
+	frame := &profileFrame{key: key, start: time.Now()}
+	p.stack = append(p.stack, frame)
+
+	return func(outputBytes int) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		elapsed := time.Since(frame.start)
+		p.stack = p.stack[:len(p.stack)-1]
+		if len(p.stack) > 0 {
+			p.stack[len(p.stack)-1].children += elapsed
+		}
+
+		stat, ok := p.stats[key]
+		if !ok {
+			stat = &ProfileStat{Kind: key.kind, Name: key.name}
+			p.stats[key] = stat
+		}
+		stat.Calls++
+		stat.Total += elapsed
+		stat.Self += elapsed - frame.children
+		stat.OutputBytes += outputBytes
+		if outputBytes > stat.MaxOutputBytes {
+			stat.MaxOutputBytes = outputBytes
+		}
+
+		p.events = append(p.events, traceEvent{
+			Name:      key.name,
+			Category:  key.kind,
+			Phase:     "X",
+			Timestamp: frame.start.Sub(p.start).Microseconds(),
+			Duration:  elapsed.Microseconds(),
+			PID:       1,
+			TID:       1,
+			Args:      map[string]int{"outputBytes": outputBytes},
+		})
+	}
+}
+
+// caller returns the name of the template currently being executed.
+func (p *RenderProfile) caller() string {
+	if len(p.stack) == 0 {
+		return ""
+	}
+	return p.stack[len(p.stack)-1].key.name
+}
+
+// Stats returns the recorded measurements, sorted by descending total time.
+func (p *RenderProfile) Stats() []ProfileStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]ProfileStat, 0, len(p.stats))
+	for _, s := range p.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		if stats[i].Kind != stats[j].Kind {
+			return stats[i].Kind < stats[j].Kind
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// LargestOutputs returns the measurements of the top-level templates and
+// named templates with the largest single output, largest first. At most n
+// entries are returned, all of them if n is not positive.
+func (p *RenderProfile) LargestOutputs(n int) []ProfileStat {
+	stats := p.Stats()
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].MaxOutputBytes > stats[j].MaxOutputBytes
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// Calls returns the call graph as the number of calls per edge.
+func (p *RenderProfile) Calls() map[ProfileCall]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	calls := make(map[ProfileCall]int, len(p.calls))
+	for k, v := range p.calls {
+		calls[k] = v
+	}
+	return calls
+}
+
+// WriteChromeTrace writes the recorded executions in the Chrome trace event
+// format, which can be loaded in chrome://tracing or https://ui.perfetto.dev.
+func (p *RenderProfile) WriteChromeTrace(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := p.events
+	if events == nil {
+		events = []traceEvent{}
+	}
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+}
@@ -0,0 +1,115 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestRenderProfile(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "name" }}moby{{ end }}{{ define "labels" }}app: {{ include "name" . }}{{ end }}`)},
+			{Name: "templates/a.yaml", Data: []byte(`{{ include "labels" . }} {{ include "name" . }}`)},
+			{Name: "templates/b.yaml", Data: []byte(`{{ tpl "{{ include \"name\" . }}" . }}`)},
+		},
+		Values: map[string]interface{}{},
+	}
+	vals := map[string]interface{}{"Values": map[string]interface{}{}}
+	v, err := chartutil.CoalesceValues(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := NewRenderProfile()
+	e := Engine{Profile: profile}
+	out, err := e.Render(c, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := make(map[string]ProfileStat)
+	for _, s := range profile.Stats() {
+		stats[s.Kind+" "+s.Name] = s
+	}
+	expectCalls := map[string]int{
+		"template moby/templates/a.yaml": 1,
+		"template moby/templates/b.yaml": 1,
+		"include labels":                 1,
+		"include name":                   3,
+		"tpl moby/templates/b.yaml":      1,
+	}
+	if len(stats) != len(expectCalls) {
+		t.Errorf("Expected %d entries, got %v", len(expectCalls), stats)
+	}
+	for k, calls := range expectCalls {
+		if stats[k].Calls != calls {
+			t.Errorf("Expected %d calls of %s, got %d", calls, k, stats[k].Calls)
+		}
+		if stats[k].Self > stats[k].Total {
+			t.Errorf("Expected self time of %s to be at most its total time", k)
+		}
+	}
+	if got := stats["template moby/templates/a.yaml"].OutputBytes; got != len(out["moby/templates/a.yaml"]) {
+		t.Errorf("Expected output of %d bytes, got %d", len(out["moby/templates/a.yaml"]), got)
+	}
+
+	expectEdges := map[ProfileCall]int{
+		{Caller: "moby/templates/a.yaml", Callee: "labels"}:                    1,
+		{Caller: "moby/templates/a.yaml", Callee: "name"}:                      1,
+		{Caller: "labels", Callee: "name"}:                                     1,
+		{Caller: "moby/templates/b.yaml", Callee: "tpl:moby/templates/b.yaml"}: 1,
+		{Caller: "moby/templates/b.yaml", Callee: "name"}:                      1,
+	}
+	calls := profile.Calls()
+	for edge, n := range expectEdges {
+		if calls[edge] != n {
+			t.Errorf("Expected %d calls from %s to %s, got %d", n, edge.Caller, edge.Callee, calls[edge])
+		}
+	}
+
+	largest := profile.LargestOutputs(1)
+	if len(largest) != 1 || largest[0].Name != "moby/templates/a.yaml" {
+		t.Errorf("Expected moby/templates/a.yaml to have the largest output, got %v", largest)
+	}
+
+	var buf bytes.Buffer
+	if err := profile.WriteChromeTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []map[string]interface{} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("Invalid trace: %s", err)
+	}
+	if len(trace.TraceEvents) != 7 {
+		t.Errorf("Expected 7 trace events, got %d", len(trace.TraceEvents))
+	}
+}
+
+func TestRenderProfileNil(t *testing.T) {
+	var profile *RenderProfile
+	done := profile.begin(ProfileTemplate, "x")
+	done(0)
+}
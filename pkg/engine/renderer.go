@@ -0,0 +1,137 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// GoTemplateRenderer is the name of the default renderer, which renders
+// templates as Go templates.
+const GoTemplateRenderer = "gotpl"
+
+// Renderer renders chart templates written in a language other than Go
+// templates.
+//
+// A chart opts into a renderer through the 'renderer' field of its
+// Chart.yaml. If the renderer has a file extension, e.g. ".star" for
+// Starlark, it renders the templates of the chart with that extension, and
+// the others remain Go templates; otherwise it renders all the templates of
+// the chart. Embedders may also select renderers by file extension for all
+// charts, with Engine.RendererExtensions. Partials and NOTES.txt are always
+// rendered as Go templates.
+type Renderer interface {
+	// Render renders a template. The name is the full path of the template,
+	// e.g. "mychart/templates/deployment.star".
+	//
+	// The values hold the same objects as '.' in Go templates: Values,
+	// Release, Chart, Capabilities, Subcharts, Template and Files, the latter
+	// with the same methods as '.Files'.
+	//
+	// The output is fed into the same pipeline as the output of Go templates,
+	// so it is expected to be YAML manifests.
+	//
+	// The context is done once the render is cancelled or past its deadline,
+	// and Render should then return.
+	Render(ctx context.Context, name string, source []byte, values chartutil.Values) (string, error)
+}
+
+// builtinRenderers are the renderers available to all engines, besides Go templates.
+var builtinRenderers = map[string]Renderer{
+	StarlarkRenderer: starlarkRenderer{},
+}
+
+// builtinRendererExtensions are the file extensions of the templates of the
+// built-in renderers.
+var builtinRendererExtensions = map[string]string{
+	StarlarkRenderer: ".star",
+}
+
+// rendererFor returns the name of the renderer of a template of a chart, or
+// an empty string for Go templates.
+func (e Engine) rendererFor(c *chart.Chart, name string) string {
+	base := path.Base(name)
+	if strings.HasPrefix(base, "_") || strings.HasSuffix(name, "NOTES.txt") {
+		return ""
+	}
+	ext := path.Ext(name)
+	if r, ok := e.RendererExtensions[ext]; ok {
+		return r
+	}
+	r := c.Metadata.Renderer
+	if r == "" || r == GoTemplateRenderer {
+		return ""
+	}
+	if rext, ok := e.rendererExtension(r); ok && rext != ext {
+		return ""
+	}
+	return r
+}
+
+// rendererExtension returns the file extension of the templates of a
+// renderer, if it has one.
+func (e Engine) rendererExtension(name string) (string, bool) {
+	for ext, r := range e.RendererExtensions {
+		if r == name {
+			return ext, true
+		}
+	}
+	ext, ok := builtinRendererExtensions[name]
+	return ext, ok
+}
+
+// HasRenderer reports whether the engine renders the templates of the charts
+// selecting the given renderer in their Chart.yaml.
+func (e Engine) HasRenderer(name string) bool {
+	if name == "" || name == GoTemplateRenderer {
+		return true
+	}
+	_, err := e.renderer(name)
+	return err == nil
+}
+
+// checkRenderers returns an error if a chart or one of its dependencies
+// selects a renderer the engine does not have.
+func (e Engine) checkRenderers(c *chart.Chart) error {
+	if !e.HasRenderer(c.Metadata.Renderer) {
+		return errors.Errorf("chart %s: unknown template renderer %q", c.ChartFullPath(), c.Metadata.Renderer)
+	}
+	for _, dep := range c.Dependencies() {
+		if err := e.checkRenderers(dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderer returns the renderer with the given name.
+func (e Engine) renderer(name string) (Renderer, error) {
+	if r, ok := e.Renderers[name]; ok {
+		return r, nil
+	}
+	if r, ok := builtinRenderers[name]; ok {
+		return r, nil
+	}
+	return nil, errors.Errorf("unknown template renderer %q", name)
+}
@@ -0,0 +1,242 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// upperRenderer renders templates by upper-casing them, prefixed with the name
+// of the release.
+type upperRenderer struct{}
+
+func (upperRenderer) Render(_ context.Context, _ string, source []byte, values chartutil.Values) (string, error) {
+	release := values["Release"].(map[string]interface{})
+	return fmt.Sprintf("%s: %s", release["Name"], strings.ToUpper(string(source))), nil
+}
+
+func renderValues(t *testing.T, c *chart.Chart) chartutil.Values {
+	t.Helper()
+	caps := chartutil.DefaultCapabilities.Copy()
+	caps.APIVersions = chartutil.VersionSet{"v1", "apps/v1"}
+	vals, err := chartutil.ToRenderValues(c, map[string]interface{}{}, chartutil.ReleaseOptions{Name: "moby"}, caps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vals
+}
+
+func TestRenderWithChartRenderer(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3", Renderer: "upper"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "moby.name" }}moby{{ end }}`)},
+			{Name: "templates/test1.yaml", Data: []byte("kind: pod")},
+			{Name: "templates/NOTES.txt", Data: []byte(`{{ include "moby.name" . }} is up`)},
+		},
+	}
+
+	e := Engine{Renderers: map[string]Renderer{"upper": upperRenderer{}}}
+	out, err := e.Render(c, renderValues(t, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"moby/templates/test1.yaml": "moby: KIND: POD",
+		"moby/templates/NOTES.txt":  "moby is up",
+	}
+	for name, want := range expect {
+		if got := out[name]; got != want {
+			t.Errorf("expected %q for %s, got %q", want, name, got)
+		}
+	}
+
+	if _, err := (Engine{}).Render(c, renderValues(t, c)); err == nil || !strings.Contains(err.Error(), `unknown template renderer "upper"`) {
+		t.Errorf("expected an unknown renderer error, got %v", err)
+	}
+
+	// The renderers of the subcharts are checked before rendering any template.
+	c.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: "sub", Version: "0.1.0", Renderer: "cue"}})
+	if _, err := e.Render(c, renderValues(t, c)); err == nil || err.Error() != `chart moby/charts/sub: unknown template renderer "cue"` {
+		t.Errorf("expected an unknown renderer error for the subchart, got %v", err)
+	}
+}
+
+func TestRenderWithRendererExtension(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/test1.up", Data: []byte("{{ not a go template")},
+			{Name: "templates/test2.yaml", Data: []byte("name: {{ .Release.Name }}")},
+		},
+	}
+
+	e := Engine{
+		Renderers:          map[string]Renderer{"upper": upperRenderer{}},
+		RendererExtensions: map[string]string{".up": "upper"},
+	}
+	out, err := e.Render(c, renderValues(t, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"moby/templates/test1.up":   "moby: {{ NOT A GO TEMPLATE",
+		"moby/templates/test2.yaml": "name: moby",
+	}
+	for name, want := range expect {
+		if got := out[name]; got != want {
+			t.Errorf("expected %q for %s, got %q", want, name, got)
+		}
+	}
+}
+
+func TestStarlarkRenderer(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3", Renderer: StarlarkRenderer},
+		Templates: []*chart.File{
+			{Name: "templates/test1.star", Data: []byte(`
+def configmap(name, data):
+    return {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": name}, "data": data}
+
+manifests = [
+    configmap(Release["Name"], {
+        "chart": Chart.Name,
+        "template": Template["Name"],
+        "replicas": str(Values["replicas"]),
+        "apps": str("apps/v1" in Capabilities.APIVersions),
+    }),
+    configmap("files", {"greeting": Files.Get("greeting.txt"), "names": ",".join(Files.Glob("*.txt").Names)}),
+]
+`)},
+			{Name: "templates/test2.star", Data: []byte(`manifests = {"kind": "Namespace", "metadata": {"name": Values["nested"]["name"]}}`)},
+			{Name: "templates/test3.star", Data: []byte(`greeting = "none"`)},
+		},
+		Files: []*chart.File{
+			{Name: "greeting.txt", Data: []byte("hello")},
+		},
+		Values: map[string]interface{}{"replicas": 3, "nested": map[string]interface{}{"name": "ns"}},
+	}
+
+	vals := renderValues(t, c)
+	vals["Values"] = c.Values
+	out, err := Engine{}.Render(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"moby/templates/test1.star": `apiVersion: v1
+data:
+  apps: "True"
+  chart: moby
+  replicas: "3"
+  template: moby/templates/test1.star
+kind: ConfigMap
+metadata:
+  name: moby
+---
+apiVersion: v1
+data:
+  greeting: hello
+  names: greeting.txt
+kind: ConfigMap
+metadata:
+  name: files
+`,
+		"moby/templates/test2.star": "kind: Namespace\nmetadata:\n  name: ns\n",
+		"moby/templates/test3.star": "",
+	}
+	for name, want := range expect {
+		if got := out[name]; got != want {
+			t.Errorf("expected %q for %s, got %q", want, name, got)
+		}
+	}
+}
+
+func TestStarlarkRendererOptIn(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/test1.star", Data: []byte(`name: {{ .Release.Name }}`)},
+		},
+	}
+	out, err := Engine{}.Render(c, renderValues(t, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out["moby/templates/test1.star"]; got != "name: moby" {
+		t.Errorf("expected a Go template without the starlark renderer, got %q", got)
+	}
+
+	c.Metadata.Renderer = StarlarkRenderer
+	c.Templates = append(c.Templates, &chart.File{Name: "templates/test2.yaml", Data: []byte(`name: {{ .Release.Name }}`)})
+	if _, err := (Engine{}).Render(c, renderValues(t, c)); err == nil || !strings.Contains(err.Error(), "test1.star") {
+		t.Errorf("expected the .star template to be rendered with Starlark, got %v", err)
+	}
+	c.Templates = c.Templates[1:]
+	out, err = Engine{}.Render(c, renderValues(t, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out["moby/templates/test2.yaml"]; got != "name: moby" {
+		t.Errorf("expected the other templates of a Starlark chart to be Go templates, got %q", got)
+	}
+}
+
+func TestStarlarkRendererErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "syntax",
+			source: "manifests = [",
+			want:   "parse error at (moby/templates/test.star:1:14)",
+		},
+		{
+			name:   "fail",
+			source: "def check():\n    fail(\"replicas must be positive\")\n\ncheck()\nmanifests = []",
+			want:   "execution error at (moby/templates/test.star:2:9): fail: replicas must be positive",
+		},
+		{
+			name:   "not a dict",
+			source: "manifests = [\"kind: Pod\"]",
+			want:   "manifest 0 is a string, not a dict",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &chart.Chart{
+				Metadata:  &chart.Metadata{Name: "moby", Version: "1.2.3", Renderer: StarlarkRenderer},
+				Templates: []*chart.File{{Name: "templates/test.star", Data: []byte(tt.source)}},
+			}
+			_, err := Engine{}.Render(c, renderValues(t, c))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,575 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	yamlv3 "sigs.k8s.io/yaml/goyaml.v3"
+)
+
+// sourceMarkerFunc is the name of the template function emitting the source
+// markers inserted into the templates when a SourceMap is collected.
+const sourceMarkerFunc = "_helmSourceMarker"
+
+// sourceMarkerDelim delimits the source markers in the output of templates.
+const sourceMarkerDelim = '\x00'
+
+// SourceLocation is a line of a template.
+type SourceLocation struct {
+	// Template is the name of the template file, e.g. "mychart/templates/deployment.yaml".
+	Template string
+	// Line is the 1-based line number in the template file.
+	Line int
+	// IncludedFrom lists the 'include' calls through which the line was
+	// produced, innermost first.
+	IncludedFrom []SourceLocation
+}
+
+// String returns the location in the form "file:line", followed by the
+// locations of the 'include' calls it was reached through.
+func (l SourceLocation) String() string {
+	s := fmt.Sprintf("%s:%d", l.Template, l.Line)
+	if len(l.IncludedFrom) > 0 {
+		from := make([]string, 0, len(l.IncludedFrom))
+		for _, f := range l.IncludedFrom {
+			from = append(from, fmt.Sprintf("%s:%d", f.Template, f.Line))
+		}
+		s += " (included from " + strings.Join(from, ", ") + ")"
+	}
+	return s
+}
+
+// SourceMap maps the lines of rendered templates back to the template lines
+// that produced them.
+//
+// A SourceMap is attached to an Engine through Engine.SourceMap and is filled
+// in by Render. Lines produced by 'include' are mapped to the named template
+// they come from on a best-effort basis, by matching their content.
+type SourceMap struct {
+	mu    sync.Mutex
+	files map[string]*sourceFile
+}
+
+type sourceFile struct {
+	content string
+	lines   []SourceLocation
+}
+
+// NewSourceMap creates an empty SourceMap.
+func NewSourceMap() *SourceMap {
+	return &SourceMap{files: make(map[string]*sourceFile)}
+}
+
+func (m *SourceMap) add(filename, content string, lines []SourceLocation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[filename] = &sourceFile{content: content, lines: lines}
+}
+
+func (m *SourceMap) file(filename string) (*sourceFile, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[filename]
+	return f, ok
+}
+
+// Files returns the names of the rendered files, sorted.
+func (m *SourceMap) Files() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the template location that produced the given 1-based line
+// of a rendered file.
+func (m *SourceMap) Lookup(filename string, line int) (SourceLocation, bool) {
+	f, ok := m.file(filename)
+	if !ok || line < 1 || line > len(f.lines) || f.lines[line-1].Template == "" {
+		return SourceLocation{}, false
+	}
+	return f.lines[line-1], true
+}
+
+// LocateField returns the template locations that produced a field of the
+// rendered manifests matching a kind and a name.
+//
+// The field path uses the notation of Kubernetes API errors, e.g.
+// "spec.template.spec.containers[0].image". If the field is not part of a
+// manifest, the location of its deepest existing parent is returned. An empty
+// filename, kind or name matches any. Files are searched in name order.
+func (m *SourceMap) LocateField(filename, kind, name, fieldPath string) []SourceLocation {
+	if m == nil {
+		return nil
+	}
+	files := []string{filename}
+	if filename == "" {
+		files = m.Files()
+	}
+	path := parseFieldPath(fieldPath)
+
+	var locs []SourceLocation
+	for _, fn := range files {
+		f, ok := m.file(fn)
+		if !ok {
+			continue
+		}
+		for _, doc := range splitSourceDocuments(f.content) {
+			var root yamlv3.Node
+			if err := yamlv3.Unmarshal([]byte(doc.text), &root); err != nil || len(root.Content) == 0 {
+				continue
+			}
+			node := root.Content[0]
+			if kind != "" && scalarAt(node, "kind") != kind {
+				continue
+			}
+			if name != "" && scalarAt(node, "metadata", "name") != name {
+				continue
+			}
+			if loc, ok := m.Lookup(fn, doc.line+locateNode(node, path)-1); ok {
+				locs = append(locs, loc)
+			}
+		}
+	}
+	return locs
+}
+
+// LocateSyntaxError returns the template location of the first YAML syntax
+// error in a rendered file.
+func (m *SourceMap) LocateSyntaxError(filename string) (SourceLocation, bool) {
+	f, ok := m.file(filename)
+	if !ok {
+		return SourceLocation{}, false
+	}
+	for _, doc := range splitSourceDocuments(f.content) {
+		var root yamlv3.Node
+		err := yamlv3.Unmarshal([]byte(doc.text), &root)
+		if err == nil {
+			continue
+		}
+		match := yamlErrorLine.FindStringSubmatch(err.Error())
+		if match == nil {
+			return SourceLocation{}, false
+		}
+		line, _ := strconv.Atoi(match[1])
+		return m.Lookup(filename, doc.line+line-1)
+	}
+	return SourceLocation{}, false
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// sourceDocument is a YAML document of a rendered file.
+type sourceDocument struct {
+	// line is the line of the rendered file the document starts on.
+	line int
+	text string
+}
+
+// splitSourceDocuments splits a rendered file on YAML document separators,
+// keeping track of the line each document starts on.
+func splitSourceDocuments(content string) []sourceDocument {
+	var docs []sourceDocument
+	var b strings.Builder
+	start := 1
+	for i, line := range strings.SplitAfter(content, "\n") {
+		if strings.HasPrefix(line, "---") {
+			docs = append(docs, sourceDocument{line: start, text: b.String()})
+			b.Reset()
+			start = i + 2
+			continue
+		}
+		b.WriteString(line)
+	}
+	return append(docs, sourceDocument{line: start, text: b.String()})
+}
+
+// fieldPathSegment is a segment of a field path: a key, or an index if key is empty.
+type fieldPathSegment struct {
+	key   string
+	index int
+}
+
+// parseFieldPath parses a field path such as "spec.containers[0].image" or
+// "metadata.labels[app.kubernetes.io/name]".
+func parseFieldPath(p string) []fieldPathSegment {
+	var segments []fieldPathSegment
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return segments
+			}
+			sub := p[1:end]
+			if i, err := strconv.Atoi(sub); err == nil {
+				segments = append(segments, fieldPathSegment{index: i})
+			} else {
+				segments = append(segments, fieldPathSegment{key: sub})
+			}
+			p = p[end+1:]
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			segments = append(segments, fieldPathSegment{key: p[:end]})
+			p = p[end:]
+		}
+	}
+	return segments
+}
+
+// locateNode returns the line of the deepest node of the path found in a
+// document, starting from its root.
+func locateNode(node *yamlv3.Node, path []fieldPathSegment) int {
+	line := node.Line
+	for _, seg := range path {
+		var next *yamlv3.Node
+		switch {
+		case seg.key != "" && node.Kind == yamlv3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == seg.key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case seg.key == "" && node.Kind == yamlv3.SequenceNode && seg.index < len(node.Content):
+			next = node.Content[seg.index]
+			line = next.Line
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+// scalarAt returns the value of the scalar at a path of mapping keys.
+func scalarAt(node *yamlv3.Node, keys ...string) string {
+	for _, key := range keys {
+		if node.Kind != yamlv3.MappingNode {
+			return ""
+		}
+		var next *yamlv3.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return ""
+		}
+		node = next
+	}
+	if node.Kind != yamlv3.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// sourceMarker is the template location a source marker stands for.
+type sourceMarker struct {
+	template string
+	line     int
+	// text is set for markers preceding text, whose output advances the
+	// template line on each newline.
+	text bool
+}
+
+// sourceCollector instruments templates with source markers and turns their
+// output back into source maps.
+//
+// Markers are only emitted while a capturing frame is on top of the stack:
+// the output of each top-level template and 'include' call is captured in a
+// frame, while the output of 'tpl' is not, as it cannot be traced back to a
+// template file.
+type sourceCollector struct {
+	markers []sourceMarker
+	stack   []*sourceFrame
+}
+
+type sourceFrame struct {
+	capture bool
+	emitted []emittedMarker
+}
+
+// emittedMarker is a marker emitted in the output of a frame, along with the
+// results of the 'include' calls made until the next marker.
+type emittedMarker struct {
+	id       int
+	includes []map[string]SourceLocation
+}
+
+// instrument inserts a source marker before each text, action and template
+// node of all templates parsed so far.
+func (c *sourceCollector) instrument(t *template.Template) {
+	seen := make(map[*parse.Tree]bool)
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || tmpl.Tree.Root == nil || seen[tmpl.Tree] {
+			continue
+		}
+		seen[tmpl.Tree] = true
+		c.instrumentList(tmpl.Tree, tmpl.Tree.Root)
+	}
+}
+
+func (c *sourceCollector) instrumentList(tree *parse.Tree, list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	nodes := make([]parse.Node, 0, 2*len(list.Nodes))
+	for _, node := range list.Nodes {
+		var line int
+		text := false
+		switch n := node.(type) {
+		case *parse.TextNode:
+			location, _ := tree.ErrorContext(n)
+			line = locationLine(tree.ParseName, location)
+			text = true
+		case *parse.ActionNode:
+			line = n.Line
+		case *parse.TemplateNode:
+			line = n.Line
+		case *parse.IfNode:
+			c.instrumentList(tree, n.List)
+			c.instrumentList(tree, n.ElseList)
+		case *parse.RangeNode:
+			c.instrumentList(tree, n.List)
+			c.instrumentList(tree, n.ElseList)
+		case *parse.WithNode:
+			c.instrumentList(tree, n.List)
+			c.instrumentList(tree, n.ElseList)
+		}
+		if line > 0 {
+			id := len(c.markers)
+			c.markers = append(c.markers, sourceMarker{template: tree.ParseName, line: line, text: text})
+			nodes = append(nodes, newMarkerNode(tree, node.Position(), line, id))
+		}
+		nodes = append(nodes, node)
+	}
+	list.Nodes = nodes
+}
+
+// locationLine extracts the line from a location returned by
+// parse.Tree.ErrorContext, of the form "name:line:byte".
+func locationLine(name, location string) int {
+	fields := strings.Split(strings.TrimPrefix(location, name+":"), ":")
+	line, _ := strconv.Atoi(fields[0])
+	return line
+}
+
+// newMarkerNode creates the action node {{ _helmSourceMarker id }}.
+func newMarkerNode(tree *parse.Tree, pos parse.Pos, line, id int) parse.Node {
+	ident := parse.NewIdentifier(sourceMarkerFunc).SetTree(tree).SetPos(pos)
+	number := &parse.NumberNode{NodeType: parse.NodeNumber, Pos: pos, IsInt: true, Int64: int64(id), Text: strconv.Itoa(id)}
+	return &parse.ActionNode{
+		NodeType: parse.NodeAction,
+		Pos:      pos,
+		Line:     line,
+		Pipe: &parse.PipeNode{
+			NodeType: parse.NodePipe,
+			Pos:      pos,
+			Line:     line,
+			Cmds:     []*parse.CommandNode{{NodeType: parse.NodeCommand, Pos: pos, Args: []parse.Node{ident, number}}},
+		},
+	}
+}
+
+// fork returns a collector for the same instrumented templates, with a stack
+// of its own, so that templates can be executed concurrently.
+func (c *sourceCollector) fork() *sourceCollector {
+	if c == nil {
+		return nil
+	}
+	return &sourceCollector{markers: c.markers}
+}
+
+// marker is the template function emitting source markers.
+func (c *sourceCollector) marker(id int) string {
+	frame := c.top()
+	if frame == nil || !frame.capture {
+		return ""
+	}
+	seq := len(frame.emitted)
+	frame.emitted = append(frame.emitted, emittedMarker{id: id})
+	return string(sourceMarkerDelim) + strconv.Itoa(seq) + string(sourceMarkerDelim)
+}
+
+// isSourceMarker reports whether p is a source marker, which the templates
+// write on its own.
+func isSourceMarker(p []byte) bool {
+	if len(p) < 3 || p[0] != sourceMarkerDelim || p[len(p)-1] != sourceMarkerDelim {
+		return false
+	}
+	for _, ch := range p[1 : len(p)-1] {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *sourceCollector) top() *sourceFrame {
+	if c == nil || len(c.stack) == 0 {
+		return nil
+	}
+	return c.stack[len(c.stack)-1]
+}
+
+// push starts a new frame and returns the function ending it.
+func (c *sourceCollector) push(capture bool) func() *sourceFrame {
+	if c == nil {
+		return func() *sourceFrame { return nil }
+	}
+	frame := &sourceFrame{capture: capture}
+	c.stack = append(c.stack, frame)
+	return func() *sourceFrame {
+		c.stack = c.stack[:len(c.stack)-1]
+		return frame
+	}
+}
+
+// included resolves the output of an 'include' call and records its source
+// lines in the calling frame. It returns the output without markers.
+func (c *sourceCollector) included(frame *sourceFrame, out string) string {
+	if c == nil {
+		return out
+	}
+	clean, lines := c.resolve(frame, out)
+	parent := c.top()
+	if parent == nil || !parent.capture || len(parent.emitted) == 0 {
+		return clean
+	}
+	byContent := make(map[string]SourceLocation)
+	for i, text := range strings.Split(clean, "\n") {
+		text = strings.TrimSpace(text)
+		if _, ok := byContent[text]; ok || text == "" || i >= len(lines) || lines[i].Template == "" {
+			continue
+		}
+		byContent[text] = lines[i]
+	}
+	last := &parent.emitted[len(parent.emitted)-1]
+	last.includes = append(last.includes, byContent)
+	return clean
+}
+
+// resolve removes the markers from the output of a frame and returns the
+// template location of each line of the output.
+//
+// A line is attributed to the template node producing its first
+// non-whitespace character. Lines produced by an action are further matched
+// against the output of the 'include' calls made by that action.
+func (c *sourceCollector) resolve(frame *sourceFrame, out string) (string, []SourceLocation) {
+	var (
+		b     strings.Builder
+		lines []SourceLocation
+		// the current marker and the number of newlines output since it
+		current  = -1
+		newlines = 0
+		// the start of the current line and where its content comes from
+		lineStart  = 0
+		lineMarker = -1
+		lineLine   = 0
+		hasContent = false
+	)
+
+	locate := func() (int, int) {
+		if current < 0 {
+			return -1, 0
+		}
+		m := c.markers[frame.emitted[current].id]
+		if m.text {
+			return current, m.line + newlines
+		}
+		return current, m.line
+	}
+
+	endLine := func() {
+		loc := SourceLocation{}
+		if lineMarker >= 0 {
+			em := frame.emitted[lineMarker]
+			m := c.markers[em.id]
+			loc = SourceLocation{Template: m.template, Line: lineLine}
+			if !m.text && len(em.includes) > 0 {
+				text := strings.TrimSpace(b.String()[lineStart:])
+				for i := len(em.includes) - 1; i >= 0; i-- {
+					if inc, ok := em.includes[i][text]; ok {
+						from := append([]SourceLocation{}, inc.IncludedFrom...)
+						inc.IncludedFrom = append(from, loc)
+						loc = inc
+						break
+					}
+				}
+			}
+		}
+		lines = append(lines, loc)
+		lineMarker, lineLine, hasContent = -1, 0, false
+	}
+
+	for i := 0; i < len(out); i++ {
+		ch := out[i]
+		if ch == sourceMarkerDelim && frame != nil {
+			if end := strings.IndexByte(out[i+1:], sourceMarkerDelim); end > 0 {
+				if seq, err := strconv.Atoi(out[i+1 : i+1+end]); err == nil && seq < len(frame.emitted) {
+					current, newlines = seq, 0
+					i += end + 1
+					continue
+				}
+			}
+		}
+		if ch == '\n' {
+			if lineMarker < 0 {
+				lineMarker, lineLine = locate()
+			}
+			endLine()
+			b.WriteByte(ch)
+			lineStart = b.Len()
+			newlines++
+			continue
+		}
+		if !hasContent && (lineMarker < 0 || (ch != ' ' && ch != '\t')) {
+			lineMarker, lineLine = locate()
+			hasContent = ch != ' ' && ch != '\t'
+		}
+		b.WriteByte(ch)
+	}
+	if b.Len() > lineStart {
+		endLine()
+	}
+	return b.String(), lines
+}
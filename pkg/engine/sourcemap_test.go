@@ -0,0 +1,147 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+const sourceMapHelpers = `{{- define "moby.labels" -}}
+app: {{ .Chart.Name }}
+tier: backend
+{{- end }}
+`
+
+const sourceMapDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    {{- include "moby.labels" . | nindent 4 }}
+spec:
+  replicas: {{ .Values.replicas }}
+{{- if .Values.extra }}
+  extra: true
+{{- end }}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ tpl "{{ .Values.svc }}" . }}
+`
+
+func sourceMapChart() *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(sourceMapHelpers)},
+			{Name: "templates/deployment.yaml", Data: []byte(sourceMapDeployment)},
+			{Name: "templates/broken.yaml", Data: []byte("a: b\n---\n{{ if true }}c: d\n  e: f: g{{ end }}\n")},
+		},
+		Values: map[string]interface{}{"replicas": 3, "extra": true, "svc": "web-svc"},
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	c := sourceMapChart()
+	vals, err := chartutil.ToRenderValues(c, map[string]interface{}{}, chartutil.ReleaseOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := Render(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := Engine{SourceMap: NewSourceMap()}
+	out, err := e.Render(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range plain {
+		if out[name] != content {
+			t.Errorf("Expected %s to render the same with a source map, got:\n%q\nwant:\n%q", name, out[name], content)
+		}
+	}
+
+	const deployment = "moby/templates/deployment.yaml"
+	const helpers = "moby/templates/_helpers.tpl"
+	expect := map[int]string{
+		1:  deployment + ":1",
+		4:  deployment + ":4",
+		5:  deployment + ":5",
+		6:  helpers + ":2 (included from " + deployment + ":6)",
+		7:  helpers + ":3 (included from " + deployment + ":6)",
+		8:  deployment + ":7",
+		9:  deployment + ":8",
+		10: deployment + ":10",
+		14: deployment + ":15",
+		15: deployment + ":16",
+	}
+	for line, want := range expect {
+		loc, ok := e.SourceMap.Lookup(deployment, line)
+		if !ok {
+			t.Errorf("Expected line %d to be mapped", line)
+			continue
+		}
+		if loc.String() != want {
+			t.Errorf("Expected line %d to map to %s, got %s", line, want, loc)
+		}
+	}
+	if _, ok := e.SourceMap.Lookup(deployment, 100); ok {
+		t.Error("Expected no mapping beyond the end of the file")
+	}
+
+	fields := map[string]string{
+		"metadata.labels.tier":        helpers + ":3 (included from " + deployment + ":6)",
+		"spec.replicas":               deployment + ":8",
+		"spec.template.spec.replicas": deployment + ":7",
+	}
+	for field, want := range fields {
+		locs := e.SourceMap.LocateField("", "Deployment", "web", field)
+		if len(locs) != 1 || locs[0].String() != want {
+			t.Errorf("Expected %s to be located at %s, got %v", field, want, locs)
+		}
+	}
+	if locs := e.SourceMap.LocateField(deployment, "Service", "web-svc", "metadata.name"); len(locs) != 1 || locs[0].String() != deployment+":16" {
+		t.Errorf("Expected service name to be located at line 16, got %v", locs)
+	}
+	if locs := e.SourceMap.LocateField("", "Deployment", "db", "spec"); len(locs) != 0 {
+		t.Errorf("Expected no location for an unknown object, got %v", locs)
+	}
+
+	loc, ok := e.SourceMap.LocateSyntaxError("moby/templates/broken.yaml")
+	if !ok || loc.String() != "moby/templates/broken.yaml:4" {
+		t.Errorf("Expected syntax error at broken.yaml:4, got %v", loc)
+	}
+}
+
+func TestParseFieldPath(t *testing.T) {
+	segments := parseFieldPath("spec.containers[1].env[FOO].value")
+	want := []fieldPathSegment{{key: "spec"}, {key: "containers"}, {index: 1}, {key: "env"}, {key: "FOO"}, {key: "value"}}
+	if len(segments) != len(want) {
+		t.Fatalf("Expected %d segments, got %v", len(want), segments)
+	}
+	for i := range want {
+		if segments[i] != want[i] {
+			t.Errorf("Expected segment %d to be %v, got %v", i, want[i], segments[i])
+		}
+	}
+}
@@ -0,0 +1,346 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// StarlarkRenderer is the name of the built-in Starlark renderer.
+const StarlarkRenderer = "starlark"
+
+// starlarkManifests is the global a Starlark template assigns its manifests to.
+const starlarkManifests = "manifests"
+
+// starlarkRenderer renders templates written in Starlark.
+//
+// The objects available as '.' in Go templates are predeclared globals, e.g.
+// Values, Release and Chart. Maps, such as Values and Release, are dicts;
+// other objects, such as Chart and Capabilities, are structs with the same
+// fields as in Go templates:
+//
+//	replicas = Values["replicas"]
+//	manifests = [{
+//	    "apiVersion": "apps/v1",
+//	    "kind": "Deployment",
+//	    "metadata": {"name": Release["Name"], "labels": {"chart": Chart.Name}},
+//	    "spec": {"replicas": replicas},
+//	}]
+//
+// The template assigns the manifests it renders to the 'manifests' global, as
+// a list of dicts or a single dict. They are rendered as YAML documents.
+type starlarkRenderer struct{}
+
+func (starlarkRenderer) Render(ctx context.Context, name string, source []byte, values chartutil.Values) (string, error) {
+	predeclared := starlark.StringDict{}
+	for k, v := range values {
+		sv, err := toStarlark(v)
+		if err != nil {
+			return "", errors.Wrapf(err, "error converting %s for Starlark template %s", k, name)
+		}
+		predeclared[k] = sv
+	}
+
+	thread := &starlark.Thread{
+		Name:  name,
+		Print: func(_ *starlark.Thread, msg string) { log.Printf("[INFO] %s: %s", name, msg) },
+	}
+	// Stop the execution, e.g. of an endless loop, once the render is
+	// interrupted.
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(ctx.Err().Error()) })
+	defer stop()
+	globals, err := starlark.ExecFile(thread, name, source, predeclared)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", errors.Wrapf(ctx.Err(), "rendering %s was interrupted", name)
+		}
+		var (
+			syntaxErr  syntax.Error
+			resolveErr resolve.ErrorList
+			evalErr    *starlark.EvalError
+		)
+		switch {
+		case errors.As(err, &syntaxErr):
+			return "", fmt.Errorf("parse error at (%s): %s", syntaxErr.Pos, syntaxErr.Msg)
+		case errors.As(err, &resolveErr) && len(resolveErr) > 0:
+			return "", fmt.Errorf("parse error at (%s): %s", resolveErr[0].Pos, resolveErr[0].Msg)
+		case errors.As(err, &evalErr):
+			// Report the innermost call made from the template rather than
+			// from within a built-in.
+			for i := range evalErr.CallStack {
+				if frame := evalErr.CallStack.At(i); frame.Pos.Filename() == name {
+					return "", fmt.Errorf("execution error at (%s): %s", frame.Pos, evalErr.Msg)
+				}
+			}
+		}
+		return "", fmt.Errorf("execution error in (%s): %s", name, err)
+	}
+
+	result, ok := globals[starlarkManifests]
+	if !ok || result == starlark.None {
+		return "", nil
+	}
+	manifests, err := fromStarlark(result)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid manifests in Starlark template %s", name)
+	}
+	docs, ok := manifests.([]interface{})
+	if !ok {
+		docs = []interface{}{manifests}
+	}
+
+	var b strings.Builder
+	for i, doc := range docs {
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return "", errors.Errorf("invalid manifests in Starlark template %s: manifest %d is a %T, not a dict", name, i, doc)
+		}
+		out, err := yaml.Marshal(doc)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid manifests in Starlark template %s", name)
+		}
+		if i > 0 {
+			b.WriteString("---\n")
+		}
+		b.Write(out)
+	}
+	return b.String(), nil
+}
+
+// toStarlark converts a template value to a Starlark value.
+func toStarlark(v interface{}) (starlark.Value, error) {
+	return reflectToStarlark(reflect.ValueOf(v))
+}
+
+func reflectToStarlark(v reflect.Value) (starlark.Value, error) {
+	if !v.IsValid() {
+		return starlark.None, nil
+	}
+	if v.CanInterface() {
+		if f, ok := v.Interface().(files); ok {
+			return starlarkFiles(f), nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return starlark.None, nil
+		}
+		return reflectToStarlark(v.Elem())
+	case reflect.String:
+		return starlark.String(v.String()), nil
+	case reflect.Bool:
+		return starlark.Bool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return starlark.MakeUint64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(v.Float()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return starlark.String(v.Bytes()), nil
+		}
+		elems := make([]starlark.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, err := reflectToStarlark(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+		return starlark.NewList(elems), nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, errors.Errorf("unsupported map key type %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		dict := starlark.NewDict(len(keys))
+		for _, key := range keys {
+			elem, err := reflectToStarlark(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(starlark.String(key.String()), elem); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	case reflect.Struct:
+		fields := starlark.StringDict{}
+		if err := addStructFields(fields, v); err != nil {
+			return nil, err
+		}
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, fields), nil
+	}
+	return nil, errors.Errorf("unsupported type %s", v.Type())
+}
+
+// addStructFields adds the exported fields of a struct to a StringDict,
+// flattening embedded structs as Go templates do.
+func addStructFields(fields starlark.StringDict, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := addStructFields(fields, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		sv, err := reflectToStarlark(v.Field(i))
+		if err != nil {
+			return errors.Wrapf(err, "field %s", field.Name)
+		}
+		fields[field.Name] = sv
+	}
+	return nil
+}
+
+// starlarkFiles exposes the files of a chart with the same methods as '.Files'
+// in Go templates.
+func starlarkFiles(f files) starlark.Value {
+	stringArg := func(name string, fn func(string) starlark.Value) *starlark.Builtin {
+		return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var s string
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+				return nil, err
+			}
+			return fn(s), nil
+		})
+	}
+	noArg := func(name string, fn func() string) *starlark.Builtin {
+		return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+				return nil, err
+			}
+			return starlark.String(fn()), nil
+		})
+	}
+
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]starlark.Value, 0, len(names))
+	for _, name := range names {
+		list = append(list, starlark.String(name))
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"Names": starlark.NewList(list),
+		"Get": stringArg("Get", func(name string) starlark.Value {
+			return starlark.String(f.Get(name))
+		}),
+		"Lines": stringArg("Lines", func(name string) starlark.Value {
+			lines := f.Lines(name)
+			elems := make([]starlark.Value, 0, len(lines))
+			for _, l := range lines {
+				elems = append(elems, starlark.String(l))
+			}
+			return starlark.NewList(elems)
+		}),
+		"Glob": stringArg("Glob", func(pattern string) starlark.Value {
+			return starlarkFiles(f.Glob(pattern))
+		}),
+		"AsConfig":  noArg("AsConfig", f.AsConfig),
+		"AsSecrets": noArg("AsSecrets", f.AsSecrets),
+	})
+}
+
+// fromStarlark converts a Starlark value to a value which can be marshalled to YAML.
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		return v.String(), nil
+	case starlark.Float:
+		return float64(v), nil
+	case *starlark.List:
+		return fromStarlarkIterable(v)
+	case starlark.Tuple:
+		return fromStarlarkIterable(v)
+	case *starlark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, errors.Errorf("dict key %s is a %s, not a string", item[0], item[0].Type())
+			}
+			val, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+		}
+		return m, nil
+	case *starlarkstruct.Struct:
+		fields := starlark.StringDict{}
+		v.ToStringDict(fields)
+		m := make(map[string]interface{}, len(fields))
+		for key, field := range fields {
+			val, err := fromStarlark(field)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+		}
+		return m, nil
+	}
+	return nil, errors.Errorf("unsupported Starlark type %s", v.Type())
+}
+
+func fromStarlarkIterable(v starlark.Iterable) ([]interface{}, error) {
+	var elems []interface{}
+	iter := v.Iterate()
+	defer iter.Done()
+	var x starlark.Value
+	for iter.Next(&x) {
+		elem, err := fromStarlark(x)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
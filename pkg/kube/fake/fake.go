@@ -22,8 +22,11 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/resource"
 
 	"helm.sh/helm/v3/pkg/kube"
@@ -46,7 +49,10 @@ type FailingKubeClient struct {
 	BuildDummy                       bool
 	BuildUnstructuredError           error
 	WaitAndGetCompletedPodPhaseError error
-	WaitDuration                     time.Duration
+	ListError                        error
+	// ListResources are the live objects returned by List.
+	ListResources kube.ResourceList
+	WaitDuration  time.Duration
 }
 
 // Create returns the configured error if set or prints
@@ -149,6 +155,33 @@ func (f *FailingKubeClient) DeleteWithPropagationPolicy(resources kube.ResourceL
 	return f.PrintingKubeClient.DeleteWithPropagationPolicy(resources, policy)
 }
 
+// List returns the configured error if set, or the configured resources of
+// the given kinds
+func (f *FailingKubeClient) List(kinds []schema.GroupVersionKind, labelSelector string) (kube.ResourceList, error) {
+	if f.ListError != nil {
+		return nil, f.ListError
+	}
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	var result kube.ResourceList
+	for _, info := range f.ListResources {
+		gvk := info.Object.GetObjectKind().GroupVersionKind()
+		accessor, err := meta.Accessor(info.Object)
+		if err != nil {
+			return nil, err
+		}
+		for _, kind := range kinds {
+			if kind.GroupKind() == gvk.GroupKind() && selector.Matches(labels.Set(accessor.GetLabels())) {
+				result = append(result, info)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
 func createDummyResourceList() kube.ResourceList {
 	var resInfo resource.Info
 	resInfo.Name = "dummyName"
@@ -0,0 +1,88 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+)
+
+// List returns the live objects of the given kinds matching the label
+// selector, in all the namespaces, or in the namespace of the client for the
+// kinds it cannot list in all the namespaces. The kinds the cluster does not
+// serve are skipped, and those whose version it does not serve anymore are
+// listed with their preferred version.
+func (c *Client) List(kinds []schema.GroupVersionKind, labelSelector string) (ResourceList, error) {
+	client, err := c.getKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := restmapper.GetAPIGroupResources(client.Discovery())
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groups)
+
+	var result ResourceList
+	listed := map[schema.GroupVersionResource]bool{}
+	for _, gvk := range kinds {
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			mapping, err = mapper.RESTMapping(gvk.GroupKind())
+		}
+		if meta.IsNoMatchError(err) {
+			c.Log("skipping %s: the cluster does not serve it", gvk)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if listed[mapping.Resource] {
+			continue
+		}
+		listed[mapping.Resource] = true
+
+		resourceType := fmt.Sprintf("%s.%s.%s", mapping.Resource.Resource, mapping.Resource.Version, mapping.Resource.Group)
+		infos, err := c.listResources(resourceType, labelSelector, true)
+		if apierrors.IsForbidden(err) {
+			infos, err = c.listResources(resourceType, labelSelector, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, infos...)
+	}
+	return result, nil
+}
+
+// listResources returns the live objects of a resource type matching the
+// label selector.
+func (c *Client) listResources(resourceType, labelSelector string, allNamespaces bool) (ResourceList, error) {
+	return c.Factory.NewBuilder().
+		Unstructured().
+		NamespaceParam(c.namespace()).
+		DefaultNamespace().
+		AllNamespaces(allNamespaces).
+		ResourceTypeOrNameArgs(true, resourceType).
+		LabelSelectorParam(labelSelector).
+		Flatten().
+		Do().Infos()
+}
@@ -0,0 +1,196 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// builtinScheme holds the built-in Kubernetes API types, whose schemas are
+// generated from their Go types.
+var builtinScheme = func() *runtime.Scheme {
+	s := runtime.NewScheme()
+	kscheme.AddToScheme(s)
+	apiextensionsv1.AddToScheme(s)
+	return s
+}()
+
+// builtinGroup returns whether a group is the group of built-in types.
+func builtinGroup(group string) bool {
+	for gvk := range builtinScheme.AllKnownTypes() {
+		if gvk.Group == group {
+			return true
+		}
+	}
+	return false
+}
+
+// The prerelease lifecycle of the beta and alpha API types.
+type (
+	apiLifecycleIntroduced interface{ APILifecycleIntroduced() (int, int) }
+	apiLifecycleRemoved    interface{ APILifecycleRemoved() (int, int) }
+)
+
+// builtinAvailable returns whether a built-in API type is available in a
+// Kubernetes version, according to its prerelease lifecycle.
+func builtinAvailable(obj runtime.Object, major, minor int) bool {
+	if o, ok := obj.(apiLifecycleIntroduced); ok {
+		maj, min := o.APILifecycleIntroduced()
+		if major < maj || (major == maj && minor < min) {
+			return false
+		}
+	}
+	if o, ok := obj.(apiLifecycleRemoved); ok {
+		maj, min := o.APILifecycleRemoved()
+		if maj > 0 && (major > maj || (major == maj && minor >= min)) {
+			return false
+		}
+	}
+	return true
+}
+
+// builtinSchema returns the schema of a built-in API type, generated from
+// its Go type.
+func builtinSchema(gvk schema.GroupVersionKind) (map[string]interface{}, bool) {
+	t, ok := builtinScheme.AllKnownTypes()[gvk]
+	if !ok {
+		return nil, false
+	}
+	g := generator{definitions: map[string]interface{}{}}
+	doc := g.schema(t)
+	doc["definitions"] = g.definitions
+	return doc, true
+}
+
+var (
+	timeType         = reflect.TypeOf(metav1.Time{})
+	microTimeType    = reflect.TypeOf(metav1.MicroTime{})
+	durationType     = reflect.TypeOf(metav1.Duration{})
+	quantityType     = reflect.TypeOf(resource.Quantity{})
+	intOrStringType  = reflect.TypeOf(intstr.IntOrString{})
+	rawExtensionType = reflect.TypeOf(runtime.RawExtension{})
+	unmarshalerType  = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// generator generates the JSON schemas of Go types, as they are decoded from
+// JSON, with the definitions of the structs they refer to.
+type generator struct {
+	definitions map[string]interface{}
+}
+
+// schema returns the schema of a type. Null is valid for every type, as it
+// is decoded as the zero value.
+func (g *generator) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType, microTimeType, durationType:
+		return nullable("string")
+	case quantityType:
+		return nullable("string", "integer", "number")
+	case intOrStringType:
+		return nullable("integer", "string")
+	case rawExtensionType:
+		return map[string]interface{}{}
+	}
+	if t.Kind() == reflect.Ptr {
+		return g.schema(t.Elem())
+	}
+	// Types decoding themselves accept anything we cannot describe.
+	if t.Implements(unmarshalerType) || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return nullable("string")
+	case reflect.Bool:
+		return nullable("boolean")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nullable("integer")
+	case reflect.Float32, reflect.Float64:
+		return nullable("number")
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nullable("string")
+		}
+		s := nullable("array")
+		s["items"] = g.schema(t.Elem())
+		return s
+	case reflect.Map:
+		s := nullable("object")
+		s["additionalProperties"] = g.schema(t.Elem())
+		return s
+	case reflect.Struct:
+		name := strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + t.Name()
+		if _, ok := g.definitions[name]; !ok {
+			// Define the struct before its fields, which may refer to it.
+			s := nullable("object")
+			g.definitions[name] = s
+			properties := map[string]interface{}{}
+			g.properties(t, properties)
+			s["properties"] = properties
+			s["additionalProperties"] = false
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// properties adds the schemas of the fields of a struct, including those of
+// its embedded structs, by their JSON names.
+func (g *generator) properties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.properties(ft, properties)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = g.schema(f.Type)
+	}
+}
+
+// nullable returns a schema of the given types or null.
+func nullable(types ...string) map[string]interface{} {
+	t := make([]interface{}, 0, len(types)+1)
+	for _, name := range types {
+		t = append(t, name)
+	}
+	return map[string]interface{}{"type": append(t, "null")}
+}
@@ -0,0 +1,301 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package kubeschema validates Kubernetes resources against the schemas of their
+kinds, offline, reporting unknown fields and values of the wrong type.
+
+The schemas of the built-in kinds are generated from the Go types of the
+Kubernetes API Helm is built with, restricted to the API versions available in
+the selected Kubernetes version. Schemas can also be loaded from OpenAPI
+documents of the Kubernetes API, from JSON schemas and from
+CustomResourceDefinitions, e.g. those of the 'crds' directory of a chart.
+*/
+package kubeschema // import "helm.sh/helm/v3/pkg/kubeschema"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// Validator validates resources against the schemas of their kinds.
+type Validator struct {
+	kubeVersion chartutil.KubeVersion
+	// schemas are the loaded schemas, which take precedence over the
+	// built-in ones.
+	schemas map[schema.GroupVersionKind]map[string]interface{}
+
+	mu       sync.Mutex
+	compiled map[schema.GroupVersionKind]*gojsonschema.Schema
+}
+
+// NewValidator creates a Validator with the built-in schemas of a Kubernetes
+// version, or of the default Kubernetes version if it is nil.
+func NewValidator(kubeVersion *chartutil.KubeVersion) *Validator {
+	v := &Validator{
+		kubeVersion: chartutil.DefaultCapabilities.KubeVersion,
+		schemas:     map[schema.GroupVersionKind]map[string]interface{}{},
+		compiled:    map[schema.GroupVersionKind]*gojsonschema.Schema{},
+	}
+	if kubeVersion != nil {
+		v.kubeVersion = *kubeVersion
+	}
+	return v
+}
+
+// Copy returns a copy of the validator, whose schemas can be added to
+// without changing those of v.
+func (v *Validator) Copy() *Validator {
+	c := NewValidator(&v.kubeVersion)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for gvk, s := range v.schemas {
+		c.schemas[gvk] = s
+	}
+	return c
+}
+
+// AddLocation loads the schemas of a file, or of the files of a directory
+// and of its subdirectories. JSON files are OpenAPI documents of the
+// Kubernetes API, as served by '/openapi/v2' or '/openapi/v3/apis/...', or
+// JSON schemas with the x-kubernetes-group-version-kind extension. YAML files
+// are CustomResourceDefinitions.
+func (v *Validator) AddLocation(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return v.addFile(path, true)
+	}
+	return filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		return v.addFile(file, false)
+	})
+}
+
+// addFile loads the schemas of a file. Files with an unknown extension are
+// ignored unless explicit is set.
+func (v *Validator) addFile(file string, explicit bool) error {
+	var parse func([]byte) (map[schema.GroupVersionKind]map[string]interface{}, error)
+	switch filepath.Ext(file) {
+	case ".json":
+		parse = parseJSONSchemas
+	case ".yaml", ".yml":
+		parse = parseCRDs
+	default:
+		if !explicit {
+			return nil
+		}
+		return errors.Errorf("%s: schema files are JSON or YAML files", file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	schemas, err := parse(data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the schemas of %s", file)
+	}
+	v.add(schemas)
+	return nil
+}
+
+// AddCRDs loads the schemas of the kinds defined by the
+// CustomResourceDefinitions of a YAML file. Its other documents are ignored.
+func (v *Validator) AddCRDs(name string, data []byte) error {
+	schemas, err := parseCRDs(data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the CustomResourceDefinitions of %s", name)
+	}
+	v.add(schemas)
+	return nil
+}
+
+// AddChartCRDs loads the schemas of the kinds defined by the
+// CustomResourceDefinitions of a chart and of its dependencies.
+func (v *Validator) AddChartCRDs(c *chart.Chart) error {
+	for _, crd := range c.CRDObjects() {
+		if err := v.AddCRDs(crd.Filename, crd.File.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Validator) add(schemas map[schema.GroupVersionKind]map[string]interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for gvk, s := range schemas {
+		v.schemas[gvk] = s
+		delete(v.compiled, gvk)
+	}
+}
+
+// Validate validates a resource against the schema of its kind, returning
+// the errors sorted by field. Resources of kinds without schema are not
+// validated, unless their API group is a built-in one.
+func (v *Validator) Validate(obj map[string]interface{}) []error {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	if apiVersion == "" || kind == "" {
+		return []error{errors.New("apiVersion and kind are required")}
+	}
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+
+	s, err := v.schema(gvk)
+	if err != nil {
+		return []error{err}
+	}
+	if s == nil {
+		return nil
+	}
+	result, err := s.Validate(gojsonschema.NewGoLoader(obj))
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range result.Errors() {
+		errs = append(errs, validationError(e))
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+// sourcePattern matches the comments naming the template of a document.
+var sourcePattern = regexp.MustCompile(`(?m)^# Source: (.+)$`)
+
+// ValidateManifest validates the resources of a YAML manifest. The errors
+// name the resource they are about, and its template if the document has a
+// '# Source:' comment.
+func (v *Validator) ValidateManifest(manifest string) []error {
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var errs []error
+	for _, k := range keys {
+		prefix := ""
+		if m := sourcePattern.FindStringSubmatch(docs[k]); m != nil {
+			prefix = m[1] + ": "
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(docs[k]), &obj); err != nil {
+			errs = append(errs, errors.Wrapf(err, "%sunable to parse YAML", prefix))
+			continue
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		if name := resourceName(obj); name != "" {
+			prefix += name + ": "
+		}
+		for _, err := range v.Validate(obj) {
+			errs = append(errs, errors.New(prefix+err.Error()))
+		}
+	}
+	return errs
+}
+
+// resourceName returns the kind and the name of a resource, e.g.
+// "Deployment/web".
+func resourceName(obj map[string]interface{}) string {
+	kind, _ := obj["kind"].(string)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if kind == "" || name == "" {
+		return kind
+	}
+	return kind + "/" + name
+}
+
+// schema returns the compiled schema of a kind, or nil if there is none.
+func (v *Validator) schema(gvk schema.GroupVersionKind) (*gojsonschema.Schema, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.compiled[gvk]; ok {
+		return s, nil
+	}
+
+	doc, ok := v.schemas[gvk]
+	if !ok {
+		obj, err := builtinScheme.New(gvk)
+		if err != nil {
+			if builtinGroup(gvk.Group) {
+				return nil, errors.Errorf("%s %s is not a kind of the Kubernetes API", gvk.GroupVersion(), gvk.Kind)
+			}
+			v.compiled[gvk] = nil
+			return nil, nil
+		}
+		major, _ := strconv.Atoi(v.kubeVersion.Major)
+		minor, _ := strconv.Atoi(strings.TrimSuffix(v.kubeVersion.Minor, "+"))
+		if !builtinAvailable(obj, major, minor) {
+			return nil, errors.Errorf("%s %s is not available in Kubernetes %s", gvk.GroupVersion(), gvk.Kind, v.kubeVersion.Version)
+		}
+		doc, _ = builtinSchema(gvk)
+	}
+
+	s, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid schema of %s %s", gvk.GroupVersion(), gvk.Kind)
+	}
+	v.compiled[gvk] = s
+	return s, nil
+}
+
+// validationError returns a validation error of a field, e.g.
+// "spec.replicas: expected integer, got string".
+func validationError(e gojsonschema.ResultError) error {
+	field := e.Field()
+	switch e := e.(type) {
+	case *gojsonschema.AdditionalPropertyNotAllowedError:
+		property := fmt.Sprint(e.Details()["property"])
+		if field == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			return errors.Errorf("%s: unknown field", property)
+		}
+		return errors.Errorf("%s.%s: unknown field", field, property)
+	case *gojsonschema.InvalidTypeError:
+		expected := strings.Split(strings.Trim(fmt.Sprint(e.Details()["expected"]), "[]"), ",")
+		types := make([]string, 0, len(expected))
+		for _, t := range expected {
+			if t != "null" {
+				types = append(types, t)
+			}
+		}
+		return errors.Errorf("%s: expected %s, got %s", field, strings.Join(types, " or "), e.Details()["given"])
+	}
+	return errors.Errorf("%s: %s", field, e.Description())
+}
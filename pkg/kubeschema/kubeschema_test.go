@@ -0,0 +1,209 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeschema
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// checkErrors checks that the errors of a manifest are the expected ones.
+func checkErrors(t *testing.T, v *Validator, manifest string, expect ...string) {
+	t.Helper()
+	errs := v.ValidateManifest(manifest)
+	got := make([]string, len(errs))
+	for i, err := range errs {
+		got[i] = err.Error()
+	}
+	if strings.Join(got, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expected the errors\n%s\ngot\n%s", strings.Join(expect, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestBuiltinSchemas(t *testing.T) {
+	v := NewValidator(nil)
+
+	checkErrors(t, v, `# Source: mychart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+spec:
+  replica: 3
+  replicas: "3"
+  selector:
+    matchLabels:
+      app: web
+  strategy:
+    rollingUpdate:
+      maxSurge: 25%
+      maxUnavailable: 1
+  template:
+    metadata:
+      creationTimestamp: null
+    spec:
+      containers:
+        - name: web
+          image: nginx
+          resources:
+            limits:
+              cpu: 1
+              memory: 1Gi
+          ports:
+            - containerPort: "80"
+`,
+		"mychart/templates/deployment.yaml: Deployment/web: spec.replica: unknown field",
+		"mychart/templates/deployment.yaml: Deployment/web: spec.replicas: expected integer, got string",
+		"mychart/templates/deployment.yaml: Deployment/web: spec.template.spec.containers.0.ports.0.containerPort: expected integer, got string",
+	)
+
+	// Raw extensions and types decoding themselves accept anything.
+	checkErrors(t, v, `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    unknown: true
+`)
+
+	checkErrors(t, v, `apiVersion: apps/v1
+kind: Deploy
+metadata:
+  name: web
+`, "Deploy/web: apps/v1 Deploy is not a kind of the Kubernetes API")
+
+	// Kinds of other groups have no schema.
+	checkErrors(t, v, `apiVersion: example.com/v1
+kind: Widget
+spec: 1
+`)
+
+	checkErrors(t, v, "metadata:\n  name: web\n", "apiVersion and kind are required")
+}
+
+func TestBuiltinSchemasKubeVersion(t *testing.T) {
+	manifest := "apiVersion: flowcontrol.apiserver.k8s.io/v1beta3\nkind: FlowSchema\nmetadata:\n  name: test\n"
+
+	checkErrors(t, NewValidator(&chartutil.KubeVersion{Version: "v1.30.0", Major: "1", Minor: "30"}), manifest)
+	checkErrors(t, NewValidator(&chartutil.KubeVersion{Version: "v1.25.0", Major: "1", Minor: "25"}), manifest,
+		"FlowSchema/test: flowcontrol.apiserver.k8s.io/v1beta3 FlowSchema is not available in Kubernetes v1.25.0")
+	checkErrors(t, NewValidator(&chartutil.KubeVersion{Version: "v1.32.0", Major: "1", Minor: "32"}), manifest,
+		"FlowSchema/test: flowcontrol.apiserver.k8s.io/v1beta3 FlowSchema is not available in Kubernetes v1.32.0")
+}
+
+func TestCRDSchemas(t *testing.T) {
+	data, err := os.ReadFile("testdata/widgets.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := NewValidator(nil)
+	v := base.Copy()
+	if err := v.AddCRDs("widgets.yaml", data); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w
+spec:
+  size: big
+  port: http
+  colour: red
+  extra:
+    known: yes
+    unknown: true
+  template:
+    apiVersion: v1
+    kind: Pod
+    metadata:
+      name: p
+    spec:
+      replicas: 1
+      paused: true
+`
+	checkErrors(t, v, manifest,
+		"Widget/w: spec.colour: unknown field",
+		"Widget/w: spec.extra.known: expected string, got boolean",
+		"Widget/w: spec.size: expected integer, got string",
+		"Widget/w: spec.template.spec.paused: unknown field",
+	)
+	// The schemas of the copied validator are unchanged.
+	checkErrors(t, base, manifest)
+
+	if err := v.AddCRDs("bad.yaml", []byte("apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nspec: [\n")); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+}
+
+func TestAddChartCRDs(t *testing.T) {
+	data, err := os.ReadFile("testdata/widgets.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "parent"}}
+	sub := &chart.Chart{Metadata: &chart.Metadata{Name: "sub"}, Files: []*chart.File{{Name: "crds/widgets.yaml", Data: data}}}
+	c.AddDependency(sub)
+
+	v := NewValidator(nil)
+	if err := v.AddChartCRDs(c); err != nil {
+		t.Fatal(err)
+	}
+	checkErrors(t, v, "apiVersion: example.com/v1\nkind: Widget\nspec:\n  size: big\n", "Widget: spec.size: expected integer, got string")
+}
+
+func TestAddLocation(t *testing.T) {
+	v := NewValidator(nil)
+	if err := v.AddLocation("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The OpenAPI document replaces the built-in schema of ConfigMaps.
+	checkErrors(t, v, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: c
+  labels:
+  creationTimestamp: not a date
+  namespace: default
+data:
+  a: 1
+limit: 1
+port: 80
+`,
+		"ConfigMap/c: data.a: expected string, got integer",
+		"ConfigMap/c: metadata.namespace: unknown field",
+	)
+	checkErrors(t, v, "apiVersion: example.com/v1\nkind: Gadget\nspec:\n  size: 1\n  color: red\n", "Gadget: spec.color: unknown field")
+	checkErrors(t, v, "apiVersion: example.com/v1\nkind: Widget\nspec:\n  size: 1\n")
+
+	if err := NewValidator(nil).AddLocation("testdata/missing.json"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/schema.json", []byte(`{"type": "object"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewValidator(nil).AddLocation(dir); err == nil || !strings.Contains(err.Error(), "x-kubernetes-group-version-kind") {
+		t.Errorf("expected an error for a JSON schema without kind, got %v", err)
+	}
+}
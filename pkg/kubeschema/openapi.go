@@ -0,0 +1,245 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// gvkExtension lists the kinds an OpenAPI definition or a JSON schema is the
+// schema of.
+const gvkExtension = "x-kubernetes-group-version-kind"
+
+// parseJSONSchemas returns the schemas of the kinds in a JSON document: an
+// OpenAPI v2 or v3 document of the Kubernetes API, as served by
+// '/openapi/v2' or '/openapi/v3/apis/<group>/<version>', or a JSON schema of
+// a single kind with the x-kubernetes-group-version-kind extension.
+func parseJSONSchemas(data []byte) (map[schema.GroupVersionKind]map[string]interface{}, error) {
+	var doc map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	schemas := map[schema.GroupVersionKind]map[string]interface{}{}
+	if definitions, ok := doc["definitions"].(map[string]interface{}); ok && doc["swagger"] != nil {
+		addDefinitions(schemas, definitions, func(name string) map[string]interface{} {
+			return map[string]interface{}{"$ref": "#/definitions/" + name, "definitions": definitions}
+		})
+		return schemas, nil
+	}
+	if components, ok := doc["components"].(map[string]interface{}); ok && doc["openapi"] != nil {
+		definitions, _ := components["schemas"].(map[string]interface{})
+		addDefinitions(schemas, definitions, func(name string) map[string]interface{} {
+			return map[string]interface{}{"$ref": "#/components/schemas/" + name, "components": map[string]interface{}{"schemas": definitions}}
+		})
+		return schemas, nil
+	}
+	gvks := groupVersionKinds(doc)
+	if len(gvks) == 0 {
+		return nil, errors.Errorf("neither an OpenAPI document nor a JSON schema with the %s extension", gvkExtension)
+	}
+	normalize(doc)
+	if definitions, ok := doc["definitions"].(map[string]interface{}); ok {
+		for _, d := range definitions {
+			if s, ok := d.(map[string]interface{}); ok {
+				normalize(s)
+			}
+		}
+	}
+	for _, gvk := range gvks {
+		schemas[gvk] = doc
+	}
+	return schemas, nil
+}
+
+// addDefinitions normalizes the definitions of an OpenAPI document and adds
+// the schemas of the kinds they define, referring to them.
+func addDefinitions(schemas map[schema.GroupVersionKind]map[string]interface{}, definitions map[string]interface{}, ref func(name string) map[string]interface{}) {
+	for name, d := range definitions {
+		s, ok := d.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Quantities are numbers or strings, but are described as strings.
+		if strings.HasSuffix(name, ".api.resource.Quantity") {
+			s["type"] = []interface{}{"string", "integer", "number"}
+			delete(s, "oneOf")
+		}
+		normalize(s)
+		for _, gvk := range groupVersionKinds(s) {
+			schemas[gvk] = ref(name)
+		}
+	}
+}
+
+// groupVersionKinds returns the kinds of the x-kubernetes-group-version-kind
+// extension of a schema.
+func groupVersionKinds(s map[string]interface{}) []schema.GroupVersionKind {
+	list, _ := s[gvkExtension].([]interface{})
+	var gvks []schema.GroupVersionKind
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		group, _ := m["group"].(string)
+		version, _ := m["version"].(string)
+		kind, _ := m["kind"].(string)
+		if version != "" && kind != "" {
+			gvks = append(gvks, schema.GroupVersionKind{Group: group, Version: version, Kind: kind})
+		}
+	}
+	return gvks
+}
+
+// parseCRDs returns the schemas of the kinds defined by the
+// CustomResourceDefinitions of a YAML file. The other documents are ignored.
+func parseCRDs(data []byte) (map[schema.GroupVersionKind]map[string]interface{}, error) {
+	schemas := map[schema.GroupVersionKind]map[string]interface{}{}
+	manifests := releaseutil.SplitManifests(string(data))
+	for _, manifest := range manifests {
+		var typeMeta struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := yaml.Unmarshal([]byte(manifest), &typeMeta); err != nil {
+			return nil, err
+		}
+		if typeMeta.Kind != "CustomResourceDefinition" || typeMeta.APIVersion != apiextensionsv1.SchemeGroupVersion.String() {
+			continue
+		}
+		var crd apiextensionsv1.CustomResourceDefinition
+		if err := yaml.Unmarshal([]byte(manifest), &crd); err != nil {
+			return nil, err
+		}
+		for _, version := range crd.Spec.Versions {
+			if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			data, err := json.Marshal(version.Schema.OpenAPIV3Schema)
+			if err != nil {
+				return nil, err
+			}
+			var s map[string]interface{}
+			if err := json.Unmarshal(data, &s); err != nil {
+				return nil, err
+			}
+			addTypeMeta(s)
+			normalize(s)
+			gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}
+			schemas[gvk] = s
+		}
+	}
+	return schemas, nil
+}
+
+// addTypeMeta adds the apiVersion, kind and metadata fields of resources to
+// the properties of a schema, unless it describes them.
+func addTypeMeta(s map[string]interface{}) {
+	properties, ok := s["properties"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, name := range []string{"apiVersion", "kind"} {
+		if _, ok := properties[name]; !ok {
+			properties[name] = map[string]interface{}{"type": "string"}
+		}
+	}
+	if _, ok := properties["metadata"]; !ok {
+		properties["metadata"] = map[string]interface{}{"type": "object"}
+	}
+}
+
+// normalize rewrites an OpenAPI schema of the Kubernetes API into a JSON
+// schema validating resources as the API server decodes them:
+//
+//   - the fields of objects with properties are the only ones allowed, unless
+//     x-kubernetes-preserve-unknown-fields is set,
+//   - null is valid for every type, as it is decoded as the zero value,
+//   - int-or-string values are integers or strings,
+//   - formats are not checked.
+func normalize(s map[string]interface{}) {
+	if s["format"] == "int-or-string" || s["x-kubernetes-int-or-string"] == true {
+		s["type"] = []interface{}{"integer", "string"}
+		delete(s, "anyOf")
+	}
+	delete(s, "format")
+	if s["x-kubernetes-embedded-resource"] == true {
+		addTypeMeta(s)
+	}
+	switch t := s["type"].(type) {
+	case string:
+		s["type"] = []interface{}{t, "null"}
+	case []interface{}:
+		if !containsNull(t) {
+			s["type"] = append(t, "null")
+		}
+	}
+
+	if properties, ok := s["properties"].(map[string]interface{}); ok {
+		normalizeAll(properties)
+		if _, ok := s["additionalProperties"]; !ok && s["x-kubernetes-preserve-unknown-fields"] != true {
+			s["additionalProperties"] = false
+		}
+	}
+	if patternProperties, ok := s["patternProperties"].(map[string]interface{}); ok {
+		normalizeAll(patternProperties)
+	}
+	for _, key := range []string{"additionalProperties", "items", "not"} {
+		if sub, ok := s[key].(map[string]interface{}); ok {
+			normalize(sub)
+		}
+	}
+	for _, key := range []string{"items", "allOf", "anyOf", "oneOf"} {
+		if list, ok := s[key].([]interface{}); ok {
+			for _, item := range list {
+				if sub, ok := item.(map[string]interface{}); ok {
+					normalize(sub)
+				}
+			}
+		}
+	}
+}
+
+// normalizeAll normalizes the schemas of a map, e.g. of properties.
+func normalizeAll(schemas map[string]interface{}) {
+	for _, item := range schemas {
+		if sub, ok := item.(map[string]interface{}); ok {
+			normalize(sub)
+		}
+	}
+}
+
+func containsNull(types []interface{}) bool {
+	for _, t := range types {
+		if t == "null" {
+			return true
+		}
+	}
+	return false
+}
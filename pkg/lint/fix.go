@@ -0,0 +1,164 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint // import "helm.sh/helm/v3/pkg/lint"
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// FileFix is the change of a file of a chart by the fixes of lint messages.
+type FileFix struct {
+	// Path is the file, relative to the chart directory.
+	Path string
+	// Rename, when set, is the new path of the file.
+	Rename string
+	// Before and After are the content of the file before and after the
+	// fixes.
+	Before, After []byte
+	// Descriptions describe the fixes applied to the file.
+	Descriptions []string
+}
+
+// Fixes returns the changes of the files of the chart at chartDir by the
+// fixes of its lint messages, in the order of the messages. The fixes which
+// cannot be applied are returned as errors, and skipped.
+func Fixes(chartDir string, messages []support.Message, opts support.FixOptions) ([]FileFix, []error) {
+	if fi, err := os.Stat(chartDir); err != nil {
+		return nil, []error{err}
+	} else if !fi.IsDir() {
+		return nil, []error{errors.Errorf("%s is not a chart directory: packaged charts cannot be fixed", chartDir)}
+	}
+
+	var fixes []*FileFix
+	byPath := map[string]*FileFix{}
+	var errs []error
+	for _, msg := range messages {
+		fix := msg.Fix
+		if fix == nil {
+			continue
+		}
+		f, ok := byPath[fix.Path]
+		if !ok {
+			data, err := os.ReadFile(filepath.Join(chartDir, filepath.FromSlash(fix.Path)))
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "%s: cannot %s", fix.Path, fix.Description))
+				continue
+			}
+			f = &FileFix{Path: fix.Path, Before: data, After: data}
+			byPath[fix.Path] = f
+			fixes = append(fixes, f)
+		}
+		if containsString(f.Descriptions, fix.Description) {
+			continue
+		}
+
+		if fix.Edit != nil {
+			after, err := fix.Edit(f.After, opts)
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "%s: cannot %s", fix.Path, fix.Description))
+				continue
+			}
+			f.After = after
+		}
+		if fix.Rename != "" {
+			if _, err := os.Stat(filepath.Join(chartDir, filepath.FromSlash(fix.Rename))); err == nil {
+				errs = append(errs, errors.Errorf("%s: cannot %s: %s already exists", fix.Path, fix.Description, fix.Rename))
+				continue
+			}
+			f.Rename = fix.Rename
+		}
+		f.Descriptions = append(f.Descriptions, fix.Description)
+	}
+
+	result := make([]FileFix, 0, len(fixes))
+	for _, f := range fixes {
+		if len(f.Descriptions) > 0 {
+			result = append(result, *f)
+		}
+	}
+	return result, errs
+}
+
+// Diff returns the change of the file as a unified diff.
+func (f FileFix) Diff() string {
+	to := f.Path
+	if f.Rename != "" {
+		to = f.Rename
+	}
+	if bytes.Equal(f.Before, f.After) {
+		return fmt.Sprintf("rename %s to %s\n", f.Path, to)
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(f.Before),
+		B:        splitLines(f.After),
+		FromFile: "a/" + f.Path,
+		ToFile:   "b/" + to,
+		Context:  3,
+	})
+	return diff
+}
+
+// Apply writes the change of the file to the chart at chartDir.
+func (f FileFix) Apply(chartDir string) error {
+	name := filepath.Join(chartDir, filepath.FromSlash(f.Path))
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(f.Before, f.After) {
+		if err := os.WriteFile(name, f.After, fi.Mode()); err != nil {
+			return err
+		}
+	}
+	if f.Rename != "" {
+		return os.Rename(name, filepath.Join(chartDir, filepath.FromSlash(f.Rename)))
+	}
+	return nil
+}
+
+// String describes the fixes of the file.
+func (f FileFix) String() string {
+	return fmt.Sprintf("%s: %s", f.Path, strings.Join(f.Descriptions, ", "))
+}
+
+// splitLines splits a file into lines, each ending in a newline.
+func splitLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+const fixChartfile = `# The chart of the fix test
+apiVersion: v2
+name: fixme
+version: 1.10 # bumped by the release job
+`
+
+const fixDeployment = `apiVersion: {{ .Values.deploymentAPIVersion }}
+kind: Deployment
+metadata:
+  name: fixme
+spec:
+  selector:
+    matchLabels:
+      app: fixme
+  template:
+    metadata:
+      labels:
+        app: fixme
+    spec:
+      containers:
+        - name: fixme
+          image: fixme:1.0
+---
+# The scheduler of the chart
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: fixme
+spec:
+  minAvailable: 1
+  selector:
+    matchLabels:
+      app: fixme
+`
+
+func writeFixChart(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "fixme")
+	files := map[string]string{
+		"Chart.yaml":                fixChartfile,
+		"values.yaml":               "deploymentAPIVersion: apps/v1beta1\n",
+		"templates/deployment.yaml": fixDeployment,
+		"templates/configmap.j2":    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: fixme\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFixes(t *testing.T) {
+	dir := writeFixChart(t)
+	kubeVersion := &chartutil.KubeVersion{Version: "v1.22.0", Major: "1", Minor: "22"}
+	linter := AllWithOptions(dir, values, namespace, WithKubeVersion(kubeVersion))
+
+	fixes, errs := Fixes(dir, linter.Messages, support.FixOptions{})
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 fixes not to apply, got %v", errs)
+	}
+	// The icon is not given, and the apiVersion of the Deployment is
+	// computed by the template.
+	if !strings.Contains(errs[0].Error(), "Chart.yaml: cannot set icon: no icon URL is given") {
+		t.Errorf("unexpected error %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), "templates/deployment.yaml: cannot replace apiVersion apps/v1beta1 with apps/v1: line 1 does not contain the apiVersion apps/v1beta1") {
+		t.Errorf("unexpected error %v", errs[1])
+	}
+
+	fixes, errs = Fixes(dir, linter.Messages, support.FixOptions{Icon: "https://example.com/icon.png"})
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 fix not to apply, got %v", errs)
+	}
+	var descriptions []string
+	for _, f := range fixes {
+		descriptions = append(descriptions, f.String())
+	}
+	want := []string{
+		"Chart.yaml: set version to 1.10.0, set icon",
+		"templates/configmap.j2: rename to .yaml",
+		"templates/deployment.yaml: replace apiVersion policy/v1beta1 with policy/v1",
+	}
+	if strings.Join(descriptions, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected the fixes\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(descriptions, "\n"))
+	}
+
+	if diff := fixes[0].Diff(); diff != `--- a/Chart.yaml
++++ b/Chart.yaml
+@@ -1,4 +1,5 @@
+ # The chart of the fix test
+ apiVersion: v2
+ name: fixme
+-version: 1.10 # bumped by the release job
++version: 1.10.0 # bumped by the release job
++icon: https://example.com/icon.png
+` {
+		t.Errorf("unexpected diff\n%s", diff)
+	}
+	if diff := fixes[1].Diff(); diff != "rename templates/configmap.j2 to templates/configmap.yaml\n" {
+		t.Errorf("unexpected diff %q", diff)
+	}
+
+	for _, f := range fixes {
+		if err := f.Apply(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "templates", "configmap.yaml")); err != nil {
+		t.Errorf("expected the template to be renamed: %s", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "templates", "deployment.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# The scheduler of the chart\napiVersion: policy/v1\n") {
+		t.Errorf("expected the apiVersion to be replaced, got\n%s", data)
+	}
+
+	// The fixed findings are not reported anymore.
+	linter = AllWithOptions(dir, values, namespace, WithKubeVersion(kubeVersion))
+	fixes, errs = Fixes(dir, linter.Messages, support.FixOptions{Icon: "https://example.com/icon.png"})
+	if len(fixes) != 0 || len(errs) != 1 {
+		t.Errorf("expected only the fix of the Deployment to remain, got %v and %v", fixes, errs)
+	}
+}
+
+func TestFixesPackagedChart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fixme-0.1.0.tgz")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, errs := Fixes(file, nil, support.FixOptions{}); len(errs) != 1 || !strings.Contains(errs[0].Error(), "packaged charts cannot be fixed") {
+		t.Errorf("expected an error for a packaged chart, got %v", errs)
+	}
+}
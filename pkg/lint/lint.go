@@ -18,8 +18,11 @@ package lint // import "helm.sh/helm/v3/pkg/lint"
 
 import (
 	"path/filepath"
+	"time"
 
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/kubeschema"
 	"helm.sh/helm/v3/pkg/lint/rules"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
@@ -31,13 +34,109 @@ func All(basedir string, values map[string]interface{}, namespace string, _ bool
 
 // AllWithKubeVersion runs all the available linters on the given base directory, allowing to specify the kubernetes version.
 func AllWithKubeVersion(basedir string, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) support.Linter {
+	return AllWithOptions(basedir, values, namespace, WithKubeVersion(kubeVersion))
+}
+
+// LinterOption configures optional behavior of AllWithOptions.
+type LinterOption func(lo *linterOptions)
+
+type linterOptions struct {
+	templates rules.TemplateLintOptions
+	config    *support.Config
+	packs     []string
+}
+
+// WithKubeVersion sets the Kubernetes version used for capabilities and deprecation checks.
+func WithKubeVersion(kubeVersion *chartutil.KubeVersion) LinterOption {
+	return func(lo *linterOptions) {
+		lo.templates.KubeVersion = kubeVersion
+	}
+}
+
+// WithCapabilities replaces the default capabilities used to render the templates,
+// e.g. with a profile captured from a cluster.
+func WithCapabilities(caps *chartutil.Capabilities) LinterOption {
+	return func(lo *linterOptions) {
+		lo.templates.Capabilities = caps
+	}
+}
+
+// WithLookupClientProvider backs the 'lookup' template function with the given
+// provider, e.g. offline fixtures, while rendering the templates.
+func WithLookupClientProvider(clientProvider engine.ClientProvider) LinterOption {
+	return func(lo *linterOptions) {
+		lo.templates.LookupClientProvider = clientProvider
+	}
+}
+
+// WithTemplateFuncs provides additional template functions while rendering
+// the templates, so that charts relying on them can be linted.
+func WithTemplateFuncs(funcs *engine.FuncRegistry) LinterOption {
+	return func(lo *linterOptions) {
+		lo.templates.Funcs = funcs
+	}
+}
+
+// WithTemplateRenderers provides additional renderers for the templates of
+// the charts selecting them in their Chart.yaml.
+func WithTemplateRenderers(renderers map[string]engine.Renderer) LinterOption {
+	return func(lo *linterOptions) {
+		lo.templates.Renderers = renderers
+	}
+}
+
+// WithRenderLimits bounds the output of the templates and the time spent
+// rendering them. A zero timeout means no limit.
+func WithRenderLimits(limits engine.RenderLimits, timeout time.Duration) LinterOption {
+	return func(lo *linterOptions) {
+		lo.templates.RenderLimits = limits
+		lo.templates.RenderTimeout = timeout
+	}
+}
+
+// WithSchemaValidator validates the rendered resources against the schemas
+// of the validator and those of the CustomResourceDefinitions of the chart.
+func WithSchemaValidator(validator *kubeschema.Validator) LinterOption {
+	return func(lo *linterOptions) {
+		lo.templates.SchemaValidator = validator
+	}
+}
+
+// WithConfig configures the rules with the given configuration instead of
+// the lint configuration files of the chart.
+func WithConfig(config *support.Config) LinterOption {
+	return func(lo *linterOptions) {
+		lo.config = config
+	}
+}
+
+// WithPacks enables packs of opt-in rules, e.g. "policy", in addition to
+// those enabled by the configuration.
+func WithPacks(packs ...string) LinterOption {
+	return func(lo *linterOptions) {
+		lo.packs = append(lo.packs, packs...)
+	}
+}
+
+// AllWithOptions runs all the available linters on the given base directory,
+// configured by the given options.
+func AllWithOptions(basedir string, values map[string]interface{}, namespace string, options ...LinterOption) support.Linter {
+	lo := linterOptions{}
+	for _, option := range options {
+		option(&lo)
+	}
+
 	// Using abs path to get directory context
 	chartDir, _ := filepath.Abs(basedir)
 
-	linter := support.Linter{ChartDir: chartDir}
+	linter := support.Linter{ChartDir: chartDir, Config: lo.config, Packs: lo.packs}
+	rules.Config(&linter, Rules())
 	rules.Chartfile(&linter)
 	rules.ValuesWithOverrides(&linter, values)
-	rules.TemplatesWithKubeVersion(&linter, values, namespace, kubeVersion)
+	rules.TemplatesWithOptions(&linter, values, namespace, lo.templates)
 	rules.Dependencies(&linter)
+	for _, check := range registeredChecks() {
+		check(&linter, values)
+	}
 	return linter
 }
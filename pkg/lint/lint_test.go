@@ -17,11 +17,17 @@ limitations under the License.
 package lint
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -171,3 +177,132 @@ func TestMalformedTemplate(t *testing.T) {
 		}
 	}
 }
+
+func TestMalformedTemplateValuesPack(t *testing.T) {
+	// The values of a chart whose templates do not render valid YAML are
+	// not reported as unused.
+	m := AllWithOptions(malformedTemplate, values, namespace, WithPacks("values")).Messages
+	if len(m) != 1 {
+		t.Fatalf("All didn't fail with expected errors, got %#v", m)
+	}
+	if !strings.Contains(m[0].Err.Error(), "invalid character '{'") {
+		t.Errorf("All didn't have the error for invalid character '{'")
+	}
+}
+
+func TestLintConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	createdChart, err := chartutil.Create("withconfig", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := "rules:\n  chart-icon-present: error\n  no-such-rule: warning\n"
+	if err := os.WriteFile(filepath.Join(dir, support.ConfigFileName), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	linter := All(createdChart, values, namespace, strict)
+	if len(linter.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %v", linter.Messages)
+	}
+	if m := linter.Messages[0]; m.RuleID != "lint-config" || !strings.Contains(m.Err.Error(), `unknown lint rule "no-such-rule"`) {
+		t.Errorf("expected a warning about the unknown rule, got %s", m)
+	}
+	if m := linter.Messages[1]; m.RuleID != "chart-icon-present" || m.Severity != support.ErrorSev {
+		t.Errorf("expected chart-icon-present to be an error, got %s", m)
+	}
+
+	// The configuration of the chart takes precedence.
+	if err := os.WriteFile(filepath.Join(createdChart, support.ConfigFileName), []byte("rules:\n  chart-icon-present: off\n  no-such-rule: off\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if m := All(createdChart, values, namespace, strict).Messages; len(m) != 1 || m[0].RuleID != "lint-config" {
+		t.Errorf("expected only a warning about the unknown rule, got %v", m)
+	}
+
+	// An explicit configuration replaces the configuration files.
+	explicit := &support.Config{Rules: map[string]string{"chart-icon-present": "warning"}}
+	m := AllWithOptions(createdChart, values, namespace, WithConfig(explicit)).Messages
+	if len(m) != 1 || m[0].RuleID != "chart-icon-present" || m[0].Severity != support.WarningSev {
+		t.Errorf("expected chart-icon-present to be a warning, got %v", m)
+	}
+}
+
+// emptyRenderer renders all templates as empty manifests.
+type emptyRenderer struct{}
+
+func (emptyRenderer) Render(context.Context, string, []byte, chartutil.Values) (string, error) {
+	return "", nil
+}
+
+func TestTemplateRenderers(t *testing.T) {
+	createdChart, err := chartutil.Create("withrenderer", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartFile := filepath.Join(createdChart, chartutil.ChartfileName)
+	data, err := os.ReadFile(chartFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(chartFile, append(data, "renderer: empty\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := All(createdChart, values, namespace, strict).Messages
+	if len(m) != 2 || m[1].RuleID != "chart-renderer" || !strings.Contains(m[1].Err.Error(), `template renderer "empty" is unknown`) {
+		t.Errorf("expected an error about the unknown renderer, got %v", m)
+	}
+
+	renderers := map[string]engine.Renderer{"empty": emptyRenderer{}}
+	m = AllWithOptions(createdChart, values, namespace, WithTemplateRenderers(renderers)).Messages
+	if len(m) != 1 || m[0].RuleID != "chart-icon-present" {
+		t.Errorf("expected only the chart-icon-present message, got %v", m)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	rule := support.Rule{ID: "test-no-forbidden-value", Severity: support.WarningSev, Description: "values do not set forbidden"}
+	err := Register(rule, func(linter *support.Linter, values map[string]interface{}) {
+		if _, ok := values["forbidden"]; ok {
+			linter.RunRule(rule, "values.yaml", errors.New("forbidden is set"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Register(rule, func(*support.Linter, map[string]interface{}) {}); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected an error for a duplicate rule, got %v", err)
+	}
+	builtin := support.Rule{ID: "chart-name", Severity: support.ErrorSev}
+	if err := Register(builtin, func(*support.Linter, map[string]interface{}) {}); err == nil {
+		t.Error("expected an error for the ID of a built-in rule")
+	}
+	if err := Register(support.Rule{ID: "test-no-check", Severity: support.InfoSev}, nil); err == nil {
+		t.Error("expected an error for a rule without check")
+	}
+
+	found := false
+	all := Rules()
+	for i, r := range all {
+		if i > 0 && all[i-1].ID > r.ID {
+			t.Errorf("rules are not sorted: %s before %s", all[i-1].ID, r.ID)
+		}
+		found = found || r.ID == rule.ID
+	}
+	if !found {
+		t.Errorf("expected %s in the rules", rule.ID)
+	}
+
+	if m := All(goodChartDir, values, namespace, strict).Messages; len(m) != 0 {
+		t.Errorf("expected no messages, got %v", m)
+	}
+	m := All(goodChartDir, map[string]interface{}{"forbidden": true}, namespace, strict).Messages
+	if len(m) != 1 || m[0].RuleID != rule.ID || m[0].Severity != support.WarningSev {
+		t.Errorf("expected a message of the registered rule, got %v", m)
+	}
+}
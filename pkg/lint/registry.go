@@ -0,0 +1,93 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// RuleCheck runs a registered rule on the chart in linter.ChartDir, with the
+// given values. It reports its findings with linter.RunRule, so that they can
+// be configured like those of the built-in rules.
+type RuleCheck func(linter *support.Linter, values map[string]interface{})
+
+type registeredRule struct {
+	rule  support.Rule
+	check RuleCheck
+}
+
+var registry struct {
+	sync.Mutex
+	rules []registeredRule
+}
+
+// Register adds a rule to those run by AllWithOptions, after the built-in
+// rules. It returns an error if the rule is invalid or its ID is already
+// used by another rule.
+func Register(rule support.Rule, check RuleCheck) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if check == nil {
+		return errors.Errorf("lint rule %s has no check", rule.ID)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	for _, r := range allRules() {
+		if r.ID == rule.ID {
+			return errors.Errorf("lint rule %s is already registered", rule.ID)
+		}
+	}
+	registry.rules = append(registry.rules, registeredRule{rule, check})
+	return nil
+}
+
+// Rules returns the built-in and the registered rules, sorted by ID.
+func Rules() []support.Rule {
+	registry.Lock()
+	defer registry.Unlock()
+	all := allRules()
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all
+}
+
+// allRules returns the built-in and the registered rules. The registry must
+// be locked.
+func allRules() []support.Rule {
+	all := rules.Builtin()
+	for _, r := range registry.rules {
+		all = append(all, r.rule)
+	}
+	return all
+}
+
+// registeredChecks returns the checks of the registered rules.
+func registeredChecks() []RuleCheck {
+	registry.Lock()
+	defer registry.Unlock()
+	checks := make([]RuleCheck, len(registry.rules))
+	for i, r := range registry.rules {
+		checks[i] = r.check
+	}
+	return checks
+}
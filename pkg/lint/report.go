@@ -0,0 +1,316 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// loadErrorRuleID is the ID of the rule reporting the charts that cannot be
+// linted at all.
+const loadErrorRuleID = "chart-loadable"
+
+// ChartReport is the result of linting a chart.
+type ChartReport struct {
+	// Chart is the path of the chart, as given to lint it.
+	Chart string
+	// Messages are the messages of the lint rules.
+	Messages []support.Message
+	// Err is the error preventing the chart from being linted, if any.
+	Err error
+}
+
+// file returns the path of a file of the chart, relative to the current
+// directory, with slashes.
+func (r ChartReport) file(p string) string {
+	if p == "" || filepath.IsAbs(p) || filepath.Clean(p) == filepath.Clean(r.Chart) {
+		return filepath.ToSlash(r.Chart)
+	}
+	return path.Join(filepath.ToSlash(r.Chart), p)
+}
+
+type jsonReport struct {
+	Charts []jsonChart `json:"charts"`
+}
+
+type jsonChart struct {
+	Chart    string        `json:"chart"`
+	Error    string        `json:"error,omitempty"`
+	Messages []jsonMessage `json:"messages"`
+}
+
+type jsonMessage struct {
+	Severity string `json:"severity"`
+	RuleID   string `json:"rule_id,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+	// Combinations are those of a lint matrix the message is reported for.
+	Combinations []string `json:"combinations,omitempty"`
+}
+
+// WriteJSON writes the reports as a JSON document.
+func WriteJSON(out io.Writer, reports []ChartReport) error {
+	report := jsonReport{Charts: []jsonChart{}}
+	for _, r := range reports {
+		c := jsonChart{Chart: r.Chart, Messages: []jsonMessage{}}
+		if r.Err != nil {
+			c.Error = r.Err.Error()
+		}
+		for _, m := range r.Messages {
+			c.Messages = append(c.Messages, jsonMessage{
+				Severity:     strings.ToLower(support.SeverityName(m.Severity)),
+				RuleID:       m.RuleID,
+				Path:         m.Path,
+				Line:         m.Line,
+				Column:       m.Column,
+				Message:      m.Err.Error(),
+				Combinations: m.Combinations,
+			})
+		}
+		report.Charts = append(report.Charts, c)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifText          `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	RuleIndex *int            `json:"ruleIndex,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifLevel returns the SARIF level of a severity.
+func sarifLevel(severity int) string {
+	switch severity {
+	case support.ErrorSev:
+		return "error"
+	case support.WarningSev:
+		return "warning"
+	case support.InfoSev:
+		return "note"
+	}
+	return "none"
+}
+
+// WriteSARIF writes the reports as a SARIF 2.1.0 log, with a result for
+// each message. The locations are relative to the current directory.
+func WriteSARIF(out io.Writer, reports []ChartReport) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "helm lint",
+			InformationURI: "https://helm.sh/docs/helm/helm_lint/",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	index := map[string]int{}
+	for i, rule := range Rules() {
+		index[rule.ID] = i
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifText{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Severity)},
+		})
+	}
+
+	result := func(ruleID string, severity int, msg, uri string, line, column int) sarifResult {
+		res := sarifResult{
+			RuleID:  ruleID,
+			Level:   sarifLevel(severity),
+			Message: sarifText{Text: msg},
+		}
+		if i, ok := index[ruleID]; ok {
+			res.RuleIndex = &i
+		}
+		loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}}}
+		if line > 0 {
+			loc.PhysicalLocation.Region = &sarifRegion{StartLine: line, StartColumn: column}
+		}
+		res.Locations = []sarifLocation{loc}
+		return res
+	}
+	for _, r := range reports {
+		if r.Err != nil {
+			run.Results = append(run.Results, result(loadErrorRuleID, support.ErrorSev, r.Err.Error(), r.file(""), 0, 0))
+		}
+		for _, m := range r.Messages {
+			run.Results = append(run.Results, result(m.RuleID, m.Severity, messageText(m), r.file(m.Path), m.Line, m.Column))
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// WriteJUnit writes the reports as a JUnit XML report, with a test suite
+// for each chart and a test case for each message. Warnings and errors are
+// failures. A chart without messages has a single passing test case.
+func WriteJUnit(out io.Writer, reports []ChartReport) error {
+	var report junitTestSuites
+	for _, r := range reports {
+		suite := junitTestSuite{Name: r.Chart}
+		fail := func(name, severity, msg string) {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      name,
+				ClassName: r.Chart,
+				Failure:   &junitFailure{Message: msg, Type: severity, Contents: msg},
+			})
+			suite.Failures++
+		}
+
+		if r.Err != nil {
+			fail(loadErrorRuleID, support.SeverityName(support.ErrorSev), r.Err.Error())
+		}
+		for _, m := range r.Messages {
+			name := m.RuleID
+			if name == "" {
+				name = "lint"
+			}
+			if file := r.file(m.Path); m.Line > 0 {
+				name = fmt.Sprintf("%s: %s:%d", name, file, m.Line)
+			} else {
+				name = fmt.Sprintf("%s: %s", name, file)
+			}
+			if m.Severity > support.InfoSev {
+				fail(name, support.SeverityName(m.Severity), messageText(m))
+				continue
+			}
+			suite.Cases = append(suite.Cases, junitTestCase{Name: name, ClassName: r.Chart, SystemOut: m.Error()})
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{Name: "lint", ClassName: r.Chart})
+		}
+		suite.Tests = len(suite.Cases)
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// messageText returns the text of the error of a message, followed by the
+// combinations of a lint matrix it is reported for, if any.
+func messageText(m support.Message) string {
+	if len(m.Combinations) == 0 {
+		return m.Err.Error()
+	}
+	return fmt.Sprintf("%s (in %s)", m.Err.Error(), strings.Join(m.Combinations, ", "))
+}
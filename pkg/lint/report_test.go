@@ -0,0 +1,149 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+var testReports = []ChartReport{{
+	Chart: "charts/web",
+	Messages: []support.Message{
+		{Severity: support.InfoSev, Path: "Chart.yaml", Err: errors.New("icon is recommended"), RuleID: "chart-icon-present"},
+		{Severity: support.ErrorSev, Path: "templates/deployment.yaml", Err: errors.New("nil pointer"), RuleID: "templates-render", Line: 3, Column: 12},
+		{Severity: support.WarningSev, Path: "charts/web", Err: errors.New("missing dependency"), RuleID: "dependencies-present"},
+	},
+}, {
+	Chart: "charts/ok",
+}, {
+	Chart: "charts/missing",
+	Err:   errors.New("unable to check Chart.yaml file in chart"),
+}}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteJSON(&out, testReports); err != nil {
+		t.Fatal(err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Charts) != 3 {
+		t.Fatalf("expected 3 charts, got %d", len(report.Charts))
+	}
+	expect := jsonMessage{Severity: "error", RuleID: "templates-render", Path: "templates/deployment.yaml", Line: 3, Column: 12, Message: "nil pointer"}
+	if m := report.Charts[0].Messages[1]; !reflect.DeepEqual(m, expect) {
+		t.Errorf("expected %+v, got %+v", expect, m)
+	}
+	if c := report.Charts[1]; c.Chart != "charts/ok" || len(c.Messages) != 0 || c.Error != "" {
+		t.Errorf("unexpected chart %+v", c)
+	}
+	if c := report.Charts[2]; c.Error != "unable to check Chart.yaml file in chart" {
+		t.Errorf("expected the error of the chart, got %+v", c)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteSARIF(&out, testReports); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF log %+v", log)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(Rules()) {
+		t.Errorf("expected %d rules, got %d", len(Rules()), len(run.Tool.Driver.Rules))
+	}
+
+	expect := []struct {
+		ruleID, level, uri string
+		line, column       int
+	}{
+		{"chart-icon-present", "note", "charts/web/Chart.yaml", 0, 0},
+		{"templates-render", "error", "charts/web/templates/deployment.yaml", 3, 12},
+		{"dependencies-present", "warning", "charts/web", 0, 0},
+		{"chart-loadable", "error", "charts/missing", 0, 0},
+	}
+	if len(run.Results) != len(expect) {
+		t.Fatalf("expected %d results, got %d", len(expect), len(run.Results))
+	}
+	for i, e := range expect {
+		r := run.Results[i]
+		if r.RuleID != e.ruleID || r.Level != e.level {
+			t.Errorf("expected result %d to be a %s of %s, got a %s of %s", i, e.level, e.ruleID, r.Level, r.RuleID)
+		}
+		if r.RuleIndex == nil || run.Tool.Driver.Rules[*r.RuleIndex].ID != e.ruleID {
+			t.Errorf("expected result %d to have the index of %s", i, e.ruleID)
+		}
+		loc := r.Locations[0].PhysicalLocation
+		if loc.ArtifactLocation.URI != e.uri {
+			t.Errorf("expected result %d to be in %s, got %s", i, e.uri, loc.ArtifactLocation.URI)
+		}
+		if e.line == 0 && loc.Region != nil {
+			t.Errorf("expected result %d to have no region, got %+v", i, loc.Region)
+		}
+		if e.line > 0 && (loc.Region == nil || loc.Region.StartLine != e.line || loc.Region.StartColumn != e.column) {
+			t.Errorf("expected result %d to be at %d:%d, got %+v", i, e.line, e.column, loc.Region)
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteJUnit(&out, testReports); err != nil {
+		t.Fatal(err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Suites) != 3 {
+		t.Fatalf("expected 3 test suites, got %d", len(report.Suites))
+	}
+
+	web := report.Suites[0]
+	if web.Tests != 3 || web.Failures != 2 {
+		t.Errorf("expected 3 tests and 2 failures, got %d and %d", web.Tests, web.Failures)
+	}
+	if c := web.Cases[0]; c.Failure != nil || !strings.Contains(c.SystemOut, "icon is recommended") {
+		t.Errorf("expected the info message to pass, got %+v", c)
+	}
+	if c := web.Cases[1]; c.Name != "templates-render: charts/web/templates/deployment.yaml:3" || c.Failure == nil || c.Failure.Type != "ERROR" {
+		t.Errorf("unexpected test case %+v", c)
+	}
+	if ok := report.Suites[1]; ok.Tests != 1 || ok.Failures != 0 {
+		t.Errorf("expected a passing test for a chart without messages, got %+v", ok)
+	}
+	if missing := report.Suites[2]; missing.Failures != 1 || missing.Cases[0].Name != "chart-loadable" {
+		t.Errorf("expected a failure for a chart that cannot be linted, got %+v", missing)
+	}
+}
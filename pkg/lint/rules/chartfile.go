@@ -36,10 +36,10 @@ func Chartfile(linter *support.Linter) {
 	chartFileName := "Chart.yaml"
 	chartPath := filepath.Join(linter.ChartDir, chartFileName)
 
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartYamlNotDirectory(chartPath))
+	linter.RunRule(chartfileNotDirectory, chartFileName, validateChartYamlNotDirectory(chartPath))
 
 	chartFile, err := chartutil.LoadChartfile(chartPath)
-	validChartFile := linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartYamlFormat(err))
+	validChartFile := linter.RunRule(chartfileFormat, chartFileName, validateChartYamlFormat(err))
 
 	// Guard clause. Following linter rules require a parsable ChartFile
 	if !validChartFile {
@@ -50,20 +50,23 @@ func Chartfile(linter *support.Linter) {
 	// errors would already be caught in the above load function
 	chartFileForTypeCheck, _ := loadChartFileForTypeCheck(chartPath)
 
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartName(chartFile))
+	linter.RunRule(chartName, chartFileName, validateChartName(chartFile))
 
 	// Chart metadata
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartAPIVersion(chartFile))
-
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartVersionType(chartFileForTypeCheck))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartVersion(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartAppVersionType(chartFileForTypeCheck))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartMaintainer(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartSources(chartFile))
-	linter.RunLinterRule(support.InfoSev, chartFileName, validateChartIconPresence(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartIconURL(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartType(chartFile))
-	linter.RunLinterRule(support.ErrorSev, chartFileName, validateChartDependencies(chartFile))
+	linter.RunRule(chartAPIVersion, chartFileName, validateChartAPIVersion(chartFile))
+
+	// The version as written, for the fix of the version rules
+	data, _ := os.ReadFile(chartPath)
+	fixVersion := versionFix(topLevelScalar(data, "version"))
+	linter.RunRule(chartVersionType, chartFileName, support.WithFix(validateChartVersionType(chartFileForTypeCheck), fixVersion))
+	linter.RunRule(chartVersion, chartFileName, support.WithFix(validateChartVersion(chartFile), fixVersion))
+	linter.RunRule(chartAppVersionType, chartFileName, validateChartAppVersionType(chartFileForTypeCheck))
+	linter.RunRule(chartMaintainers, chartFileName, validateChartMaintainer(chartFile))
+	linter.RunRule(chartSources, chartFileName, validateChartSources(chartFile))
+	linter.RunRule(chartIconPresent, chartFileName, support.WithFix(validateChartIconPresence(chartFile), iconFix))
+	linter.RunRule(chartIconURL, chartFileName, validateChartIconURL(chartFile))
+	linter.RunRule(chartType, chartFileName, validateChartType(chartFile))
+	linter.RunRule(chartDependencies, chartFileName, validateChartDependencies(chartFile))
 }
 
 func validateChartVersionType(data map[string]interface{}) error {
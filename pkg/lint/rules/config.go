@@ -0,0 +1,60 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules // import "helm.sh/helm/v3/pkg/lint/rules"
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// Config loads the lint configuration files of the chart into the Linter,
+// unless its configuration is already set, and checks that the configuration
+// only refers to the known rules and packs.
+func Config(linter *support.Linter, known []support.Rule) {
+	if linter.Config == nil {
+		config, err := support.LoadConfig(linter.ChartDir)
+		if !linter.RunRule(lintConfig, support.ConfigFileName, err) {
+			return
+		}
+		linter.Config = config
+	}
+	if linter.Config == nil {
+		return
+	}
+
+	path := support.ConfigFileName
+	if files := linter.Config.Files(); len(files) > 0 {
+		path = files[len(files)-1]
+	}
+	ids := map[string]bool{}
+	packs := map[string]bool{}
+	for _, rule := range known {
+		ids[rule.ID] = true
+		packs[rule.Pack] = true
+	}
+	for _, id := range linter.Config.RuleIDs() {
+		if !ids[id] {
+			linter.RunRule(lintConfig, path, errors.Errorf("unknown lint rule %q", id))
+		}
+	}
+	for _, pack := range linter.Config.Packs {
+		if pack == "" || !packs[pack] {
+			linter.RunRule(lintConfig, path, errors.Errorf("unknown lint rule pack %q", pack))
+		}
+	}
+}
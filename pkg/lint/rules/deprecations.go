@@ -18,6 +18,7 @@ package rules // import "helm.sh/helm/v3/pkg/lint/rules"
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -40,6 +41,61 @@ var (
 type deprecatedAPIError struct {
 	Deprecated string
 	Message    string
+	// Replacement is the API version and kind replacing the deprecated
+	// one, if known.
+	Replacement schema.GroupVersionKind
+}
+
+// apiLifecycleReplacement is implemented by the deprecated API types which
+// have a replacement.
+type apiLifecycleReplacement interface {
+	APILifecycleReplacement() schema.GroupVersionKind
+}
+
+// apiLifecycleRemoved is implemented by the API types which are removed in a
+// version of Kubernetes.
+type apiLifecycleRemoved interface {
+	APILifecycleRemoved() (major, minor int)
+}
+
+// RemovedAPI is an API of Kubernetes which is removed in a version of
+// Kubernetes, and replaced by another API version of the same kind.
+type RemovedAPI struct {
+	Deprecated  schema.GroupVersionKind
+	Replacement schema.GroupVersionKind
+	// RemovedMajor and RemovedMinor are the version of Kubernetes the
+	// deprecated API is removed in.
+	RemovedMajor, RemovedMinor int
+}
+
+// RemovedAPIs returns the removed APIs of Kubernetes with a replacement of
+// the same kind, as known to client-go, sorted by group, version and kind.
+func RemovedAPIs() []RemovedAPI {
+	var apis []RemovedAPI
+	for gvk := range kscheme.Scheme.AllKnownTypes() {
+		obj, err := kscheme.Scheme.New(gvk)
+		if err != nil {
+			continue
+		}
+		replaced, ok := obj.(apiLifecycleReplacement)
+		if !ok {
+			continue
+		}
+		removed, ok := obj.(apiLifecycleRemoved)
+		if !ok {
+			continue
+		}
+		replacement := replaced.APILifecycleReplacement()
+		major, minor := removed.APILifecycleRemoved()
+		if replacement.Kind != gvk.Kind || major == 0 {
+			continue
+		}
+		apis = append(apis, RemovedAPI{Deprecated: gvk, Replacement: replacement, RemovedMajor: major, RemovedMinor: minor})
+	}
+	sort.Slice(apis, func(i, j int) bool {
+		return apis[i].Deprecated.String() < apis[j].Deprecated.String()
+	})
+	return apis
 }
 
 func (e deprecatedAPIError) Error() string {
@@ -86,10 +142,14 @@ func validateNoDeprecations(resource *K8sYamlStruct, kubeVersion *chartutil.Kube
 		return nil
 	}
 	gvk := fmt.Sprintf("%s %s", resource.APIVersion, resource.Kind)
-	return deprecatedAPIError{
+	deprecated := deprecatedAPIError{
 		Deprecated: gvk,
 		Message:    deprecation.WarningMessage(runtimeObject),
 	}
+	if r, ok := runtimeObject.(apiLifecycleReplacement); ok {
+		deprecated.Replacement = r.APILifecycleReplacement()
+	}
+	return deprecated
 }
 
 func resourceToRuntimeObject(resource *K8sYamlStruct) (runtime.Object, error) {
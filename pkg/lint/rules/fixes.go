@@ -0,0 +1,222 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	yamlv3 "sigs.k8s.io/yaml/goyaml.v3"
+
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// leadingVersion matches the numbers a version starts with, e.g. "v1.2" in
+// "v1.2-final".
+var leadingVersion = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// versionFix returns the fix setting the version of Chart.yaml to the SemVer
+// form of its current value, or nil if it has none.
+func versionFix(version string) *support.Fix {
+	fixed := fixedVersion(version)
+	if fixed == "" || fixed == version {
+		return nil
+	}
+	return &support.Fix{
+		Description: fmt.Sprintf("set version to %s", fixed),
+		Path:        "Chart.yaml",
+		Edit: func(data []byte, _ support.FixOptions) ([]byte, error) {
+			return setTopLevelScalar(data, "version", fixed)
+		},
+	}
+}
+
+// fixedVersion returns the SemVer form of a version, e.g. "1.2.0" for "v1.2",
+// or "" if it does not start with a version number.
+func fixedVersion(version string) string {
+	if v, err := semver.NewVersion(version); err == nil {
+		return v.String()
+	}
+	m := leadingVersion.FindStringSubmatch(version)
+	if m == nil {
+		return ""
+	}
+	for i := 2; i <= 3; i++ {
+		if m[i] == "" {
+			m[i] = "0"
+		}
+	}
+	v, err := semver.NewVersion(strings.Join(m[1:], "."))
+	if err != nil {
+		return ""
+	}
+	return v.String()
+}
+
+// iconFix is the fix adding the icon given by the options to Chart.yaml.
+var iconFix = &support.Fix{
+	Description: "set icon",
+	Path:        "Chart.yaml",
+	Edit: func(data []byte, opts support.FixOptions) ([]byte, error) {
+		if opts.Icon == "" {
+			return nil, errors.New("no icon URL is given")
+		}
+		return setTopLevelScalar(data, "icon", opts.Icon)
+	},
+}
+
+// extensionFix returns the fix renaming a template with an invalid extension
+// to a YAML file, or to a .tpl file for partials, whose names start with an
+// underscore.
+func extensionFix(fileName string) *support.Fix {
+	ext := ".yaml"
+	if strings.HasPrefix(path.Base(fileName), "_") {
+		ext = ".tpl"
+	}
+	return &support.Fix{
+		Description: fmt.Sprintf("rename to %s", ext),
+		Path:        fileName,
+		Rename:      strings.TrimSuffix(fileName, path.Ext(fileName)) + ext,
+	}
+}
+
+// deprecatedAPIFix returns the fix replacing the apiVersion of a resource of
+// a deprecated API with that of its replacement, in the template line it
+// comes from, or nil if the replacement is unknown or has another kind.
+func deprecatedAPIFix(err error, obj *K8sYamlStruct, chartName string, locs []engine.SourceLocation) *support.Fix {
+	var deprecated deprecatedAPIError
+	if !errors.As(err, &deprecated) || deprecated.Replacement.Empty() || deprecated.Replacement.Kind != obj.Kind || len(locs) == 0 {
+		return nil
+	}
+	loc := locs[0]
+	if !strings.HasPrefix(loc.Template, chartName+"/") {
+		return nil
+	}
+	from := obj.APIVersion
+	to := deprecated.Replacement.GroupVersion().String()
+	return &support.Fix{
+		Description: fmt.Sprintf("replace apiVersion %s with %s", from, to),
+		Path:        strings.TrimPrefix(loc.Template, chartName+"/"),
+		Edit: func(data []byte, _ support.FixOptions) ([]byte, error) {
+			lines := bytes.SplitAfter(data, []byte("\n"))
+			if loc.Line < 1 || loc.Line > len(lines) {
+				return nil, errors.Errorf("line %d is out of range", loc.Line)
+			}
+			line := lines[loc.Line-1]
+			if !bytes.Contains(line, []byte(from)) {
+				return nil, errors.Errorf("line %d does not contain the apiVersion %s", loc.Line, from)
+			}
+			lines[loc.Line-1] = bytes.Replace(line, []byte(from), []byte(to), 1)
+			return bytes.Join(lines, nil), nil
+		},
+	}
+}
+
+// topLevelScalar returns the text of the scalar value of a key of the
+// top-level mapping of a YAML document, e.g. "1.10" for a version, which
+// decodes to the number 1.1.
+func topLevelScalar(data []byte, key string) string {
+	var doc yamlv3.Node
+	if yamlv3.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+		return ""
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key && root.Content[i+1].Kind == yamlv3.ScalarNode {
+			return root.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// setTopLevelScalar sets a key of the top-level mapping of a YAML document to
+// a string, in place, keeping the comments and the formatting of the other
+// lines. The key is appended to the document if it is missing.
+func setTopLevelScalar(data []byte, key, value string) ([]byte, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	encoded, err := yamlv3.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	encoded = bytes.TrimSuffix(encoded, []byte("\n"))
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yamlv3.MappingNode {
+		return nil, errors.New("the document is not a mapping")
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != key {
+			continue
+		}
+		v := root.Content[i+1]
+		lines := bytes.SplitAfter(data, []byte("\n"))
+		if v.Kind != yamlv3.ScalarNode || v.Style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle) != 0 || v.Line > len(lines) {
+			return nil, errors.Errorf("the value of %s is not a single-line scalar", key)
+		}
+		line := lines[v.Line-1]
+		start := v.Column - 1
+		end := scalarEnd(line, start, v.Style)
+		fixed := append(append(append([]byte{}, line[:start]...), encoded...), line[end:]...)
+		lines[v.Line-1] = fixed
+		return bytes.Join(lines, nil), nil
+	}
+
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	return append(append(data, fmt.Sprintf("%s: ", key)...), append(encoded, '\n')...), nil
+}
+
+// scalarEnd returns the offset of the end of the scalar starting at start in
+// a line.
+func scalarEnd(line []byte, start int, style yamlv3.Style) int {
+	rest := line[start:]
+	switch {
+	case style&yamlv3.DoubleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] == '\\' {
+				i++
+			} else if rest[i] == '"' {
+				return start + i + 1
+			}
+		}
+	case style&yamlv3.SingleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] == '\'' {
+				if i+1 < len(rest) && rest[i+1] == '\'' {
+					i++
+					continue
+				}
+				return start + i + 1
+			}
+		}
+	}
+	end := len(rest)
+	if i := bytes.Index(rest, []byte(" #")); i >= 0 {
+		end = i
+	}
+	return start + len(bytes.TrimRight(rest[:end], " \t\r\n"))
+}
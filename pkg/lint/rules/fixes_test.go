@@ -0,0 +1,59 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import "testing"
+
+func TestSetTopLevelScalar(t *testing.T) {
+	for _, tt := range []struct {
+		name, in, key, value, out string
+	}{
+		{"plain", "name: x\nversion: 1.2 # bumped\n", "version", "1.2.0", "name: x\nversion: 1.2.0 # bumped\n"},
+		{"double quoted", "version: \"v1\" # v\ndescription: a\n", "version", "1.0.0", "version: 1.0.0 # v\ndescription: a\n"},
+		{"single quoted", "version: 'it''s'\n", "version", "1.0.0", "version: 1.0.0\n"},
+		{"nested key", "dependencies:\n  - name: icon\n    icon: x\n", "icon", "https://example.com/icon.png", "dependencies:\n  - name: icon\n    icon: x\nicon: https://example.com/icon.png\n"},
+		{"missing newline", "name: x", "icon", "a: b", "name: x\nicon: 'a: b'\n"},
+	} {
+		out, err := setTopLevelScalar([]byte(tt.in), tt.key, tt.value)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if string(out) != tt.out {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.out, out)
+		}
+	}
+
+	if _, err := setTopLevelScalar([]byte("version: |\n  1.0\n"), "version", "1.0.0"); err == nil {
+		t.Error("expected an error for a block scalar")
+	}
+}
+
+func TestFixedVersion(t *testing.T) {
+	for version, fixed := range map[string]string{
+		"1.2":         "1.2.0",
+		"v1.2.3":      "1.2.3",
+		"1.10":        "1.10.0",
+		"1.2.3.4":     "1.2.3",
+		"2-final_rc1": "2.0.0",
+		"latest":      "",
+	} {
+		if got := fixedVersion(version); got != fixed {
+			t.Errorf("fixedVersion(%q): expected %q, got %q", version, fixed, got)
+		}
+	}
+}
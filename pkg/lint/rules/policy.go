@@ -0,0 +1,238 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/lint/support"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// PolicyDisableAnnotation disables the rules of the policy pack for a
+// resource. Its value lists the IDs of the disabled rules, separated by
+// commas, or is empty to disable all of them, e.g.
+//
+//	lint.helm.sh/disable: "host-network,run-as-non-root"
+const PolicyDisableAnnotation = "lint.helm.sh/disable"
+
+// workload is a resource with a pod spec.
+type workload struct {
+	metadata metav1.ObjectMeta
+	spec     corev1.PodSpec
+	// specPath is the path of the pod spec in the resource.
+	specPath string
+	// longRunning is set for the workloads whose pods are expected to run
+	// until they are replaced, e.g. Deployments.
+	longRunning bool
+}
+
+// decodeWorkload decodes the pod spec of a resource of a workload kind. It
+// returns nil for the resources of other kinds, and for those which do not
+// decode, which are reported by other rules.
+func decodeWorkload(kind string, data []byte) *workload {
+	switch kind {
+	case "Pod":
+		var o corev1.Pod
+		if json.Unmarshal(data, &o) == nil {
+			return &workload{metadata: o.ObjectMeta, spec: o.Spec, specPath: "spec"}
+		}
+	case "Deployment":
+		var o appsv1.Deployment
+		if json.Unmarshal(data, &o) == nil {
+			return &workload{metadata: o.ObjectMeta, spec: o.Spec.Template.Spec, specPath: "spec.template.spec", longRunning: true}
+		}
+	case "StatefulSet":
+		var o appsv1.StatefulSet
+		if json.Unmarshal(data, &o) == nil {
+			return &workload{metadata: o.ObjectMeta, spec: o.Spec.Template.Spec, specPath: "spec.template.spec", longRunning: true}
+		}
+	case "DaemonSet":
+		var o appsv1.DaemonSet
+		if json.Unmarshal(data, &o) == nil {
+			return &workload{metadata: o.ObjectMeta, spec: o.Spec.Template.Spec, specPath: "spec.template.spec", longRunning: true}
+		}
+	case "ReplicaSet":
+		var o appsv1.ReplicaSet
+		if json.Unmarshal(data, &o) == nil {
+			return &workload{metadata: o.ObjectMeta, spec: o.Spec.Template.Spec, specPath: "spec.template.spec", longRunning: true}
+		}
+	case "Job":
+		var o batchv1.Job
+		if json.Unmarshal(data, &o) == nil {
+			return &workload{metadata: o.ObjectMeta, spec: o.Spec.Template.Spec, specPath: "spec.template.spec"}
+		}
+	case "CronJob":
+		var o batchv1.CronJob
+		if json.Unmarshal(data, &o) == nil {
+			return &workload{metadata: o.ObjectMeta, spec: o.Spec.JobTemplate.Spec.Template.Spec, specPath: "spec.jobTemplate.spec.template.spec"}
+		}
+	}
+	return nil
+}
+
+// policyFinding is a violation of a rule of the policy pack by a field of a
+// workload.
+type policyFinding struct {
+	rule support.Rule
+	// field is the path of the field, e.g. "spec.template.spec.containers[0].image".
+	field string
+	err   error
+}
+
+// lintWorkloadPolicies checks the pod spec of a rendered resource against
+// the rules of the policy pack, unless they are disabled for the resource by
+// its PolicyDisableAnnotation annotation.
+func lintWorkloadPolicies(linter *support.Linter, fpath string, sourceMap *engine.SourceMap, renderedName string, obj *K8sYamlStruct, data []byte) {
+	w := decodeWorkload(obj.Kind, data)
+	if w == nil {
+		return
+	}
+	disabled, hasAnnotation := w.metadata.Annotations[PolicyDisableAnnotation]
+	ids := strings.FieldsFunc(disabled, func(r rune) bool { return r == ',' || r == ' ' })
+	for _, f := range w.policyFindings() {
+		if hasAnnotation && (len(ids) == 0 || contains(ids, f.rule.ID)) {
+			continue
+		}
+		err := errors.Wrapf(f.err, "%s/%s", obj.Kind, obj.Metadata.Name)
+		linter.RunRule(f.rule, fpath, withSourceLocation(err, sourceMap.LocateField(renderedName, obj.Kind, obj.Metadata.Name, f.field)...))
+	}
+}
+
+// policyFindings returns the violations of the rules of the policy pack by
+// the pod spec of the workload.
+func (w *workload) policyFindings() []policyFinding {
+	var findings []policyFinding
+	add := func(rule support.Rule, field string, format string, args ...interface{}) {
+		findings = append(findings, policyFinding{rule: rule, field: field, err: errors.Errorf(format, args...)})
+	}
+
+	spec := w.spec
+	if spec.HostNetwork {
+		add(hostNetwork, w.specPath+".hostNetwork", "the pod uses the host network")
+	}
+	for i, v := range spec.Volumes {
+		if v.HostPath != nil {
+			add(hostPathVolume, fmt.Sprintf("%s.volumes[%d].hostPath", w.specPath, i), "volume %q mounts the host path %s", v.Name, v.HostPath.Path)
+		}
+	}
+
+	podSecurity := spec.SecurityContext
+	if podSecurity == nil {
+		podSecurity = &corev1.PodSecurityContext{}
+	}
+	hook := w.metadata.Annotations[release.HookAnnotation] != ""
+	lists := []struct {
+		name       string
+		containers []corev1.Container
+	}{
+		{"initContainers", spec.InitContainers},
+		{"containers", spec.Containers},
+	}
+	for _, list := range lists {
+		for i, c := range list.containers {
+			field := fmt.Sprintf("%s.%s[%d]", w.specPath, list.name, i)
+			security := c.SecurityContext
+			if security == nil {
+				security = &corev1.SecurityContext{}
+			}
+
+			if security.Privileged != nil && *security.Privileged {
+				add(privilegedContainer, field+".securityContext.privileged", "container %q is privileged", c.Name)
+			}
+			if latestImage(c.Image) {
+				add(imageLatestTag, field+".image", "container %q uses the image %q, without a tag or with the latest tag", c.Name, c.Image)
+			}
+			if missing := missingResources(c.Resources); len(missing) > 0 {
+				add(containerResources, field+".resources", "container %q does not set %s", c.Name, strings.Join(missing, ", "))
+			}
+			if w.longRunning && !hook && list.name == "containers" {
+				var missing []string
+				if c.LivenessProbe == nil {
+					missing = append(missing, "liveness probe")
+				}
+				if c.ReadinessProbe == nil {
+					missing = append(missing, "readiness probe")
+				}
+				if len(missing) > 0 {
+					add(containerProbes, field, "container %q has no %s", c.Name, strings.Join(missing, " and "))
+				}
+			}
+
+			nonRoot, user := podSecurity.RunAsNonRoot, podSecurity.RunAsUser
+			if security.RunAsNonRoot != nil {
+				nonRoot = security.RunAsNonRoot
+			}
+			if security.RunAsUser != nil {
+				user = security.RunAsUser
+			}
+			if (user != nil && *user == 0) || (user == nil && (nonRoot == nil || !*nonRoot)) {
+				add(runAsNonRoot, field+".securityContext", "container %q may run as root, as it sets neither runAsNonRoot nor a non-root runAsUser", c.Name)
+			}
+			if security.ReadOnlyRootFilesystem == nil || !*security.ReadOnlyRootFilesystem {
+				add(readOnlyRootFilesystem, field+".securityContext.readOnlyRootFilesystem", "container %q has a writable root filesystem", c.Name)
+			}
+		}
+	}
+	return findings
+}
+
+// latestImage returns whether an image reference has no tag, or the latest
+// tag, and no digest.
+func latestImage(image string) bool {
+	if image == "" || strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	return i < 0 || name[i+1:] == "latest"
+}
+
+// missingResources returns the CPU and memory requests and limits a
+// container does not set.
+func missingResources(resources corev1.ResourceRequirements) []string {
+	var missing []string
+	for _, r := range []struct {
+		kind string
+		list corev1.ResourceList
+	}{{"requests", resources.Requests}, {"limits", resources.Limits}} {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if _, ok := r.list[name]; !ok {
+				missing = append(missing, fmt.Sprintf("resources.%s.%s", r.kind, name))
+			}
+		}
+	}
+	return missing
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
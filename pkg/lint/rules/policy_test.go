@@ -0,0 +1,189 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+const policyDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    spec:
+      hostNetwork: true
+      securityContext:
+        runAsNonRoot: true
+      containers:
+        - name: web
+          image: nginx
+          securityContext:
+            privileged: true
+          resources:
+            limits:
+              cpu: 1
+              memory: 1Gi
+          livenessProbe:
+            httpGet:
+              port: 80
+      volumes:
+        - name: logs
+          hostPath:
+            path: /var/log
+`
+
+const policyCronJob = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  schedule: "@daily"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: backup
+              image: registry.example.com:5000/backup@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+              securityContext:
+                runAsUser: 0
+                readOnlyRootFilesystem: true
+              resources:
+                requests:
+                  cpu: 100m
+                  memory: 64Mi
+                limits:
+                  cpu: 100m
+                  memory: 64Mi
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test
+  annotations:
+    helm.sh/hook: test
+    lint.helm.sh/disable: "container-resources, read-only-root-filesystem"
+spec:
+  containers:
+    - name: test
+      image: busybox:latest
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  annotations:
+    lint.helm.sh/disable: ""
+spec:
+  selector:
+    matchLabels:
+      app: agent
+  template:
+    spec:
+      hostNetwork: true
+      containers:
+        - name: agent
+          image: agent
+`
+
+func TestWorkloadPolicies(t *testing.T) {
+	mychart := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "policy",
+			Version:    "0.1.0",
+			Icon:       "satisfy-the-linting-gods.gif",
+		},
+		Templates: []*chart.File{
+			{Name: "templates/deployment.yaml", Data: []byte(policyDeployment)},
+			{Name: "templates/jobs.yaml", Data: []byte(policyCronJob)},
+		},
+	}
+	tmpdir := t.TempDir()
+	if err := chartutil.SaveDir(mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+	chartDir := filepath.Join(tmpdir, mychart.Name())
+
+	// The rules of the policy pack are opt-in.
+	linter := support.Linter{ChartDir: chartDir}
+	Templates(&linter, values, namespace, strict)
+	if len(linter.Messages) != 0 {
+		t.Fatalf("Expected no lint messages without the policy pack, got %v", linter.Messages)
+	}
+
+	linter = support.Linter{ChartDir: chartDir, Packs: []string{"policy"}}
+	Templates(&linter, values, namespace, strict)
+	want := []struct {
+		rule, err string
+	}{
+		{"host-network", "Deployment/web: the pod uses the host network (at policy/templates/deployment.yaml:11)"},
+		{"host-path-volume", "Deployment/web: volume \"logs\" mounts the host path /var/log (at policy/templates/deployment.yaml:28)"},
+		{"privileged-container", "Deployment/web: container \"web\" is privileged (at policy/templates/deployment.yaml:18)"},
+		{"image-latest-tag", "Deployment/web: container \"web\" uses the image \"nginx\", without a tag or with the latest tag (at policy/templates/deployment.yaml:16)"},
+		{"container-resources", "Deployment/web: container \"web\" does not set resources.requests.cpu, resources.requests.memory (at policy/templates/deployment.yaml:19)"},
+		{"container-probes", "Deployment/web: container \"web\" has no readiness probe (at policy/templates/deployment.yaml:15)"},
+		{"read-only-root-filesystem", "Deployment/web: container \"web\" has a writable root filesystem (at policy/templates/deployment.yaml:17)"},
+		{"run-as-non-root", "CronJob/backup: container \"backup\" may run as root, as it sets neither runAsNonRoot nor a non-root runAsUser (at policy/templates/jobs.yaml:14)"},
+		{"image-latest-tag", "Pod/test: container \"test\" uses the image \"busybox:latest\", without a tag or with the latest tag (at policy/templates/jobs.yaml:35)"},
+		{"run-as-non-root", "Pod/test: container \"test\" may run as root, as it sets neither runAsNonRoot nor a non-root runAsUser (at policy/templates/jobs.yaml:34)"},
+	}
+	if len(linter.Messages) != len(want) {
+		t.Fatalf("Expected %d lint messages, got %d: %v", len(want), len(linter.Messages), linter.Messages)
+	}
+	for i, msg := range linter.Messages {
+		if msg.RuleID != want[i].rule || msg.Err.Error() != want[i].err {
+			t.Errorf("Expected %s: %s, got %s: %s", want[i].rule, want[i].err, msg.RuleID, msg.Err)
+		}
+		if msg.Path != "templates/deployment.yaml" && msg.Path != "templates/jobs.yaml" {
+			t.Errorf("Unexpected path %s", msg.Path)
+		}
+	}
+
+	// A rule of the pack is enabled by configuring its severity.
+	linter = support.Linter{ChartDir: chartDir, Config: &support.Config{Rules: map[string]string{"host-network": "error"}}}
+	Templates(&linter, values, namespace, strict)
+	if len(linter.Messages) != 1 || linter.Messages[0].RuleID != "host-network" || linter.Messages[0].Severity != support.ErrorSev {
+		t.Errorf("Expected a host-network error, got %v", linter.Messages)
+	}
+}
+
+func TestLatestImage(t *testing.T) {
+	for image, latest := range map[string]bool{
+		"nginx":                        true,
+		"nginx:latest":                 true,
+		"nginx:1.25":                   false,
+		"localhost:5000/nginx":         true,
+		"localhost:5000/nginx:1.25":    false,
+		"nginx@sha256:0123456789abcde": false,
+		"":                             false,
+	} {
+		if got := latestImage(image); got != latest {
+			t.Errorf("latestImage(%q): expected %v, got %v", image, latest, got)
+		}
+	}
+}
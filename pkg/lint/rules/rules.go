@@ -0,0 +1,181 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules // import "helm.sh/helm/v3/pkg/lint/rules"
+
+import "helm.sh/helm/v3/pkg/lint/support"
+
+// policyPack is the pack of the opt-in rules checking workloads for security
+// and best practices.
+const policyPack = "policy"
+
+// valuesPack is the pack of the opt-in rules checking the values the
+// templates use against those the chart defines.
+const valuesPack = "values"
+
+// The built-in lint rules. Their IDs are stable, as they are used in lint
+// configuration files and suppression comments.
+var (
+	lintConfig = support.Rule{ID: "lint-config", Severity: support.WarningSev,
+		Description: "the lint configuration files are valid and only configure known rules"}
+
+	chartLoadable = support.Rule{ID: "chart-loadable", Severity: support.ErrorSev,
+		Description: "the chart can be loaded"}
+
+	chartfileNotDirectory = support.Rule{ID: "chartfile-not-directory", Severity: support.ErrorSev,
+		Description: "Chart.yaml is a file"}
+	chartfileFormat = support.Rule{ID: "chartfile-format", Severity: support.ErrorSev,
+		Description: "Chart.yaml is valid YAML"}
+	chartName = support.Rule{ID: "chart-name", Severity: support.ErrorSev,
+		Description: "the chart has a valid name"}
+	chartAPIVersion = support.Rule{ID: "chart-api-version", Severity: support.ErrorSev,
+		Description: "the chart has a supported apiVersion"}
+	chartVersionType = support.Rule{ID: "chart-version-type", Severity: support.ErrorSev,
+		Description: "the version of the chart is a string"}
+	chartVersion = support.Rule{ID: "chart-version", Severity: support.ErrorSev,
+		Description: "the version of the chart is a valid semantic version"}
+	chartAppVersionType = support.Rule{ID: "chart-app-version-type", Severity: support.ErrorSev,
+		Description: "the appVersion of the chart is a string"}
+	chartMaintainers = support.Rule{ID: "chart-maintainers", Severity: support.ErrorSev,
+		Description: "the maintainers of the chart have a name and valid emails and URLs"}
+	chartSources = support.Rule{ID: "chart-sources", Severity: support.ErrorSev,
+		Description: "the sources of the chart are valid URLs"}
+	chartIconPresent = support.Rule{ID: "chart-icon-present", Severity: support.InfoSev,
+		Description: "the chart has an icon"}
+	chartIconURL = support.Rule{ID: "chart-icon-url", Severity: support.ErrorSev,
+		Description: "the icon of the chart is a valid URL"}
+	chartType = support.Rule{ID: "chart-type", Severity: support.ErrorSev,
+		Description: "the type of the chart is valid for its apiVersion"}
+	chartDependencies = support.Rule{ID: "chart-dependencies", Severity: support.ErrorSev,
+		Description: "dependencies are only declared in Chart.yaml by v2 charts"}
+	chartRenderer = support.Rule{ID: "chart-renderer", Severity: support.ErrorSev,
+		Description: "the template renderer of the chart is known"}
+
+	valuesFilePresent = support.Rule{ID: "values-file-present", Severity: support.InfoSev,
+		Description: "the chart has a values.yaml file"}
+	valuesValid = support.Rule{ID: "values-valid", Severity: support.ErrorSev,
+		Description: "the values are valid YAML and match the values schema"}
+	valuesDefined = support.Rule{ID: "values-defined", Severity: support.WarningSev, Pack: valuesPack,
+		Description: "the values the templates use are defined in values.yaml or the values schema"}
+	valuesUsed = support.Rule{ID: "values-used", Severity: support.WarningSev, Pack: valuesPack,
+		Description: "the values of values.yaml are used by the templates or by the subcharts"}
+
+	templatesDirPresent = support.Rule{ID: "templates-dir-present", Severity: support.WarningSev,
+		Description: "the chart has a templates directory"}
+	templatesRender = support.Rule{ID: "templates-render", Severity: support.ErrorSev,
+		Description: "the templates render without errors"}
+	templateExtension = support.Rule{ID: "template-extension", Severity: support.ErrorSev,
+		Description: "templates have a supported file extension"}
+	noCRDHooks = support.Rule{ID: "no-crd-hooks", Severity: support.WarningSev,
+		Description: "templates do not use the crd-install hook, which Helm 3 ignores"}
+	noReleaseTime = support.Rule{ID: "no-release-time", Severity: support.ErrorSev,
+		Description: "templates do not use .Release.Time, which Helm 3 removed"}
+	topLevelIndent = support.Rule{ID: "top-level-indent", Severity: support.WarningSev,
+		Description: "rendered templates do not start with an indentation"}
+	yamlValid = support.Rule{ID: "yaml-valid", Severity: support.ErrorSev,
+		Description: "rendered templates are valid YAML"}
+	metadataName = support.Rule{ID: "metadata-name", Severity: support.WarningSev,
+		Description: "the names of the resources are valid"}
+	deprecatedAPI = support.Rule{ID: "deprecated-api", Severity: support.WarningSev,
+		Description: "resources do not use APIs deprecated in the target Kubernetes version"}
+	matchSelector = support.Rule{ID: "match-selector", Severity: support.ErrorSev,
+		Description: "workloads have a selector with matchLabels or matchExpressions"}
+	listAnnotations = support.Rule{ID: "list-annotations", Severity: support.ErrorSev,
+		Description: "the items of List resources have no helm.sh/resource-policy annotation, which is ignored"}
+	schemaValid = support.Rule{ID: "schema-valid", Severity: support.ErrorSev,
+		Description: "rendered resources match the schemas of their kinds, with --validate-schema"}
+	templateNameCollision = support.Rule{ID: "template-name-collision", Severity: support.WarningSev,
+		Description: "named templates are not defined more than once with different contents"}
+	templateNamespace = support.Rule{ID: "template-namespace", Severity: support.WarningSev,
+		Description: "the template namespaces of library charts are only used by them"}
+
+	// The opt-in rules of the policy pack, checking the pod specs of
+	// workloads for security and best practices.
+	privilegedContainer = support.Rule{ID: "privileged-container", Severity: support.WarningSev, Pack: policyPack,
+		Description: "containers are not privileged"}
+	hostPathVolume = support.Rule{ID: "host-path-volume", Severity: support.WarningSev, Pack: policyPack,
+		Description: "pods do not mount hostPath volumes"}
+	hostNetwork = support.Rule{ID: "host-network", Severity: support.WarningSev, Pack: policyPack,
+		Description: "pods do not use the host network"}
+	containerResources = support.Rule{ID: "container-resources", Severity: support.WarningSev, Pack: policyPack,
+		Description: "containers set CPU and memory requests and limits"}
+	imageLatestTag = support.Rule{ID: "image-latest-tag", Severity: support.WarningSev, Pack: policyPack,
+		Description: "container images have a tag other than latest, or a digest"}
+	containerProbes = support.Rule{ID: "container-probes", Severity: support.WarningSev, Pack: policyPack,
+		Description: "the containers of long-running workloads have liveness and readiness probes"}
+	runAsNonRoot = support.Rule{ID: "run-as-non-root", Severity: support.WarningSev, Pack: policyPack,
+		Description: "containers do not run as root"}
+	readOnlyRootFilesystem = support.Rule{ID: "read-only-root-filesystem", Severity: support.WarningSev, Pack: policyPack,
+		Description: "containers have a read-only root filesystem"}
+
+	dependenciesDeclared = support.Rule{ID: "dependencies-declared", Severity: support.ErrorSev,
+		Description: "the charts in the charts directory are declared as dependencies"}
+	dependenciesUnique = support.Rule{ID: "dependencies-unique", Severity: support.ErrorSev,
+		Description: "dependencies have unique names or aliases"}
+	dependenciesPresent = support.Rule{ID: "dependencies-present", Severity: support.WarningSev,
+		Description: "the dependencies are in the charts directory"}
+)
+
+// Builtin returns the built-in lint rules.
+func Builtin() []support.Rule {
+	return []support.Rule{
+		lintConfig,
+		chartLoadable,
+		chartfileNotDirectory,
+		chartfileFormat,
+		chartName,
+		chartAPIVersion,
+		chartVersionType,
+		chartVersion,
+		chartAppVersionType,
+		chartMaintainers,
+		chartSources,
+		chartIconPresent,
+		chartIconURL,
+		chartType,
+		chartDependencies,
+		chartRenderer,
+		valuesFilePresent,
+		valuesValid,
+		valuesDefined,
+		valuesUsed,
+		templatesDirPresent,
+		templatesRender,
+		templateExtension,
+		noCRDHooks,
+		noReleaseTime,
+		topLevelIndent,
+		yamlValid,
+		metadataName,
+		deprecatedAPI,
+		matchSelector,
+		listAnnotations,
+		schemaValid,
+		templateNameCollision,
+		templateNamespace,
+		privilegedContainer,
+		hostPathVolume,
+		hostNetwork,
+		containerResources,
+		imageLatestTag,
+		containerProbes,
+		runAsNonRoot,
+		readOnlyRootFilesystem,
+		dependenciesDeclared,
+		dependenciesUnique,
+		dependenciesPresent,
+	}
+}
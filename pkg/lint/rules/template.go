@@ -19,6 +19,8 @@ package rules
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -26,6 +28,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/api/validation"
@@ -33,9 +36,11 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apimachinery/pkg/util/yaml"
 
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/kubeschema"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -51,10 +56,39 @@ func Templates(linter *support.Linter, values map[string]interface{}, namespace
 
 // TemplatesWithKubeVersion lints the templates in the Linter, allowing to specify the kubernetes version.
 func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) {
+	TemplatesWithOptions(linter, values, namespace, TemplateLintOptions{KubeVersion: kubeVersion})
+}
+
+// TemplateLintOptions holds the optional settings of TemplatesWithOptions.
+type TemplateLintOptions struct {
+	// KubeVersion is the Kubernetes version used for capabilities and deprecation checks.
+	KubeVersion *chartutil.KubeVersion
+	// Capabilities replaces the default capabilities. KubeVersion still takes
+	// precedence over its Kubernetes version.
+	Capabilities *chartutil.Capabilities
+	// LookupClientProvider, when set, backs the 'lookup' template function.
+	LookupClientProvider engine.ClientProvider
+	// Funcs, when set, provides additional template functions.
+	Funcs *engine.FuncRegistry
+	// Renderers provides additional renderers for the templates of the
+	// charts selecting them in their Chart.yaml.
+	Renderers map[string]engine.Renderer
+	// RenderLimits bounds the output of the templates.
+	RenderLimits engine.RenderLimits
+	// RenderTimeout, when set, is the maximum time spent rendering the templates.
+	RenderTimeout time.Duration
+	// SchemaValidator, when set, validates the rendered resources against
+	// its schemas and those of the CustomResourceDefinitions of the chart.
+	SchemaValidator *kubeschema.Validator
+}
+
+// TemplatesWithOptions lints the templates in the Linter using the given options.
+func TemplatesWithOptions(linter *support.Linter, values map[string]interface{}, namespace string, opts TemplateLintOptions) {
+	kubeVersion := opts.KubeVersion
 	fpath := "templates/"
 	templatesPath := filepath.Join(linter.ChartDir, fpath)
 
-	templatesDirExist := linter.RunLinterRule(support.WarningSev, fpath, validateTemplatesDir(templatesPath))
+	templatesDirExist := linter.RunRule(templatesDirPresent, fpath, validateTemplatesDir(templatesPath))
 
 	// Templates directory is optional for now
 	if !templatesDirExist {
@@ -64,7 +98,7 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 	// Load chart and parse templates
 	chart, err := loader.Load(linter.ChartDir)
 
-	chartLoaded := linter.RunLinterRule(support.ErrorSev, fpath, err)
+	chartLoaded := linter.RunRule(chartLoadable, fpath, err)
 
 	if !chartLoaded {
 		return
@@ -76,6 +110,12 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 	}
 
 	caps := chartutil.DefaultCapabilities.Copy()
+	if opts.Capabilities != nil {
+		caps = opts.Capabilities.Copy()
+		if kubeVersion == nil {
+			kubeVersion = &caps.KubeVersion
+		}
+	}
 	if kubeVersion != nil {
 		caps.KubeVersion = *kubeVersion
 	}
@@ -93,19 +133,48 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 
 	valuesToRender, err := chartutil.ToRenderValues(chart, cvals, options, caps)
 	if err != nil {
-		linter.RunLinterRule(support.ErrorSev, fpath, err)
+		linter.RunRule(templatesRender, fpath, err)
 		return
 	}
 	var e engine.Engine
+	if opts.LookupClientProvider != nil {
+		e = engine.NewWithClientProvider(opts.LookupClientProvider)
+	}
 	e.LintMode = true
-	renderedContentMap, err := e.Render(chart, valuesToRender)
+	e.Funcs = opts.Funcs
+	e.Renderers = opts.Renderers
+	e.Limits = opts.RenderLimits
+	sourceMap := engine.NewSourceMap()
+	e.SourceMap = sourceMap
+	definitions := engine.NewTemplateDefinitions()
+	e.Definitions = definitions
+	ctx := context.Background()
+	if opts.RenderTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.RenderTimeout)
+		defer cancel()
+	}
+	if !linter.RunRule(chartRenderer, "Chart.yaml", validateChartRenderer(e, chart.Metadata)) {
+		return
+	}
+	renderedContentMap, err := e.RenderContext(ctx, chart, valuesToRender)
 
-	renderOk := linter.RunLinterRule(support.ErrorSev, fpath, err)
+	renderOk := linter.RunRule(templatesRender, fpath, err)
 
 	if !renderOk {
 		return
 	}
 
+	lintTemplateDefinitions(linter, chart, definitions)
+	valueRefs := analyzeValueReferences(chart)
+	lintValuesDefined(linter, chart, valueRefs, cvals)
+
+	var validator *kubeschema.Validator
+	if opts.SchemaValidator != nil {
+		validator = opts.SchemaValidator.Copy()
+		linter.RunRule(schemaValid, "crds/", validator.AddChartCRDs(chart))
+	}
+
 	/* Iterate over all the templates to check:
 	- It is a .yaml file
 	- All the values in the template file is defined
@@ -117,27 +186,25 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 		fileName, data := template.Name, template.Data
 		fpath = fileName
 
-		linter.RunLinterRule(support.ErrorSev, fpath, validateAllowedExtension(fileName))
+		linter.RunRule(templateExtension, fpath, support.WithFix(validateAllowedExtension(fileName, chart.Metadata.Renderer), extensionFix(fileName)))
 		// These are v3 specific checks to make sure and warn people if their
 		// chart is not compatible with v3
-		linter.RunLinterRule(support.WarningSev, fpath, validateNoCRDHooks(data))
-		linter.RunLinterRule(support.ErrorSev, fpath, validateNoReleaseTime(data))
+		linter.RunRule(noCRDHooks, fpath, validateNoCRDHooks(data))
+		linter.RunRule(noReleaseTime, fpath, validateNoReleaseTime(data))
 
-		// We only apply the following lint rules to yaml files
-		if filepath.Ext(fileName) != ".yaml" || filepath.Ext(fileName) == ".yml" {
+		// We only apply the following lint rules to yaml files, and to templates
+		// of other renderers, which output yaml as well
+		if filepath.Ext(fileName) != ".yaml" && !(filepath.Ext(fileName) == ".star" && chart.Metadata.Renderer == engine.StarlarkRenderer) {
 			continue
 		}
 
-		// NOTE: disabled for now, Refs https://github.com/helm/helm/issues/1463
-		// Check that all the templates have a matching value
-		// linter.RunLinterRule(support.WarningSev, fpath, validateNoMissingValues(templatesPath, valuesToRender, preExecutedTemplate))
-
 		// NOTE: disabled for now, Refs https://github.com/helm/helm/issues/1037
 		// linter.RunLinterRule(support.WarningSev, fpath, validateQuotes(string(preExecutedTemplate)))
 
-		renderedContent := renderedContentMap[path.Join(chart.Name(), fileName)]
+		renderedName := path.Join(chart.Name(), fileName)
+		renderedContent := renderedContentMap[renderedName]
 		if strings.TrimSpace(renderedContent) != "" {
-			linter.RunLinterRule(support.WarningSev, fpath, validateTopIndentLevel(renderedContent))
+			linter.RunRule(topLevelIndent, fpath, validateTopIndentLevel(renderedContent))
 
 			decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(renderedContent), 4096)
 
@@ -147,24 +214,114 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 				// key will be raised as well
 				var yamlStruct *K8sYamlStruct
 
-				err := decoder.Decode(&yamlStruct)
+				var raw json.RawMessage
+				err := decoder.Decode(&raw)
 				if err == io.EOF {
 					break
 				}
+				if err == nil && len(raw) > 0 {
+					err = json.Unmarshal(raw, &yamlStruct)
+				}
 
 				//  If YAML linting fails here, it will always fail in the next block as well, so we should return here.
 				// fix https://github.com/helm/helm/issues/11391
-				if !linter.RunLinterRule(support.ErrorSev, fpath, validateYamlContent(err)) {
+				if err != nil {
+					if loc, ok := sourceMap.LocateSyntaxError(renderedName); ok {
+						err = withSourceLocation(err, loc)
+					}
+				}
+				if !linter.RunRule(yamlValid, fpath, validateYamlContent(err)) {
 					return
 				}
 				if yamlStruct != nil {
 					// NOTE: set to warnings to allow users to support out-of-date kubernetes
 					// Refs https://github.com/helm/helm/issues/8596
-					linter.RunLinterRule(support.WarningSev, fpath, validateMetadataName(yamlStruct))
-					linter.RunLinterRule(support.WarningSev, fpath, validateNoDeprecations(yamlStruct, kubeVersion))
+					linter.RunRule(metadataName, fpath, validateMetadataName(yamlStruct))
+					deprecated := validateNoDeprecations(yamlStruct, kubeVersion)
+					apiVersion := sourceMap.LocateField(renderedName, yamlStruct.Kind, yamlStruct.Metadata.Name, "apiVersion")
+					linter.RunRule(deprecatedAPI, fpath, support.WithFix(deprecated, deprecatedAPIFix(deprecated, yamlStruct, chart.Name(), apiVersion)))
+
+					selector := sourceMap.LocateField(renderedName, yamlStruct.Kind, yamlStruct.Metadata.Name, "spec.selector")
+					linter.RunRule(matchSelector, fpath, withSourceLocation(validateMatchSelector(yamlStruct, renderedContent), selector...))
+					linter.RunRule(listAnnotations, fpath, validateListAnnotations(yamlStruct, renderedContent))
+					lintWorkloadPolicies(linter, fpath, sourceMap, renderedName, yamlStruct, raw)
+				}
+			}
 
-					linter.RunLinterRule(support.ErrorSev, fpath, validateMatchSelector(yamlStruct, renderedContent))
-					linter.RunLinterRule(support.ErrorSev, fpath, validateListAnnotations(yamlStruct, renderedContent))
+			if validator != nil {
+				for _, err := range validator.ValidateManifest(renderedContent) {
+					linter.RunRule(schemaValid, fpath, err)
+				}
+			}
+		}
+	}
+
+	// The templates all render valid YAML at this point. Those which do not
+	// are likely not finished, and the values they would use are not
+	// reported as unused.
+	lintValuesUsed(linter, chart, valueRefs)
+}
+
+// lintTemplateDefinitions checks that named templates are not defined more than
+// once with different contents, and that the namespaces library charts export
+// their templates in are only used by them.
+func lintTemplateDefinitions(linter *support.Linter, c *chart.Chart, definitions *engine.TemplateDefinitions) {
+	// relPath returns the path of a template relative to the linted chart.
+	relPath := func(loc engine.SourceLocation) string {
+		return strings.TrimPrefix(loc.Template, c.Name()+"/")
+	}
+
+	for _, collision := range definitions.Collisions() {
+		last := collision.Locations[len(collision.Locations)-1]
+		linter.RunRule(templateNameCollision, relPath(last), errors.New(collision.String()))
+	}
+
+	// Map the templates directory of each chart to the chart, and the
+	// exported namespaces to the library charts exporting them.
+	owners := map[string]*chart.Chart{}
+	namespaces := map[string]string{}
+	var walk func(*chart.Chart)
+	walk = func(ch *chart.Chart) {
+		owners[path.Join(ch.ChartFullPath(), "templates")+"/"] = ch
+		if ns := ch.Metadata.TemplateNamespace; ns != "" && ch.Metadata.Type == "library" {
+			namespaces[ns] = ch.Name()
+		}
+		for _, dep := range ch.Dependencies() {
+			walk(dep)
+		}
+	}
+	walk(c)
+	if len(namespaces) == 0 {
+		return
+	}
+	ownerOf := func(loc engine.SourceLocation) *chart.Chart {
+		var owner *chart.Chart
+		longest := 0
+		for dir, ch := range owners {
+			if strings.HasPrefix(loc.Template, dir) && len(dir) > longest {
+				owner, longest = ch, len(dir)
+			}
+		}
+		return owner
+	}
+	inNamespace := func(name, ns string) bool {
+		return strings.HasPrefix(name, ns+".")
+	}
+
+	for _, name := range definitions.Names() {
+		for _, loc := range definitions.Locations(name) {
+			owner := ownerOf(loc)
+			if owner == nil {
+				continue
+			}
+			if ns := owner.Metadata.TemplateNamespace; ns != "" && !inNamespace(name, ns) {
+				linter.RunRule(templateNamespace, relPath(loc),
+					errors.Errorf("template %q is defined outside of the namespace %q exported by library chart %q (at %s)", name, ns, owner.Name(), loc))
+			}
+			for ns, library := range namespaces {
+				if inNamespace(name, ns) && owner.Name() != library {
+					linter.RunRule(templateNamespace, relPath(loc),
+						errors.Errorf("template %q is defined in the namespace %q exported by library chart %q (at %s)", name, ns, library, loc))
 				}
 			}
 		}
@@ -178,6 +335,15 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 // into the YAML parser. So we trap it here.
 //
 // See https://github.com/helm/helm/issues/8467
+// validateChartRenderer checks that the engine has the renderer the chart
+// selects.
+func validateChartRenderer(e engine.Engine, cf *chart.Metadata) error {
+	if !e.HasRenderer(cf.Renderer) {
+		return errors.Errorf("template renderer %q is unknown", cf.Renderer)
+	}
+	return nil
+}
+
 func validateTopIndentLevel(content string) error {
 	// Read lines until we get to a non-empty one
 	scanner := bufio.NewScanner(bytes.NewBufferString(content))
@@ -197,6 +363,16 @@ func validateTopIndentLevel(content string) error {
 	return scanner.Err()
 }
 
+// withSourceLocation appends the first template location an error was traced
+// back to, so that errors in manifests produced by 'include' point at the
+// named template they come from.
+func withSourceLocation(err error, locs ...engine.SourceLocation) error {
+	if err == nil || len(locs) == 0 {
+		return err
+	}
+	return errors.Errorf("%s (at %s)", err, locs[0])
+}
+
 // Validation functions
 func validateTemplatesDir(templatesPath string) error {
 	if fi, err := os.Stat(templatesPath); err == nil {
@@ -207,7 +383,9 @@ func validateTemplatesDir(templatesPath string) error {
 	return nil
 }
 
-func validateAllowedExtension(fileName string) error {
+// validateAllowedExtension checks the extension of a template, allowing
+// ".star" for the charts rendered with Starlark.
+func validateAllowedExtension(fileName, renderer string) error {
 	ext := filepath.Ext(fileName)
 	validExtensions := []string{".yaml", ".yml", ".tpl", ".txt"}
 
@@ -216,6 +394,12 @@ func validateAllowedExtension(fileName string) error {
 			return nil
 		}
 	}
+	if renderer == engine.StarlarkRenderer {
+		if ext == ".star" {
+			return nil
+		}
+		return errors.Errorf("file extension '%s' not valid. Valid extensions are .yaml, .yml, .tpl, .txt, or .star", ext)
+	}
 
 	return errors.Errorf("file extension '%s' not valid. Valid extensions are .yaml, .yml, .tpl, or .txt", ext)
 }
@@ -25,26 +25,43 @@ import (
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
 const templateTestBasedir = "./testdata/albatross"
 
+func TestValidateChartRenderer(t *testing.T) {
+	e := engine.Engine{Renderers: map[string]engine.Renderer{"jsonnet": nil}}
+	for renderer, valid := range map[string]bool{"": true, "gotpl": true, "starlark": true, "jsonnet": true, "cue": false} {
+		err := validateChartRenderer(e, &chart.Metadata{Renderer: renderer})
+		if valid && err != nil {
+			t.Errorf("expected renderer %q to be valid, got %s", renderer, err)
+		}
+		if !valid && (err == nil || !strings.Contains(err.Error(), "is unknown")) {
+			t.Errorf("expected renderer %q to be unknown, got %v", renderer, err)
+		}
+	}
+}
+
 func TestValidateAllowedExtension(t *testing.T) {
-	var failTest = []string{"/foo", "/test.toml"}
+	var failTest = []string{"/foo", "/test.toml", "foo.star"}
 	for _, test := range failTest {
-		err := validateAllowedExtension(test)
+		err := validateAllowedExtension(test, "")
 		if err == nil || !strings.Contains(err.Error(), "Valid extensions are .yaml, .yml, .tpl, or .txt") {
 			t.Errorf("validateAllowedExtension('%s') to return \"Valid extensions are .yaml, .yml, .tpl, or .txt\", got no error", test)
 		}
 	}
 	var successTest = []string{"/foo.yaml", "foo.yaml", "foo.tpl", "/foo/bar/baz.yaml", "NOTES.txt"}
 	for _, test := range successTest {
-		err := validateAllowedExtension(test)
+		err := validateAllowedExtension(test, "")
 		if err != nil {
 			t.Errorf("validateAllowedExtension('%s') to return no error but got \"%s\"", test, err.Error())
 		}
 	}
+	if err := validateAllowedExtension("foo.star", "starlark"); err != nil {
+		t.Errorf("validateAllowedExtension('foo.star') to return no error for a Starlark chart but got \"%s\"", err.Error())
+	}
 }
 
 var values = map[string]interface{}{"nameOverride": "", "httpPort": 80}
@@ -241,6 +258,105 @@ func TestDeprecatedAPIFails(t *testing.T) {
 	}
 }
 
+func TestTemplateErrorSourceLocation(t *testing.T) {
+	mychart := chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "sourcemap",
+			Version:    "0.1.0",
+			Icon:       "satisfy-the-linting-gods.gif",
+		},
+		Templates: []*chart.File{
+			{
+				Name: "templates/_helpers.tpl",
+				Data: []byte("{{- define \"labels\" }}\napp: web\n  tier: [broken\n{{- end }}"),
+			},
+			{
+				Name: "templates/configmap.yaml",
+				Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n  labels:\n    {{- include \"labels\" . | nindent 4 }}"),
+			},
+		},
+	}
+	tmpdir := t.TempDir()
+
+	if err := chartutil.SaveDir(&mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(tmpdir, mychart.Name())}
+	Templates(&linter, values, namespace, strict)
+	if l := len(linter.Messages); l != 1 {
+		t.Fatalf("Expected 1 lint error, got %d: %v", l, linter.Messages)
+	}
+
+	want := "(at sourcemap/templates/_helpers.tpl:3 (included from sourcemap/templates/configmap.yaml:6))"
+	if err := linter.Messages[0].Err.Error(); !strings.Contains(err, want) {
+		t.Errorf("Expected error to contain %q, got %q", want, err)
+	}
+}
+
+func TestTemplateDefinitions(t *testing.T) {
+	library := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion:        "v2",
+			Name:              "common",
+			Version:           "0.1.0",
+			Type:              "library",
+			TemplateNamespace: "common",
+		},
+		Templates: []*chart.File{
+			{
+				Name: "templates/_labels.tpl",
+				Data: []byte("{{- define \"common.labels\" }}app: {{ .Chart.Name }}{{ end }}\n{{- define \"fullname\" }}{{ .Release.Name }}{{ end }}"),
+			},
+		},
+	}
+	mychart := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "definitions",
+			Version:    "0.1.0",
+			Icon:       "satisfy-the-linting-gods.gif",
+		},
+		Templates: []*chart.File{
+			{
+				Name: "templates/_helpers.tpl",
+				Data: []byte("{{- define \"common.labels\" }}app: web{{ end }}"),
+			},
+			{
+				Name: "templates/configmap.yaml",
+				Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n  labels:\n    {{- include \"common.labels\" . | nindent 4 }}"),
+			},
+		},
+	}
+	mychart.AddDependency(library)
+	tmpdir := t.TempDir()
+
+	if err := chartutil.SaveDir(mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(tmpdir, mychart.Name())}
+	Templates(&linter, values, namespace, strict)
+
+	want := []string{
+		`named template "common.labels" is defined in definitions/charts/common/templates/_labels.tpl:1 and definitions/templates/_helpers.tpl:1 with different contents, the latter is used`,
+		`template "common.labels" is defined in the namespace "common" exported by library chart "common" (at definitions/templates/_helpers.tpl:1)`,
+		`template "fullname" is defined outside of the namespace "common" exported by library chart "common" (at definitions/charts/common/templates/_labels.tpl:2)`,
+	}
+	if len(linter.Messages) != len(want) {
+		t.Fatalf("Expected %d lint warnings, got %d: %v", len(want), len(linter.Messages), linter.Messages)
+	}
+	for i, msg := range linter.Messages {
+		if msg.Severity != support.WarningSev {
+			t.Errorf("Expected a warning, got %v", msg)
+		}
+		if msg.Err.Error() != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], msg.Err.Error())
+		}
+	}
+}
+
 const manifest = `apiVersion: v1
 kind: ConfigMap
 metadata:
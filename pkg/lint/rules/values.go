@@ -42,13 +42,13 @@ func Values(linter *support.Linter) {
 func ValuesWithOverrides(linter *support.Linter, values map[string]interface{}) {
 	file := "values.yaml"
 	vf := filepath.Join(linter.ChartDir, file)
-	fileExists := linter.RunLinterRule(support.InfoSev, file, validateValuesFileExistence(vf))
+	fileExists := linter.RunRule(valuesFilePresent, file, validateValuesFileExistence(vf))
 
 	if !fileExists {
 		return
 	}
 
-	linter.RunLinterRule(support.ErrorSev, file, validateValuesFile(vf, values))
+	linter.RunRule(valuesValid, file, validateValuesFile(vf, values))
 }
 
 func validateValuesFileExistence(valuesPath string) error {
@@ -0,0 +1,651 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+	yamlv3 "sigs.k8s.io/yaml/goyaml.v3"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// lintValuesDefined checks that the values the templates of a chart refer to
+// are defined by its values or its values schema.
+//
+// The references are found statically, in the parse trees of the templates:
+// fields of .Values and of $.Values, of variables holding values, and of the
+// context passed to named templates by 'include' and 'template'. Values used
+// in ways that cannot be followed, e.g. passed to a function or printed as a
+// whole, are used with all their fields.
+func lintValuesDefined(linter *support.Linter, c *chart.Chart, a *valueAnalysis, values map[string]interface{}) {
+	var schema map[string]interface{}
+	if len(c.Schema) > 0 {
+		// An invalid schema is reported by the values rules.
+		_ = json.Unmarshal(c.Schema, &schema)
+	}
+	reported := map[string]bool{}
+	for _, ref := range a.refs {
+		if ref.chart != c || ref.optional || ref.defined(values, schema) {
+			continue
+		}
+		fpath := strings.TrimPrefix(ref.location.Template, c.Name()+"/")
+		err := errors.Errorf("%s is not defined in values.yaml or the values schema (at %s)", ref, ref.location)
+		if key := fpath + "\x00" + err.Error(); !reported[key] {
+			reported[key] = true
+			linter.RunRule(valuesDefined, fpath, err)
+		}
+	}
+}
+
+// lintValuesUsed checks that the values of the values.yaml file of a chart
+// are used by its templates or by its subcharts. It is not checked when a
+// template does not parse, as its references are unknown.
+func lintValuesUsed(linter *support.Linter, c *chart.Chart, a *valueAnalysis) {
+	if a.unparsed {
+		return
+	}
+	file := "values.yaml"
+	data, err := os.ReadFile(filepath.Join(linter.ChartDir, file))
+	if err != nil {
+		return
+	}
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return
+	}
+	for _, unused := range a.unusedValues(c, doc.Content[0], nil) {
+		linter.RunRule(valuesUsed, file, errors.Errorf("value %q is not used by the templates (at %s:%d)", strings.Join(unused.path, "."), file, unused.line))
+	}
+}
+
+// valueRef is a reference of a template to the values of a chart, e.g.
+// .Values.image.tag.
+type valueRef struct {
+	chart *chart.Chart
+	path  []string
+	// whole is set when the value is used as a whole, e.g. printed, rather
+	// than only tested or used through its fields.
+	whole bool
+	// optional is set when the template handles the value being undefined,
+	// e.g. tests it or gives it a default.
+	optional bool
+	location engine.SourceLocation
+}
+
+func (r valueRef) String() string {
+	return ".Values." + strings.Join(r.path, ".")
+}
+
+// defined returns whether the referenced value is defined by the values or
+// by the schema of its chart. Global values, fields of values which are not
+// maps, and fields of empty maps are considered defined.
+func (r valueRef) defined(values, schema map[string]interface{}) bool {
+	if len(r.path) == 0 || r.path[0] == "global" {
+		return true
+	}
+	return valuesDefine(values, r.path) || (schema != nil && schemaDefines(schema, r.path))
+}
+
+func valuesDefine(values interface{}, path []string) bool {
+	for _, key := range path {
+		m, ok := asMap(values)
+		if !ok || len(m) == 0 {
+			return true
+		}
+		if values, ok = m[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaDefines returns whether a JSON schema describes a field. Fields of
+// schemas without properties are considered described.
+func schemaDefines(schema map[string]interface{}, path []string) bool {
+	for _, key := range path {
+		properties, _ := schema["properties"].(map[string]interface{})
+		if sub, ok := properties[key].(map[string]interface{}); ok {
+			schema = sub
+			continue
+		}
+		if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			schema = additional
+			continue
+		}
+		_, hasProperties := schema["properties"]
+		_, hasPatterns := schema["patternProperties"]
+		return !hasProperties && !hasPatterns && schema["additionalProperties"] != false
+	}
+	return true
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v, true
+	case chartutil.Values:
+		return v, true
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = val
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// valueKind is the kind of the data a template expression evaluates to, as
+// far as it is known statically.
+type valueKind int
+
+const (
+	unknownData valueKind = iota
+	// rootData is the top-level data of the templates of a chart, with its
+	// .Values, .Release, .Chart, ...
+	rootData
+	// valuesData is a value of the values of a chart.
+	valuesData
+)
+
+// templateData is the data a template expression evaluates to.
+type templateData struct {
+	kind  valueKind
+	chart *chart.Chart
+	path  []string
+	// node is the expression, for the location of references.
+	node parse.Node
+}
+
+// field returns the data of a field.
+func (d templateData) field(name string, node parse.Node) templateData {
+	switch d.kind {
+	case rootData:
+		if name == "Values" {
+			return templateData{kind: valuesData, chart: d.chart, node: node}
+		}
+	case valuesData:
+		p := make([]string, len(d.path), len(d.path)+1)
+		copy(p, d.path)
+		return templateData{kind: valuesData, chart: d.chart, path: append(p, name), node: node}
+	}
+	return templateData{}
+}
+
+// key identifies the data passed to a named template.
+func (d templateData) key() string {
+	if d.kind == unknownData {
+		return ""
+	}
+	return fmt.Sprintf("%d:%s:%s", d.kind, d.chart.ChartFullPath(), strings.Join(d.path, "."))
+}
+
+// valueAnalysis finds the references of templates to values.
+type valueAnalysis struct {
+	// templates are the files and the named templates, by name.
+	templates map[string]*parse.Tree
+	// walked are the named templates already walked, with the key of their
+	// data.
+	walked map[string]bool
+	refs   []valueRef
+	// charts are the charts whose templates are analyzed.
+	charts map[*chart.Chart]bool
+	// unparsed is set when a template of the charts does not parse.
+	unparsed bool
+}
+
+// analyzeValueReferences finds the references of the templates of a chart
+// and of its subcharts to their values.
+func analyzeValueReferences(c *chart.Chart) *valueAnalysis {
+	a := &valueAnalysis{
+		templates: map[string]*parse.Tree{},
+		walked:    map[string]bool{},
+		charts:    map[*chart.Chart]bool{},
+	}
+
+	// Parse the templates in the order of the engine, deepest first, for the
+	// named templates defined more than once to be those in use.
+	files := map[string]*chart.Chart{}
+	var names []string
+	var collect func(*chart.Chart)
+	collect = func(ch *chart.Chart) {
+		if ch.Metadata.Renderer != "" && ch.Metadata.Renderer != engine.GoTemplateRenderer {
+			return
+		}
+		a.charts[ch] = true
+		for _, t := range ch.Templates {
+			if t == nil || path.Ext(t.Name) == ".star" {
+				continue
+			}
+			name := path.Join(ch.ChartFullPath(), t.Name)
+			files[name] = ch
+			names = append(names, name)
+		}
+		for _, dep := range ch.Dependencies() {
+			collect(dep)
+		}
+	}
+	collect(c)
+	sort.Slice(names, func(i, j int) bool {
+		ci, cj := strings.Count(names[i], "/"), strings.Count(names[j], "/")
+		if ci == cj {
+			return names[i] > names[j]
+		}
+		return ci > cj
+	})
+	var roots []*parse.Tree
+	for _, name := range names {
+		var data []byte
+		for _, t := range files[name].Templates {
+			if t != nil && path.Join(files[name].ChartFullPath(), t.Name) == name {
+				data = t.Data
+			}
+		}
+		tree := parse.New(name)
+		tree.Mode = parse.SkipFuncCheck
+		trees := map[string]*parse.Tree{}
+		if _, err := tree.Parse(string(data), "", "", trees); err != nil {
+			// The templates which do not parse are reported when rendering.
+			a.unparsed = true
+			continue
+		}
+		for n, t := range trees {
+			a.templates[n] = t
+		}
+		roots = append(roots, tree)
+	}
+
+	for _, tree := range roots {
+		if tree.Root == nil {
+			continue
+		}
+		root := templateData{kind: rootData, chart: files[tree.ParseName]}
+		a.walk(tree.Root, root, newTemplateScope(tree, root))
+	}
+	return a
+}
+
+// templateScope holds the variables of a template, and the values tested by
+// the enclosing if and with actions.
+type templateScope struct {
+	tree   *parse.Tree
+	vars   map[string]templateData
+	guards []valueRef
+}
+
+func newTemplateScope(tree *parse.Tree, dot templateData) *templateScope {
+	return &templateScope{tree: tree, vars: map[string]templateData{"$": dot}}
+}
+
+// block returns a scope for a block, whose variables go out of scope at its
+// end.
+func (s *templateScope) block() *templateScope {
+	vars := make(map[string]templateData, len(s.vars))
+	for k, v := range s.vars {
+		vars[k] = v
+	}
+	return &templateScope{tree: s.tree, vars: vars, guards: s.guards}
+}
+
+// guard returns a scope for the block of an if or with action, where the
+// values referred to since the given number of references are tested.
+func (s *templateScope) guard(a *valueAnalysis, since int) *templateScope {
+	block := s.block()
+	block.guards = append(append([]valueRef{}, s.guards...), a.refs[since:]...)
+	return block
+}
+
+// guarded returns whether a value is tested by an enclosing action.
+func (s *templateScope) guarded(d templateData) bool {
+	for _, g := range s.guards {
+		if g.chart == d.chart && hasPrefix(d.path, g.path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *valueAnalysis) walk(node parse.Node, dot templateData, s *templateScope) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			a.walk(child, dot, s)
+		}
+	case *parse.ActionNode:
+		d := a.pipe(n.Pipe, dot, s)
+		if len(n.Pipe.Decl) == 0 {
+			a.use(d, true, false, s)
+		}
+	case *parse.IfNode:
+		since := len(a.refs)
+		vars := s.block()
+		a.use(a.pipe(n.Pipe, dot, vars), false, true, s)
+		a.walk(n.List, dot, vars.guard(a, since))
+		a.walk(n.ElseList, dot, s.block())
+	case *parse.WithNode:
+		since := len(a.refs)
+		vars := s.block()
+		d := a.pipe(n.Pipe, dot, vars)
+		a.use(d, false, true, s)
+		a.walk(n.List, d, vars.guard(a, since))
+		a.walk(n.ElseList, dot, s.block())
+	case *parse.RangeNode:
+		block := s.block()
+		a.use(a.pipe(n.Pipe, dot, block), true, false, s)
+		for _, v := range n.Pipe.Decl {
+			block.vars[v.Ident[0]] = templateData{}
+		}
+		a.walk(n.List, templateData{}, block)
+		a.walk(n.ElseList, dot, s.block())
+	case *parse.TemplateNode:
+		var d templateData
+		if n.Pipe != nil {
+			d = a.pipe(n.Pipe, dot, s)
+		}
+		a.include(n.Name, d, s)
+	}
+}
+
+// pipe returns the data of a pipeline, declaring its variables.
+func (a *valueAnalysis) pipe(p *parse.PipeNode, dot templateData, s *templateScope) templateData {
+	if p == nil {
+		return templateData{}
+	}
+	var d *templateData
+	for _, cmd := range p.Cmds {
+		result := a.command(cmd, dot, s, d)
+		d = &result
+	}
+	if d == nil {
+		return templateData{}
+	}
+	for _, v := range p.Decl {
+		s.vars[v.Ident[0]] = *d
+	}
+	return *d
+}
+
+// command returns the data of a command, with the data piped into it if any.
+func (a *valueAnalysis) command(cmd *parse.CommandNode, dot templateData, s *templateScope, piped *templateData) templateData {
+	args := make([]templateData, 0, len(cmd.Args))
+	optional := false
+	useArgs := func() {
+		for _, d := range args {
+			a.use(d, true, optional, s)
+		}
+		if piped != nil {
+			a.use(*piped, true, optional, s)
+		}
+	}
+
+	if fn, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		optional = optionalFuncs[fn.Ident]
+		switch fn.Ident {
+		case "include":
+			if len(cmd.Args) > 1 {
+				if name, ok := cmd.Args[1].(*parse.StringNode); ok {
+					var d templateData
+					if len(cmd.Args) > 2 {
+						d = a.arg(cmd.Args[2], dot, s)
+					} else if piped != nil {
+						d = *piped
+					}
+					a.include(name.Text, d, s)
+					return templateData{}
+				}
+			}
+		case "index":
+			if len(cmd.Args) > 1 {
+				d := a.arg(cmd.Args[1], dot, s)
+				for _, key := range cmd.Args[2:] {
+					k, ok := key.(*parse.StringNode)
+					if !ok {
+						a.use(d, true, false, s)
+						args = append(args, a.arg(key, dot, s))
+						d = templateData{}
+						continue
+					}
+					d = d.field(k.Text, k)
+				}
+				if piped != nil {
+					a.use(d, true, false, s)
+					d = templateData{}
+				}
+				useArgs()
+				return d
+			}
+		}
+		for _, arg := range cmd.Args[1:] {
+			args = append(args, a.arg(arg, dot, s))
+		}
+		useArgs()
+		return templateData{}
+	}
+
+	d := a.arg(cmd.Args[0], dot, s)
+	if len(cmd.Args) == 1 && piped == nil {
+		return d
+	}
+	// A method call, or a value called with arguments.
+	args = append(args, d)
+	for _, arg := range cmd.Args[1:] {
+		args = append(args, a.arg(arg, dot, s))
+	}
+	useArgs()
+	return templateData{}
+}
+
+// optionalFuncs are the template functions handling undefined values.
+var optionalFuncs = map[string]bool{
+	"default":  true,
+	"required": true,
+	"empty":    true,
+	"coalesce": true,
+	"ternary":  true,
+	"and":      true,
+	"or":       true,
+	"not":      true,
+}
+
+// arg returns the data of an argument of a command.
+func (a *valueAnalysis) arg(node parse.Node, dot templateData, s *templateScope) templateData {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return fields(dot, n.Ident, n)
+	case *parse.VariableNode:
+		return fields(s.vars[n.Ident[0]], n.Ident[1:], n)
+	case *parse.ChainNode:
+		return fields(a.arg(n.Node, dot, s), n.Field, n)
+	case *parse.PipeNode:
+		return a.pipe(n, dot, s.block())
+	}
+	return templateData{}
+}
+
+func fields(d templateData, names []string, node parse.Node) templateData {
+	if len(names) == 0 && d.kind != unknownData {
+		d.node = node
+	}
+	for _, name := range names {
+		d = d.field(name, node)
+	}
+	return d
+}
+
+// include walks a named template, with the data passed to it. The data is
+// used as a whole if the template is not known.
+func (a *valueAnalysis) include(name string, d templateData, s *templateScope) {
+	tree, ok := a.templates[name]
+	if !ok || tree.Root == nil {
+		a.use(d, true, false, s)
+		return
+	}
+	key := name + "\x00" + d.key()
+	if a.walked[key] {
+		return
+	}
+	a.walked[key] = true
+	a.walk(tree.Root, d, newTemplateScope(tree, d))
+}
+
+// use records the use of data, as a whole or only through its fields, and
+// whether the template handles it being undefined.
+func (a *valueAnalysis) use(d templateData, whole, optional bool, s *templateScope) {
+	switch d.kind {
+	case rootData:
+		// The top-level data gives access to all the values, e.g. to 'tpl'.
+		if whole {
+			a.refs = append(a.refs, valueRef{chart: d.chart, whole: true})
+		}
+	case valuesData:
+		ref := valueRef{chart: d.chart, path: d.path, whole: whole, optional: optional || s.guarded(d)}
+		if d.node != nil {
+			location, _ := s.tree.ErrorContext(d.node)
+			file, rest, _ := strings.Cut(location, ":")
+			line, _, _ := strings.Cut(rest, ":")
+			ref.location.Template = file
+			ref.location.Line, _ = strconv.Atoi(line)
+		}
+		a.refs = append(a.refs, ref)
+	}
+}
+
+// uses returns whether a chart uses a value, or some of its fields.
+func (a *valueAnalysis) uses(c *chart.Chart, p []string) bool {
+	for _, ref := range a.refs {
+		if ref.chart != c {
+			continue
+		}
+		if hasPrefix(ref.path, p) || (ref.whole && hasPrefix(p, ref.path)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(p, prefix []string) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	for i := range prefix {
+		if p[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// unusedValue is a value of a values file used neither by the templates of
+// its chart nor by those of its subcharts.
+type unusedValue struct {
+	path []string
+	line int
+}
+
+// unusedValues returns the values of a YAML mapping of the values of a chart
+// which are not used. The values of subcharts, and global values, are used if
+// the subcharts use them.
+func (a *valueAnalysis) unusedValues(c *chart.Chart, node *yamlv3.Node, p []string) []unusedValue {
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	var unused []unusedValue
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		if key == "<<" {
+			continue
+		}
+		kp := append(append([]string{}, p...), key)
+		if len(p) == 0 && key == "global" {
+			continue
+		}
+		if len(p) == 0 && isDependency(c, key) {
+			if sub := dependency(c, key); sub != nil && a.charts[sub] {
+				unused = append(unused, a.subchartUnusedValues(sub, value, kp)...)
+			}
+			continue
+		}
+		if !a.uses(c, kp) {
+			unused = append(unused, unusedValue{path: kp, line: node.Content[i].Line})
+			continue
+		}
+		if !a.usesWhole(c, kp) {
+			unused = append(unused, a.unusedValues(c, value, kp)...)
+		}
+	}
+	return unused
+}
+
+// subchartUnusedValues returns the values a chart sets for a subchart which
+// are not used by it.
+func (a *valueAnalysis) subchartUnusedValues(sub *chart.Chart, node *yamlv3.Node, p []string) []unusedValue {
+	unused := a.unusedValues(sub, node, nil)
+	for i := range unused {
+		unused[i].path = append(append([]string{}, p...), unused[i].path...)
+	}
+	return unused
+}
+
+// usesWhole returns whether a chart uses a value as a whole, with all its
+// fields.
+func (a *valueAnalysis) usesWhole(c *chart.Chart, p []string) bool {
+	for _, ref := range a.refs {
+		if ref.chart == c && ref.whole && hasPrefix(p, ref.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDependency returns whether a key of the values of a chart holds the
+// values of one of its dependencies, enabled or not.
+func isDependency(c *chart.Chart, key string) bool {
+	for _, dep := range c.Metadata.Dependencies {
+		if dep.Name == key || dep.Alias == key {
+			return true
+		}
+	}
+	return false
+}
+
+// dependency returns the loaded dependency of a chart with the given name.
+func dependency(c *chart.Chart, name string) *chart.Chart {
+	for _, dep := range c.Dependencies() {
+		if dep.Name() == name {
+			return dep
+		}
+	}
+	return nil
+}
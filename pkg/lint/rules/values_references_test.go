@@ -0,0 +1,179 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+const referencesValues = `image:
+  repository: nginx
+  tag: latest
+labels: {}
+service:
+  port: 80
+  type: ClusterIP
+tls:
+  cert: ""
+unused:
+  nested: true
+podAnnotations:
+  a: b
+sub:
+  enabled: true
+  unusedBySub: 1
+global:
+  domain: example.com
+`
+
+const referencesHelpers = `{{- define "refs.image" -}}
+{{ .Values.image.repository }}:{{ .Values.image.tg }}
+{{- end }}
+{{- define "refs.port" -}}
+{{ .port }}{{ .protocol }}
+{{- end }}
+`
+
+const referencesDeployment = `apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ .Release.Name }}
+  labels:
+    extra: {{ .Values.labels.extra }}
+  {{- with .Values.podAnnotations }}
+  annotations:
+    {{- toYaml . | nindent 4 }}
+  {{- end }}
+spec:
+  containers:
+    - image: "{{ include "refs.image" . }}"
+      {{- $svc := .Values.service }}
+      ports:
+        - containerPort: {{ include "refs.port" $svc }}
+      env:
+        - name: TYPE
+          value: {{ $svc.typ | quote }}
+        - name: CERT
+          value: {{ index $.Values "tls" "cert" | quote }}
+        - name: DOMAIN
+          value: {{ .Values.global.domain }}
+        - name: REPLICAS
+          value: {{ .Values.replicas | default 1 | quote }}
+  {{- if .Values.debug }}
+        - name: DEBUG
+          value: {{ .Values.debug.level | quote }}
+  {{- end }}
+`
+
+func TestValueReferences(t *testing.T) {
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "sub", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/configmap.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: sub\ndata:\n  enabled: {{ .Values.enabled | quote }}\n")},
+		},
+	}
+	mychart := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion:   "v2",
+			Name:         "refs",
+			Version:      "0.1.0",
+			Icon:         "satisfy-the-linting-gods.gif",
+			Dependencies: []*chart.Dependency{{Name: "sub", Version: "0.1.0"}},
+		},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(referencesHelpers)},
+			{Name: "templates/pod.yaml", Data: []byte(referencesDeployment)},
+		},
+		Raw: []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte(referencesValues)}},
+	}
+	mychart.AddDependency(sub)
+	tmpdir := t.TempDir()
+	if err := chartutil.SaveDir(mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := chartutil.ReadValues([]byte(referencesValues))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rules are opt-in.
+	linter := support.Linter{ChartDir: filepath.Join(tmpdir, mychart.Name())}
+	Templates(&linter, vals, namespace, strict)
+	if len(linter.Messages) != 0 {
+		t.Fatalf("Expected no lint warnings without the values pack, got %v", linter.Messages)
+	}
+
+	linter = support.Linter{ChartDir: filepath.Join(tmpdir, mychart.Name()), Packs: []string{valuesPack}}
+	Templates(&linter, vals, namespace, strict)
+
+	want := []struct {
+		rule, path, err string
+	}{
+		{"values-defined", "templates/_helpers.tpl", ".Values.image.tg is not defined in values.yaml or the values schema (at refs/templates/_helpers.tpl:2)"},
+		{"values-defined", "templates/_helpers.tpl", ".Values.service.protocol is not defined in values.yaml or the values schema (at refs/templates/_helpers.tpl:5)"},
+		{"values-defined", "templates/pod.yaml", ".Values.service.typ is not defined in values.yaml or the values schema (at refs/templates/pod.yaml:19)"},
+		{"values-used", "values.yaml", `value "image.tag" is not used by the templates (at values.yaml:3)`},
+		{"values-used", "values.yaml", `value "service.type" is not used by the templates (at values.yaml:7)`},
+		{"values-used", "values.yaml", `value "unused" is not used by the templates (at values.yaml:10)`},
+		{"values-used", "values.yaml", `value "sub.unusedBySub" is not used by the templates (at values.yaml:16)`},
+	}
+	if len(linter.Messages) != len(want) {
+		t.Fatalf("Expected %d lint warnings, got %d: %v", len(want), len(linter.Messages), linter.Messages)
+	}
+	for i, msg := range linter.Messages {
+		if msg.RuleID != want[i].rule || msg.Path != want[i].path || msg.Err.Error() != want[i].err {
+			t.Errorf("Expected %s %s: %s, got %s %s: %s", want[i].rule, want[i].path, want[i].err, msg.RuleID, msg.Path, msg.Err)
+		}
+		if msg.Severity != support.WarningSev {
+			t.Errorf("Expected a warning, got %v", msg)
+		}
+	}
+}
+
+func TestSchemaDefines(t *testing.T) {
+	schema := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"image": map[string]interface{}{
+				"properties": map[string]interface{}{"tag": map[string]interface{}{"type": "string"}},
+			},
+			"labels": map[string]interface{}{
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"extra": map[string]interface{}{"type": "object"},
+		},
+	}
+	for _, tt := range []struct {
+		path    []string
+		defined bool
+	}{
+		{[]string{"image", "tag"}, true},
+		{[]string{"image", "tg"}, false},
+		{[]string{"imge"}, false},
+		{[]string{"labels", "app"}, true},
+		{[]string{"extra", "anything"}, true},
+	} {
+		if got := schemaDefines(schema, tt.path); got != tt.defined {
+			t.Errorf("schemaDefines(%v): expected %v, got %v", tt.path, tt.defined, got)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package support
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigFileName is the name of the lint configuration files, in a chart
+// directory or in any of its parent directories, e.g. at the root of a
+// repository of charts.
+const ConfigFileName = ".helmlint.yaml"
+
+// Config configures the lint rules, e.g.
+//
+//	packs: ["policy"]
+//	rules:
+//	  chart-icon-present: off
+//	  metadata-name: error
+//	ignore:
+//	  - paths: ["templates/legacy/*"]
+//	    rules: ["deprecated-api"]
+type Config struct {
+	// Rules maps rule IDs to the severity of their messages, 'info',
+	// 'warning' or 'error', or to 'off' to disable them.
+	Rules map[string]string `json:"rules,omitempty"`
+	// Packs are the packs of opt-in rules enabled. An opt-in rule is also
+	// enabled by configuring its severity in Rules.
+	Packs []string `json:"packs,omitempty"`
+	// Ignore ignores the messages of rules for some paths.
+	Ignore []ConfigIgnore `json:"ignore,omitempty"`
+
+	// files are the configuration files the configuration is loaded from.
+	files []string
+}
+
+// ConfigIgnore ignores the messages of rules for some paths.
+type ConfigIgnore struct {
+	// Paths are glob patterns of paths relative to the configuration file.
+	// A pattern matching a directory matches the files in it.
+	Paths []string `json:"paths"`
+	// Rules are the IDs of the ignored rules. All the rules are ignored if
+	// there are none.
+	Rules []string `json:"rules,omitempty"`
+
+	// dir is the directory of the configuration file.
+	dir string
+}
+
+// LoadConfig loads the configuration files of a chart directory: those in
+// the directory and in its parent directories, up to the root of the
+// repository containing it if any. The settings of the files closer to the
+// chart take precedence. It returns nil if there is no configuration file.
+func LoadConfig(chartDir string) (*Config, error) {
+	dir, err := filepath.Abs(chartDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for {
+		file := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	config := &Config{Rules: map[string]string{}}
+	for i := len(files) - 1; i >= 0; i-- {
+		c, err := ReadConfigFile(files[i])
+		if err != nil {
+			return nil, err
+		}
+		for id, severity := range c.Rules {
+			config.Rules[id] = severity
+		}
+		for _, pack := range c.Packs {
+			if !contains(config.Packs, pack) {
+				config.Packs = append(config.Packs, pack)
+			}
+		}
+		config.Ignore = append(config.Ignore, c.Ignore...)
+		config.files = append(config.files, files[i])
+	}
+	return config, nil
+}
+
+// ReadConfigFile reads a lint configuration file.
+func ReadConfigFile(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", file)
+	}
+	for id, severity := range config.Rules {
+		if !isOff(severity) {
+			if _, err := ParseSeverity(severity); err != nil {
+				return nil, errors.Wrapf(err, "%s: rule %s", file, id)
+			}
+		}
+	}
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	for i := range config.Ignore {
+		if len(config.Ignore[i].Paths) == 0 {
+			return nil, errors.Errorf("%s: ignore entry %d has no paths", file, i+1)
+		}
+		config.Ignore[i].dir = dir
+	}
+	config.files = []string{file}
+	return config, nil
+}
+
+// Files returns the configuration files the configuration is loaded from,
+// the furthest from the chart first.
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// RuleIDs returns the IDs of the rules configured, sorted.
+func (c *Config) RuleIDs() []string {
+	ids := map[string]bool{}
+	for id := range c.Rules {
+		ids[id] = true
+	}
+	for _, ignore := range c.Ignore {
+		for _, id := range ignore.Rules {
+			ids[id] = true
+		}
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// enables returns whether the configuration enables an opt-in rule, by its
+// pack or by its ID.
+func (c *Config) enables(rule Rule) bool {
+	if _, ok := c.Rules[rule.ID]; ok {
+		return true
+	}
+	return contains(c.Packs, rule.Pack)
+}
+
+// severity returns the severity of the messages of a rule, and whether the
+// rule is enabled.
+func (c *Config) severity(rule Rule) (int, bool) {
+	name, ok := c.Rules[rule.ID]
+	if !ok {
+		return rule.Severity, true
+	}
+	if isOff(name) {
+		return rule.Severity, false
+	}
+	severity, err := ParseSeverity(name)
+	if err != nil {
+		return rule.Severity, true
+	}
+	return severity, true
+}
+
+// ignores returns whether the messages of a rule are ignored for the file
+// at an absolute path.
+func (c *Config) ignores(ruleID, file string) bool {
+	if file == "" {
+		return false
+	}
+	for _, ignore := range c.Ignore {
+		if len(ignore.Rules) > 0 && !contains(ignore.Rules, ruleID) {
+			continue
+		}
+		rel, err := filepath.Rel(ignore.dir, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, pattern := range ignore.Paths {
+			if matchesPathOrParent(strings.TrimSuffix(pattern, "/"), rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesPathOrParent returns whether a glob pattern matches a slash
+// separated path or any of its parent directories.
+func matchesPathOrParent(pattern, p string) bool {
+	for ; p != "." && p != "/"; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isOff returns whether a severity disables a rule. An unquoted 'off' is
+// decoded as 'false' in YAML.
+func isOff(severity string) bool {
+	return severity == "off" || severity == "false"
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	repo := t.TempDir()
+	chartDir := filepath.Join(repo, "charts", "web")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(repo, ConfigFileName), `
+packs: ["policy"]
+rules:
+  chart-icon-present: error
+  metadata-name: off
+ignore:
+  - paths: ["charts/*/templates/legacy"]
+    rules: ["deprecated-api"]
+`)
+	writeFile(t, filepath.Join(chartDir, ConfigFileName), `
+packs: ["policy"]
+rules:
+  chart-icon-present: warning
+ignore:
+  - paths: ["templates/generated-*.yaml"]
+`)
+
+	config, err := LoadConfig(chartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Files()) != 2 || config.Files()[0] != filepath.Join(repo, ConfigFileName) {
+		t.Errorf("unexpected configuration files %v", config.Files())
+	}
+
+	// The configuration of the chart takes precedence.
+	if severity, enabled := config.severity(Rule{ID: "chart-icon-present", Severity: InfoSev}); severity != WarningSev || !enabled {
+		t.Errorf("expected chart-icon-present to be a warning, got %d, %t", severity, enabled)
+	}
+	if _, enabled := config.severity(Rule{ID: "metadata-name", Severity: WarningSev}); enabled {
+		t.Errorf("expected metadata-name to be disabled")
+	}
+	if severity, enabled := config.severity(Rule{ID: "chart-name", Severity: ErrorSev}); severity != ErrorSev || !enabled {
+		t.Errorf("expected chart-name to keep its severity, got %d, %t", severity, enabled)
+	}
+
+	// Paths are relative to the configuration files.
+	ignores := []struct {
+		rule, path string
+		ignored    bool
+	}{
+		{"deprecated-api", "templates/legacy/deployment.yaml", true},
+		{"deprecated-api", "templates/deployment.yaml", false},
+		{"metadata-name", "templates/legacy/deployment.yaml", false},
+		{"metadata-name", "templates/generated-service.yaml", true},
+		{"chart-name", "templates/generated-service.yaml", true},
+		{"chart-name", "templates/service.yaml", false},
+	}
+	for _, tt := range ignores {
+		if ignored := config.ignores(tt.rule, filepath.Join(chartDir, tt.path)); ignored != tt.ignored {
+			t.Errorf("expected ignores(%s, %s) to be %t", tt.rule, tt.path, tt.ignored)
+		}
+	}
+
+	if packs := strings.Join(config.Packs, ","); packs != "policy" {
+		t.Errorf("unexpected packs %s", packs)
+	}
+	if ids := strings.Join(config.RuleIDs(), ","); ids != "chart-icon-present,deprecated-api,metadata-name" {
+		t.Errorf("unexpected rule IDs %s", ids)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := map[string]string{
+		"rules:\n  chart-name: fatal\n":  `invalid severity "fatal"`,
+		"ignore:\n  - rules: [a]\n":      "ignore entry 1 has no paths",
+		"rule:\n  chart-name: warning\n": `unknown field "rule"`,
+	}
+	for content, expect := range tests {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, ConfigFileName), content)
+		if _, err := LoadConfig(dir); err == nil || !strings.Contains(err.Error(), expect) {
+			t.Errorf("expected an error containing %q, got %v", expect, err)
+		}
+	}
+
+	if config, err := LoadConfig(t.TempDir()); config != nil || err != nil {
+		t.Errorf("expected no configuration, got %v, %v", config, err)
+	}
+}
+
+func TestRunRule(t *testing.T) {
+	chartDir := t.TempDir()
+	writeFile(t, filepath.Join(chartDir, "templates", "all.yaml"), "{{/* helm-lint-disable */}}\n")
+	writeFile(t, filepath.Join(chartDir, "templates", "some.yaml"), "{{- /* helm-lint-disable metadata-name, deprecated-api */ -}}\n")
+	writeFile(t, filepath.Join(chartDir, "Chart.yaml"), "# helm-lint-disable chart-icon-present\nname: web\n")
+
+	metadataName := Rule{ID: "metadata-name", Severity: WarningSev}
+	chartName := Rule{ID: "chart-name", Severity: ErrorSev}
+	iconPresent := Rule{ID: "chart-icon-present", Severity: InfoSev}
+	errLint := errors.New("lint failed")
+
+	linter := Linter{ChartDir: chartDir, Config: &Config{Rules: map[string]string{"chart-name": "warning"}}}
+	tests := []struct {
+		rule     Rule
+		path     string
+		reported bool
+	}{
+		{metadataName, "templates/all.yaml", false},
+		{chartName, "templates/all.yaml", false},
+		{metadataName, "templates/some.yaml", false},
+		{chartName, "templates/some.yaml", true},
+		{iconPresent, "Chart.yaml", false},
+		{metadataName, "Chart.yaml", true},
+		{metadataName, "templates/missing.yaml", true},
+		{metadataName, "", true},
+	}
+	for _, tt := range tests {
+		before := len(linter.Messages)
+		if linter.RunRule(tt.rule, tt.path, errLint) {
+			t.Errorf("expected RunRule(%s, %s) to fail", tt.rule.ID, tt.path)
+		}
+		if reported := len(linter.Messages) > before; reported != tt.reported {
+			t.Errorf("expected RunRule(%s, %s) to report a message: %t", tt.rule.ID, tt.path, tt.reported)
+		}
+	}
+
+	if !linter.RunRule(chartName, "Chart.yaml", nil) {
+		t.Error("expected RunRule to pass without an error")
+	}
+	// The severity of chart-name is overridden.
+	if linter.HighestSeverity != WarningSev {
+		t.Errorf("expected the highest severity to be a warning, got %d", linter.HighestSeverity)
+	}
+	if linter.Messages[0].RuleID != "chart-name" {
+		t.Errorf("expected the message to have the ID of its rule, got %q", linter.Messages[0].RuleID)
+	}
+}
+
+func TestRunRulePacks(t *testing.T) {
+	privileged := Rule{ID: "privileged-container", Severity: WarningSev, Pack: "policy"}
+	hostNetwork := Rule{ID: "host-network", Severity: WarningSev, Pack: "policy"}
+	errLint := errors.New("lint failed")
+
+	tests := []struct {
+		name     string
+		linter   Linter
+		rule     Rule
+		reported bool
+	}{
+		{"pack not enabled", Linter{}, privileged, false},
+		{"pack enabled by the linter", Linter{Packs: []string{"policy"}}, privileged, true},
+		{"pack enabled by the configuration", Linter{Config: &Config{Packs: []string{"policy"}}}, privileged, true},
+		{"rule configured", Linter{Config: &Config{Rules: map[string]string{"host-network": "error"}}}, hostNetwork, true},
+		{"other rule configured", Linter{Config: &Config{Rules: map[string]string{"host-network": "error"}}}, privileged, false},
+		{"rule of an enabled pack disabled", Linter{Packs: []string{"policy"}, Config: &Config{Rules: map[string]string{"host-network": "off"}}}, hostNetwork, false},
+	}
+	for _, tt := range tests {
+		tt.linter.ChartDir = t.TempDir()
+		tt.linter.RunRule(tt.rule, "templates/deployment.yaml", errLint)
+		if reported := len(tt.linter.Messages) > 0; reported != tt.reported {
+			t.Errorf("%s: expected a message to be reported: %t", tt.name, tt.reported)
+		}
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	if err := (Rule{ID: "my-rule", Severity: WarningSev}).Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for _, rule := range []Rule{{ID: "My_Rule", Severity: WarningSev}, {ID: "", Severity: WarningSev}, {ID: "my-rule", Severity: UnknownSev}, {ID: "my-rule", Severity: WarningSev, Pack: "My Pack"}} {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", rule)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package support
+
+// Fix is the automatic remediation of the finding of a lint message, by
+// editing or renaming a file of the chart.
+type Fix struct {
+	// Description describes the change, e.g. "set version to 1.2.0".
+	Description string
+	// Path is the file to change, relative to the chart directory.
+	Path string
+	// Edit, when set, returns the new content of the file, given its
+	// current content.
+	Edit func(data []byte, opts FixOptions) ([]byte, error)
+	// Rename, when set, is the new path of the file, relative to the chart
+	// directory.
+	Rename string
+}
+
+// FixOptions provides the values fixes cannot infer from the chart.
+type FixOptions struct {
+	// Icon is the URL of the icon set in the charts without one.
+	Icon string
+}
+
+// fixError is an error with a fix.
+type fixError struct {
+	error
+	fix *Fix
+}
+
+// WithFix attaches a fix to the error of a rule. RunRule reports the error
+// as the Err of the message, and the fix as its Fix. It returns nil if err
+// is nil.
+func WithFix(err error, fix *Fix) error {
+	if err == nil {
+		return nil
+	}
+	return &fixError{error: err, fix: fix}
+}
+
+// splitFix returns the fix attached to an error by WithFix, if any, and the
+// error it is attached to.
+func splitFix(err error) (*Fix, error) {
+	if fe, ok := err.(*fixError); ok {
+		return fe.fix, fe.error
+	}
+	return nil, err
+}
@@ -16,7 +16,10 @@ limitations under the License.
 
 package support
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Severity indicates the severity of a Message.
 const (
@@ -39,6 +42,15 @@ type Linter struct {
 	// The highest severity of all the failing lint rules
 	HighestSeverity int
 	ChartDir        string
+	// Config, when set, disables rules, overrides their severity and
+	// ignores their messages for some paths.
+	Config *Config
+	// Packs are the packs of opt-in rules enabled, in addition to those
+	// enabled by Config.
+	Packs []string
+
+	// suppressions caches the rules disabled by comments in each file.
+	suppressions map[string][]string
 }
 
 // Message describes an error encountered while linting.
@@ -47,9 +59,24 @@ type Message struct {
 	Severity int
 	Path     string
 	Err      error
+	// RuleID is the ID of the rule reporting the message, if any.
+	RuleID string
+	// Line and Column are the 1-based position in the file at Path the
+	// message refers to, or 0 if unknown.
+	Line   int
+	Column int
+	// Fix, when set, remediates the finding automatically.
+	Fix *Fix
+	// Combinations are the names of the combinations of values and
+	// Kubernetes versions of a lint matrix the message is reported for. It
+	// is empty outside a matrix.
+	Combinations []string
 }
 
 func (m Message) Error() string {
+	if len(m.Combinations) > 0 {
+		return fmt.Sprintf("[%s] %s: %s (in %s)", sev[m.Severity], m.Path, m.Err.Error(), strings.Join(m.Combinations, ", "))
+	}
 	return fmt.Sprintf("[%s] %s: %s", sev[m.Severity], m.Path, m.Err.Error())
 }
 
@@ -74,3 +101,35 @@ func (l *Linter) RunLinterRule(severity int, path string, err error) bool {
 	}
 	return err == nil
 }
+
+// RunRule reports the error of a rule, if any, as RunLinterRule does with
+// the severity of the rule. The error is not reported if the rule is
+// disabled or its messages are ignored for the path, by the configuration of
+// the linter or by a comment in the file at path, nor if the rule is in a
+// pack which is not enabled. It returns true if the validation passed.
+func (l *Linter) RunRule(rule Rule, path string, err error) bool {
+	if err == nil {
+		return true
+	}
+	if rule.Pack != "" && !contains(l.Packs, rule.Pack) && (l.Config == nil || !l.Config.enables(rule)) {
+		return false
+	}
+	severity := rule.Severity
+	if l.Config != nil {
+		var enabled bool
+		if severity, enabled = l.Config.severity(rule); !enabled || l.Config.ignores(rule.ID, l.absPath(path)) {
+			return false
+		}
+	}
+	if l.suppressed(rule.ID, path) {
+		return false
+	}
+
+	fix, err := splitFix(err)
+	line, column := position(path, err)
+	l.Messages = append(l.Messages, Message{Severity: severity, Path: path, Err: err, RuleID: rule.ID, Line: line, Column: column, Fix: fix})
+	if severity > l.HighestSeverity {
+		l.HighestSeverity = severity
+	}
+	return false
+}
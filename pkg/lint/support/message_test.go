@@ -63,18 +63,46 @@ func TestRunLinterRule(t *testing.T) {
 }
 
 func TestMessage(t *testing.T) {
-	m := Message{ErrorSev, "Chart.yaml", errors.New("Foo")}
+	m := Message{Severity: ErrorSev, Path: "Chart.yaml", Err: errors.New("Foo")}
 	if m.Error() != "[ERROR] Chart.yaml: Foo" {
 		t.Errorf("Unexpected output: %s", m.Error())
 	}
 
-	m = Message{WarningSev, "templates/", errors.New("Bar")}
+	m = Message{Severity: WarningSev, Path: "templates/", Err: errors.New("Bar")}
 	if m.Error() != "[WARNING] templates/: Bar" {
 		t.Errorf("Unexpected output: %s", m.Error())
 	}
 
-	m = Message{InfoSev, "templates/rc.yaml", errors.New("FooBar")}
+	m = Message{Severity: InfoSev, Path: "templates/rc.yaml", Err: errors.New("FooBar")}
 	if m.Error() != "[INFO] templates/rc.yaml: FooBar" {
 		t.Errorf("Unexpected output: %s", m.Error())
 	}
 }
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		path, err    string
+		line, column int
+	}{
+		{"templates/bad.yaml", `template: mychart/templates/bad.yaml:2:13: executing "mychart/templates/bad.yaml" at <.Values.x.y>: nil pointer`, 2, 13},
+		{"templates/bad.yaml", `template: mychart/templates/bad.yaml:7: function "foo" not defined`, 7, 0},
+		{"templates/bad.yaml", `unable to parse YAML (at mychart/templates/bad.yaml:4)`, 4, 0},
+		{"templates/bad.yaml", `unable to parse YAML (at mychart/templates/_helpers.tpl:4)`, 0, 0},
+		{"templates/bad.yaml", `error converting YAML to JSON: yaml: line 3: mapping values are not allowed`, 0, 0},
+		{"templates/", `template: mychart/templates/bad.yaml:2:13: nil pointer`, 0, 0},
+		{"Chart.yaml", `error converting YAML to JSON: yaml: line 6: did not find expected '-' indicator`, 6, 0},
+		{"values.yaml", `values don't meet the specifications of the schema`, 0, 0},
+		{"", `yaml: line 6: did not find expected key`, 0, 0},
+	}
+	for _, tt := range tests {
+		if line, column := position(tt.path, errors.New(tt.err)); line != tt.line || column != tt.column {
+			t.Errorf("expected position(%s, %q) to be %d:%d, got %d:%d", tt.path, tt.err, tt.line, tt.column, line, column)
+		}
+	}
+
+	l := Linter{}
+	l.RunRule(Rule{ID: "chart-name", Severity: ErrorSev}, "Chart.yaml", errors.New("yaml: line 2: did not find expected key"))
+	if m := l.Messages[0]; m.Line != 2 || m.Column != 0 {
+		t.Errorf("expected the message to be at line 2, got %d:%d", m.Line, m.Column)
+	}
+}
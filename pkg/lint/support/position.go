@@ -0,0 +1,55 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package support
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlLinePattern matches the line of YAML syntax errors, e.g.
+// 'yaml: line 3: mapping values are not allowed in this context'.
+var yamlLinePattern = regexp.MustCompile(`\byaml: line (\d+):`)
+
+// position returns the line and the column in the file at path that an
+// error refers to, if the error tells them, or 0.
+//
+// Template errors and source locations tell them as 'mychart/<path>:3:12'
+// or 'mychart/<path>:3'. YAML syntax errors tell the line, but only the
+// lines of files that are not templates, as those of templates are lines of
+// the rendered manifests.
+func position(path string, err error) (int, int) {
+	if path == "" || strings.HasSuffix(path, "/") {
+		return 0, 0
+	}
+	msg := err.Error()
+
+	location := regexp.MustCompile(`(?:^|[\s(/])` + regexp.QuoteMeta(path) + `:(\d+)(?::(\d+))?`)
+	if m := location.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		column, _ := strconv.Atoi(m[2])
+		return line, column
+	}
+	if !strings.HasPrefix(path, "templates/") {
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return line, 0
+		}
+	}
+	return 0, 0
+}
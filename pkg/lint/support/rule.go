@@ -0,0 +1,137 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Rule identifies a lint rule.
+type Rule struct {
+	// ID is the stable identifier of the rule, in kebab-case, used to
+	// configure it and to suppress its messages.
+	ID string
+	// Severity is the default severity of the messages of the rule, one of
+	// the *Sev constants.
+	Severity int
+	// Description describes what the rule checks.
+	Description string
+	// Pack is the pack of opt-in rules the rule belongs to, if any. The
+	// rules of a pack are disabled unless the pack is enabled, or the rule
+	// is configured.
+	Pack string
+}
+
+var ruleIDPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Validate checks that the ID, the severity and the pack of the rule are
+// valid.
+func (r Rule) Validate() error {
+	if !ruleIDPattern.MatchString(r.ID) {
+		return errors.Errorf("invalid lint rule ID %q: IDs are lowercase words separated by dashes", r.ID)
+	}
+	if r.Severity < InfoSev || r.Severity > ErrorSev {
+		return errors.Errorf("invalid severity %d of lint rule %s", r.Severity, r.ID)
+	}
+	if r.Pack != "" && !ruleIDPattern.MatchString(r.Pack) {
+		return errors.Errorf("invalid pack %q of lint rule %s: packs are lowercase words separated by dashes", r.Pack, r.ID)
+	}
+	return nil
+}
+
+// SeverityName returns the name of a severity, e.g. "WARNING".
+func SeverityName(severity int) string {
+	if severity < 0 || severity >= len(sev) {
+		return sev[UnknownSev]
+	}
+	return sev[severity]
+}
+
+// ParseSeverity parses the name of a severity, e.g. "warning", ignoring case.
+func ParseSeverity(name string) (int, error) {
+	for severity, s := range sev {
+		if severity != UnknownSev && strings.EqualFold(name, s) {
+			return severity, nil
+		}
+	}
+	return UnknownSev, errors.Errorf("invalid severity %q: valid severities are info, warning and error", name)
+}
+
+// suppressionPattern matches the comments suppressing the messages of rules
+// in a file, e.g. '{{/* helm-lint-disable metadata-name */}}' or
+// '# helm-lint-disable'. Without rule IDs, all the rules are suppressed.
+var suppressionPattern = regexp.MustCompile(`helm-lint-disable((?:[ \t,]+[a-z0-9]+(?:-[a-z0-9]+)*)*)`)
+
+// suppressed returns whether the messages of a rule are suppressed by a
+// comment in the file at path.
+func (l *Linter) suppressed(ruleID, path string) bool {
+	if l.suppressions == nil {
+		l.suppressions = map[string][]string{}
+	}
+	ids, ok := l.suppressions[path]
+	if !ok {
+		ids = readSuppressions(l.absPath(path))
+		l.suppressions[path] = ids
+	}
+	for _, id := range ids {
+		if id == "*" || id == ruleID {
+			return true
+		}
+	}
+	return false
+}
+
+// readSuppressions returns the IDs of the rules suppressed in a file, with
+// '*' for all the rules.
+func readSuppressions(path string) []string {
+	if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	ids := []string{}
+	for _, match := range suppressionPattern.FindAllStringSubmatch(string(data), -1) {
+		fields := strings.FieldsFunc(match[1], func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+		if len(fields) == 0 {
+			fields = []string{"*"}
+		}
+		ids = append(ids, fields...)
+	}
+	return ids
+}
+
+// absPath returns the absolute path of a path of a message, which is
+// relative to the chart directory unless it is absolute.
+func (l *Linter) absPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	abs, err := filepath.Abs(filepath.Join(l.ChartDir, path))
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,555 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package policy evaluates deploy-time policies against releases before they are
+installed or upgraded.
+
+A policy is a CEL expression which evaluates to true when the policy is met.
+Policies of the object scope are evaluated for each rendered object, including
+hooks and the CRDs of the crds/ directories of the chart, with the variables 'object', the object, 'namespace', its namespace or
+the namespace of the release, and 'release'. Policies of the release scope are
+evaluated once, with the variables 'objects', the list of the objects, and
+'release'. 'release' has the fields name, namespace, revision, isInstall,
+isUpgrade, labels and chart, with the fields name, version and appVersion.
+
+Policies are read from YAML files:
+
+	policies:
+	  - name: team-label
+	    description: Deployments set the app.kubernetes.io/team label
+	    match:
+	      kinds: [Deployment]
+	    validation: "'app.kubernetes.io/team' in object.metadata.?labels.orValue({})"
+	  - name: no-load-balancers
+	    mode: warn
+	    match:
+	      kinds: [Service]
+	      namespaces: [internal]
+	    validation: "object.spec.?type.orValue('') != 'LoadBalancer'"
+	  - name: small-releases
+	    scope: release
+	    validation: "size(objects) <= 50"
+*/
+package policy // import "helm.sh/helm/v3/pkg/policy"
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// costLimit bounds the cost of evaluating a validation expression, as the
+// Kubernetes API server does for the CEL expressions of admission policies,
+// so that an expensive expression fails instead of hanging the release.
+const costLimit = 1000000
+
+// Mode is what a violation of a policy does to the release.
+type Mode string
+
+const (
+	// Deny fails the installation or upgrade of the release.
+	Deny Mode = "deny"
+	// Warn reports the violation, and lets the release proceed.
+	Warn Mode = "warn"
+)
+
+// Scope is what a policy is evaluated over.
+type Scope string
+
+const (
+	// ObjectScope evaluates the policy for each object of the release.
+	ObjectScope Scope = "object"
+	// ReleaseScope evaluates the policy once for the release.
+	ReleaseScope Scope = "release"
+)
+
+// Policy is a deploy-time policy.
+type Policy struct {
+	// Name identifies the policy in the violations.
+	Name string `json:"name"`
+	// Description is reported for the violations, unless Message is set.
+	Description string `json:"description,omitempty"`
+	// Mode is deny, the default, or warn.
+	Mode Mode `json:"mode,omitempty"`
+	// Scope is object, the default, or release.
+	Scope Scope `json:"scope,omitempty"`
+	// Match selects the objects the policy applies to.
+	Match Match `json:"match,omitempty"`
+	// Validation is the CEL expression, true when the policy is met.
+	Validation string `json:"validation"`
+	// Message is reported for the violations.
+	Message string `json:"message,omitempty"`
+}
+
+// Match selects objects. An empty list matches all the objects.
+type Match struct {
+	APIVersions []string `json:"apiVersions,omitempty"`
+	Kinds       []string `json:"kinds,omitempty"`
+	// Namespaces are matched against the namespace of the objects, or that of
+	// the release for the objects which do not set one.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// File is the format of policy files.
+type File struct {
+	Policies []Policy `json:"policies"`
+}
+
+// Set is a set of compiled policies.
+type Set struct {
+	policies []compiledPolicy
+}
+
+type compiledPolicy struct {
+	Policy
+	program cel.Program
+}
+
+// Load reads the policies of files, and of the YAML files of directories.
+func Load(paths ...string) (*Set, error) {
+	var policies []Policy
+	for _, path := range paths {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			files = nil
+			for _, pattern := range []string{"*.yaml", "*.yml"} {
+				matches, err := filepath.Glob(filepath.Join(path, pattern))
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, matches...)
+			}
+			if len(files) == 0 {
+				return nil, errors.Errorf("no policy files in %s", path)
+			}
+			sort.Strings(files)
+		}
+		for _, name := range files {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return nil, err
+			}
+			var f File
+			if err := yaml.UnmarshalStrict(data, &f); err != nil {
+				return nil, errors.Wrapf(err, "reading policy file %s", name)
+			}
+			policies = append(policies, f.Policies...)
+		}
+	}
+	return NewSet(policies...)
+}
+
+// NewSet compiles policies.
+func NewSet(policies ...Policy) (*Set, error) {
+	s := &Set{}
+	names := map[string]bool{}
+	for _, p := range policies {
+		if p.Name == "" {
+			return nil, errors.New("a policy has no name")
+		}
+		if names[p.Name] {
+			return nil, errors.Errorf("policy %q is defined more than once", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Mode == "" {
+			p.Mode = Deny
+		}
+		if p.Mode != Deny && p.Mode != Warn {
+			return nil, errors.Errorf("policy %q: invalid mode %q: allowed values are deny and warn", p.Name, p.Mode)
+		}
+		if p.Scope == "" {
+			p.Scope = ObjectScope
+		}
+		program, err := compile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "policy %q", p.Name)
+		}
+		s.policies = append(s.policies, compiledPolicy{Policy: p, program: program})
+	}
+	return s, nil
+}
+
+// compile compiles the validation expression of a policy.
+func compile(p Policy) (cel.Program, error) {
+	options := []cel.EnvOption{ext.Strings(), cel.OptionalTypes(), cel.Variable("release", cel.DynType)}
+	switch p.Scope {
+	case ObjectScope:
+		options = append(options, cel.Variable("object", cel.DynType), cel.Variable("namespace", cel.StringType))
+	case ReleaseScope:
+		options = append(options, cel.Variable("objects", cel.ListType(cel.DynType)))
+	default:
+		return nil, errors.Errorf("invalid scope %q: allowed values are object and release", p.Scope)
+	}
+	env, err := cel.NewEnv(options...)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(p.Validation) == "" {
+		return nil, errors.New("the validation expression is empty")
+	}
+	ast, issues := env.Compile(p.Validation)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if t := ast.OutputType(); !t.IsExactType(cel.BoolType) && !t.IsExactType(cel.DynType) {
+		return nil, errors.Errorf("the validation expression returns %s instead of a bool", t)
+	}
+	return env.Program(ast, cel.CostLimit(costLimit))
+}
+
+// Policies returns the policies of the set.
+func (s *Set) Policies() []Policy {
+	policies := make([]Policy, 0, len(s.policies))
+	for _, p := range s.policies {
+		policies = append(policies, p.Policy)
+	}
+	return policies
+}
+
+// Violation is the violation of a policy by a release or one of its objects.
+type Violation struct {
+	Policy string
+	Mode   Mode
+	// Object identifies the object, e.g. "Deployment/web", for the policies
+	// of the object scope.
+	Object string
+	// Source is the template of the object.
+	Source  string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Object == "" {
+		return fmt.Sprintf("%s: %s", v.Policy, v.Message)
+	}
+	if v.Source == "" {
+		return fmt.Sprintf("%s: %s: %s", v.Policy, v.Object, v.Message)
+	}
+	return fmt.Sprintf("%s: %s (%s): %s", v.Policy, v.Object, v.Source, v.Message)
+}
+
+// Report is the result of the evaluation of policies.
+type Report struct {
+	Violations []Violation
+}
+
+// Warnings returns the violations of the policies of the warn mode.
+func (r *Report) Warnings() []Violation {
+	return r.filter(Warn)
+}
+
+// Denials returns the violations of the policies of the deny mode.
+func (r *Report) Denials() []Violation {
+	return r.filter(Deny)
+}
+
+func (r *Report) filter(mode Mode) []Violation {
+	var violations []Violation
+	for _, v := range r.Violations {
+		if v.Mode == mode {
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// Err returns a DeniedError if policies of the deny mode are violated.
+func (r *Report) Err() error {
+	if denials := r.Denials(); len(denials) > 0 {
+		return &DeniedError{Violations: denials}
+	}
+	return nil
+}
+
+// DeniedError is returned for the releases which violate policies of the deny
+// mode.
+type DeniedError struct {
+	Violations []Violation
+}
+
+func (e *DeniedError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "denied by %d policy violation(s):", len(e.Violations))
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "\n- %s", v)
+	}
+	return b.String()
+}
+
+// object is a rendered object of a release.
+type object struct {
+	data   map[string]interface{}
+	source string
+}
+
+func (o object) field(path ...string) string {
+	var v interface{} = o.data
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = m[key]
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// Evaluate evaluates the policies of the set against the manifest and the
+// hooks of a release.
+func (s *Set) Evaluate(rel *release.Release, isUpgrade bool) (*Report, error) {
+	objects, err := releaseObjects(rel)
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(rel, isUpgrade, objects, true), nil
+}
+
+// EvaluateCRDs evaluates the policies of the object scope against the CRDs of
+// the crds/ directories of the chart of a release, which are installed before
+// the release.
+func (s *Set) EvaluateCRDs(rel *release.Release, crds []chart.CRD) (*Report, error) {
+	var objects []object
+	for _, crd := range crds {
+		for _, manifest := range sortedManifests(string(crd.File.Data)) {
+			o, err := decodeObject(crd.Filename, manifest)
+			if err != nil {
+				return nil, err
+			}
+			if o != nil {
+				objects = append(objects, *o)
+			}
+		}
+	}
+	return s.evaluate(rel, false, objects, false), nil
+}
+
+// evaluate evaluates the policies of the set against objects, and those of
+// the release scope if withRelease is set.
+func (s *Set) evaluate(rel *release.Release, isUpgrade bool, objects []object, withRelease bool) *Report {
+	releaseVar := releaseVariable(rel, isUpgrade)
+
+	report := &Report{}
+	for _, p := range s.policies {
+		if p.Scope == ReleaseScope && !withRelease {
+			continue
+		}
+		var matched []object
+		for _, o := range objects {
+			if p.Match.matches(o, rel.Namespace) {
+				matched = append(matched, o)
+			}
+		}
+
+		if p.Scope == ReleaseScope {
+			list := make([]interface{}, 0, len(matched))
+			for _, o := range matched {
+				list = append(list, o.data)
+			}
+			if msg, ok := p.evaluate(map[string]interface{}{"release": releaseVar, "objects": list}); !ok {
+				report.Violations = append(report.Violations, Violation{Policy: p.Name, Mode: p.Mode, Message: msg})
+			}
+			continue
+		}
+		for _, o := range matched {
+			namespace := o.field("metadata", "namespace")
+			if namespace == "" {
+				namespace = rel.Namespace
+			}
+			if msg, ok := p.evaluate(map[string]interface{}{"release": releaseVar, "object": o.data, "namespace": namespace}); !ok {
+				report.Violations = append(report.Violations, Violation{
+					Policy:  p.Name,
+					Mode:    p.Mode,
+					Object:  o.field("kind") + "/" + o.field("metadata", "name"),
+					Source:  o.source,
+					Message: msg,
+				})
+			}
+		}
+	}
+	return report
+}
+
+// evaluate evaluates the validation expression of the policy. It returns the
+// message of the violation and false if the policy is not met, including when
+// the expression fails.
+func (p *compiledPolicy) evaluate(vars map[string]interface{}) (string, bool) {
+	out, _, err := p.program.Eval(vars)
+	if err != nil {
+		return fmt.Sprintf("evaluating the validation expression: %s", err), false
+	}
+	if met, ok := out.Value().(bool); !ok {
+		return fmt.Sprintf("the validation expression returned %v instead of a bool", out.Value()), false
+	} else if met {
+		return "", true
+	}
+	switch {
+	case p.Message != "":
+		return p.Message, false
+	case p.Description != "":
+		return p.Description, false
+	}
+	return fmt.Sprintf("failed %s", p.Validation), false
+}
+
+func (m Match) matches(o object, releaseNamespace string) bool {
+	namespace := o.field("metadata", "namespace")
+	if namespace == "" {
+		namespace = releaseNamespace
+	}
+	return matchesAny(m.APIVersions, o.field("apiVersion")) &&
+		matchesAny(m.Kinds, o.field("kind")) &&
+		matchesAny(m.Namespaces, namespace)
+}
+
+func matchesAny(list []string, s string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeObject decodes an object of a manifest. It returns nil for empty
+// documents.
+func decodeObject(source, manifest string) (*object, error) {
+	var data map[string]interface{}
+	if err := yaml.Unmarshal([]byte(manifest), &data, func(d *json.Decoder) *json.Decoder {
+		d.UseNumber()
+		return d
+	}); err != nil {
+		return nil, errors.Wrapf(err, "decoding an object of %s", source)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return &object{data: normalize(data).(map[string]interface{}), source: source}, nil
+}
+
+// releaseObjects decodes the objects of the manifest and the hooks of a release.
+func releaseObjects(rel *release.Release) ([]object, error) {
+	var objects []object
+	add := func(source, manifest string) error {
+		o, err := decodeObject(source, manifest)
+		if o != nil {
+			objects = append(objects, *o)
+		}
+		return err
+	}
+
+	for _, manifest := range sortedManifests(rel.Manifest) {
+		if err := add(manifestSource(manifest), manifest); err != nil {
+			return nil, err
+		}
+	}
+	for _, h := range rel.Hooks {
+		if err := add(h.Path, h.Manifest); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// sortedManifests splits a manifest into its documents, in order.
+func sortedManifests(manifest string) []string {
+	manifests := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(manifests))
+	for k := range manifests {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+	docs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		docs = append(docs, manifests[k])
+	}
+	return docs
+}
+
+// manifestSource returns the template of a manifest, from its "# Source:"
+// comment.
+func manifestSource(manifest string) string {
+	for _, line := range strings.Split(manifest, "\n") {
+		if source, ok := strings.CutPrefix(line, "# Source: "); ok {
+			return strings.TrimSpace(source)
+		}
+	}
+	return ""
+}
+
+// normalize converts the numbers of decoded JSON to int64 or float64, which
+// CEL compares as expected.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalize(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// releaseVariable returns the value of the 'release' variable.
+func releaseVariable(rel *release.Release, isUpgrade bool) map[string]interface{} {
+	labels := map[string]interface{}{}
+	for k, v := range rel.Labels {
+		labels[k] = v
+	}
+	chart := map[string]interface{}{}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		chart["name"] = rel.Chart.Metadata.Name
+		chart["version"] = rel.Chart.Metadata.Version
+		chart["appVersion"] = rel.Chart.Metadata.AppVersion
+	}
+	return map[string]interface{}{
+		"name":      rel.Name,
+		"namespace": rel.Namespace,
+		"revision":  int64(rel.Version),
+		"isInstall": !isUpgrade,
+		"isUpgrade": isUpgrade,
+		"labels":    labels,
+		"chart":     chart,
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+const testManifest = `---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app.kubernetes.io/team: payments
+spec:
+  replicas: 3
+---
+# Source: web/templates/worker.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  replicas: 1
+---
+# Source: web/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: internal
+spec:
+  type: LoadBalancer
+`
+
+const testPolicies = `policies:
+  - name: team-label
+    description: Deployments set the app.kubernetes.io/team label
+    match:
+      apiVersions: [apps/v1]
+      kinds: [Deployment]
+    validation: "'app.kubernetes.io/team' in object.metadata.?labels.orValue({})"
+  - name: no-load-balancers
+    mode: warn
+    match:
+      kinds: [Service]
+      namespaces: [internal]
+    validation: "object.spec.?type.orValue('') != 'LoadBalancer'"
+  - name: replicas
+    match:
+      namespaces: [default]
+    validation: "object.spec.replicas <= 2"
+    message: at most 2 replicas
+  - name: small-releases
+    scope: release
+    validation: "size(objects) <= 3 && release.chart.name == 'web' && release.isInstall"
+`
+
+func testRelease() *release.Release {
+	return &release.Release{
+		Name:      "web",
+		Namespace: "default",
+		Version:   1,
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "web", Version: "0.1.0"}},
+		Manifest:  testManifest,
+		Hooks: []*release.Hook{{
+			Path:     "web/templates/test.yaml",
+			Manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: web-test\n",
+		}},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "policies.yaml"), []byte(testPolicies), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Policies()) != 4 || set.Policies()[0].Mode != Deny || set.Policies()[3].Scope != ReleaseScope {
+		t.Fatalf("unexpected policies %v", set.Policies())
+	}
+
+	report, err := set.Evaluate(testRelease(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"team-label: Deployment/worker (web/templates/worker.yaml): Deployments set the app.kubernetes.io/team label",
+		"no-load-balancers: Service/web (web/templates/service.yaml): failed object.spec.?type.orValue('') != 'LoadBalancer'",
+		"replicas: Deployment/web (web/templates/deployment.yaml): at most 2 replicas",
+		"replicas: Job/web-test (web/templates/test.yaml): evaluating the validation expression: no such key: spec",
+		"small-releases: failed size(objects) <= 3 && release.chart.name == 'web' && release.isInstall",
+	}
+	if len(report.Violations) != len(want) {
+		t.Fatalf("expected %d violations, got %v", len(want), report.Violations)
+	}
+	for i, v := range report.Violations {
+		if v.String() != want[i] {
+			t.Errorf("expected violation %q, got %q", want[i], v)
+		}
+	}
+	if len(report.Warnings()) != 1 || len(report.Denials()) != 4 {
+		t.Errorf("expected 1 warning and 4 denials, got %v and %v", report.Warnings(), report.Denials())
+	}
+
+	err = report.Err()
+	if _, ok := err.(*DeniedError); !ok {
+		t.Fatalf("expected a DeniedError, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "denied by 4 policy violation(s):\n- team-label: Deployment/worker") {
+		t.Errorf("unexpected error %q", err)
+	}
+}
+
+func TestEvaluateUpgrade(t *testing.T) {
+	set, err := NewSet(Policy{Name: "upgrades", Scope: ReleaseScope, Validation: "release.isUpgrade && release.revision == 2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := testRelease()
+	rel.Version = 2
+	report, err := set.Evaluate(rel, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Violations) != 0 || report.Err() != nil {
+		t.Errorf("expected no violations, got %v", report.Violations)
+	}
+}
+
+func TestEvaluateCostLimit(t *testing.T) {
+	set, err := NewSet(Policy{Name: "expensive", Scope: ReleaseScope, Validation: "objects.all(a, objects.all(b, objects.all(c, objects.all(d, true))))"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := testRelease()
+	var manifest strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&manifest, "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm-%d\n", i)
+	}
+	rel.Manifest = manifest.String()
+	report, err := set.Evaluate(rel, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Violations) != 1 || !strings.Contains(report.Violations[0].Message, "cost limit exceeded") {
+		t.Errorf("expected the cost limit to be exceeded, got %v", report.Violations)
+	}
+}
+
+func TestNewSetErrors(t *testing.T) {
+	for _, tt := range []struct {
+		policy Policy
+		err    string
+	}{
+		{Policy{Validation: "true"}, "a policy has no name"},
+		{Policy{Name: "p", Mode: "block", Validation: "true"}, `policy "p": invalid mode "block"`},
+		{Policy{Name: "p", Scope: "chart", Validation: "true"}, `policy "p": invalid scope "chart"`},
+		{Policy{Name: "p"}, `policy "p": the validation expression is empty`},
+		{Policy{Name: "p", Validation: "object.metadata.name + '-x'"}, `policy "p": the validation expression returns`},
+		{Policy{Name: "p", Validation: "size(objects) > 0"}, `policy "p": ERROR: <input>:1:6: undeclared reference to 'objects'`},
+	} {
+		_, err := NewSet(tt.policy)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("expected error %q, got %v", tt.err, err)
+		}
+	}
+
+	if _, err := NewSet(Policy{Name: "p", Validation: "true"}, Policy{Name: "p", Validation: "true"}); err == nil {
+		t.Error("expected an error for a policy defined twice")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "no policy files") {
+		t.Errorf("expected an error for a directory without policy files, got %v", err)
+	}
+	file := filepath.Join(dir, "policies.yaml")
+	if err := os.WriteFile(file, []byte("policies:\n  - name: p\n    validate: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil || !strings.Contains(err.Error(), "reading policy file") {
+		t.Errorf("expected an error for an unknown field, got %v", err)
+	}
+}
@@ -29,6 +29,9 @@ type Release struct {
 	// Config is the set of extra Values added to the chart.
 	// These values override the default values inside of the chart.
 	Config map[string]interface{} `json:"config,omitempty"`
+	// Generated is the set of values generated by the 'generated' template
+	// function, which are reused by the next revisions.
+	Generated map[string]interface{} `json:"generated,omitempty"`
 	// Manifest is the string representation of the rendered template.
 	Manifest string `json:"manifest,omitempty"`
 	// Hooks are all of the hooks declared for this release.
@@ -0,0 +1,330 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// Assertion checks the documents rendered by a test. Exactly one of Equal,
+// MatchRegex, Exists, HasDocuments, FailedTemplate and MatchSnapshot is set.
+type Assertion struct {
+	// Template, Kind and Name select the documents asserted on by the
+	// template rendering them, relative to the templates directory and
+	// possibly a glob pattern, by their kind and by their name. All the
+	// documents are selected by default.
+	Template string `json:"template,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Name     string `json:"name,omitempty"`
+	// Not negates the assertion.
+	Not bool `json:"not,omitempty"`
+
+	Equal          *EqualAssertion          `json:"equal,omitempty"`
+	MatchRegex     *MatchRegexAssertion     `json:"matchRegex,omitempty"`
+	Exists         *ExistsAssertion         `json:"exists,omitempty"`
+	HasDocuments   *HasDocumentsAssertion   `json:"hasDocuments,omitempty"`
+	FailedTemplate *FailedTemplateAssertion `json:"failedTemplate,omitempty"`
+	MatchSnapshot  *MatchSnapshotAssertion  `json:"matchSnapshot,omitempty"`
+}
+
+// EqualAssertion checks that the value at a JSONPath, e.g. 'spec.replicas'
+// or '{.spec.containers[0].image}', of each selected document is equal to
+// Value.
+type EqualAssertion struct {
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MatchRegexAssertion checks that the string at a JSONPath of each selected
+// document matches Pattern.
+type MatchRegexAssertion struct {
+	Path    string `json:"path"`
+	Pattern string `json:"pattern"`
+}
+
+// ExistsAssertion checks that each selected document has a value at a JSONPath.
+type ExistsAssertion struct {
+	Path string `json:"path"`
+}
+
+// HasDocumentsAssertion checks the number of selected documents.
+type HasDocumentsAssertion struct {
+	Count int `json:"count"`
+}
+
+// FailedTemplateAssertion checks that the chart fails to render, with an
+// error containing ErrorMessage or matching ErrorPattern if they are set.
+type FailedTemplateAssertion struct {
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	ErrorPattern string `json:"errorPattern,omitempty"`
+}
+
+// MatchSnapshotAssertion checks that the selected documents are the same as
+// when the snapshot of the assertion was taken. The snapshot is taken the
+// first time the assertion is checked.
+type MatchSnapshotAssertion struct{}
+
+// document is a YAML document rendered by a template.
+type document struct {
+	template string
+	content  map[string]interface{}
+}
+
+func (d document) kind() string {
+	kind, _ := d.content["kind"].(string)
+	return kind
+}
+
+func (d document) name() string {
+	metadata, _ := d.content["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return name
+}
+
+func (d document) String() string {
+	return fmt.Sprintf("%s/%s in %s", d.kind(), d.name(), d.template)
+}
+
+func (a *Assertion) validate() error {
+	set := 0
+	for _, v := range []interface{}{a.Equal, a.MatchRegex, a.Exists, a.HasDocuments, a.FailedTemplate, a.MatchSnapshot} {
+		if !reflect.ValueOf(v).IsNil() {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("exactly one of equal, matchRegex, exists, hasDocuments, failedTemplate and matchSnapshot is required")
+	}
+	if a.MatchRegex != nil {
+		if _, err := regexp.Compile(a.MatchRegex.Pattern); err != nil {
+			return errors.Wrap(err, "invalid matchRegex pattern")
+		}
+	}
+	if a.FailedTemplate != nil && a.FailedTemplate.ErrorPattern != "" {
+		if _, err := regexp.Compile(a.FailedTemplate.ErrorPattern); err != nil {
+			return errors.Wrap(err, "invalid failedTemplate errorPattern")
+		}
+	}
+	return nil
+}
+
+// selects returns whether the assertion applies to a document.
+func (a *Assertion) selects(d document) bool {
+	if a.Template != "" {
+		if ok, _ := path.Match(a.Template, d.template); !ok {
+			return false
+		}
+	}
+	return (a.Kind == "" || a.Kind == d.kind()) && (a.Name == "" || a.Name == d.name())
+}
+
+// expectation describes what the assertion expects, e.g. 'spec.replicas to equal 3'.
+func (a *Assertion) expectation() string {
+	to := " to "
+	if a.Not {
+		to = " not to "
+	}
+	switch {
+	case a.Equal != nil:
+		return a.Equal.Path + to + "equal " + toJSON(a.Equal.Value)
+	case a.MatchRegex != nil:
+		return a.MatchRegex.Path + to + "match " + a.MatchRegex.Pattern
+	case a.Exists != nil:
+		return a.Exists.Path + to + "exist"
+	case a.HasDocuments != nil:
+		return fmt.Sprintf("the templates%srender %d documents", to, a.HasDocuments.Count)
+	case a.FailedTemplate != nil:
+		s := "the templates" + to + "fail to render"
+		if a.FailedTemplate.ErrorMessage != "" {
+			s += fmt.Sprintf(" with an error containing %q", a.FailedTemplate.ErrorMessage)
+		} else if a.FailedTemplate.ErrorPattern != "" {
+			s += " with an error matching " + a.FailedTemplate.ErrorPattern
+		}
+		return s
+	default:
+		return "the documents" + to + "match the snapshot"
+	}
+}
+
+// check checks the assertion against the documents rendered by a test, or
+// the error rendering them, and returns why it fails. snapshot returns the
+// snapshot of the assertion, taking it from the given content if there is none.
+func (a *Assertion) check(docs []document, renderErr error, snapshot func(content string) string) error {
+	if a.FailedTemplate != nil {
+		ok := renderErr != nil
+		actual := "rendered successfully"
+		if renderErr != nil {
+			actual = "failed with: " + renderErr.Error()
+			if msg := a.FailedTemplate.ErrorMessage; msg != "" {
+				ok = strings.Contains(renderErr.Error(), msg)
+			} else if pattern := a.FailedTemplate.ErrorPattern; pattern != "" {
+				ok = regexp.MustCompile(pattern).MatchString(renderErr.Error())
+			}
+		}
+		return a.result(ok, actual)
+	}
+	if renderErr != nil {
+		return errors.Wrap(renderErr, "failed to render")
+	}
+
+	var selected []document
+	for _, d := range docs {
+		if a.selects(d) {
+			selected = append(selected, d)
+		}
+	}
+
+	switch {
+	case a.HasDocuments != nil:
+		return a.result(len(selected) == a.HasDocuments.Count, fmt.Sprintf("got %d", len(selected)))
+	case a.MatchSnapshot != nil:
+		var b strings.Builder
+		for i, d := range selected {
+			if i > 0 {
+				b.WriteString("---\n")
+			}
+			out, err := yaml.Marshal(d.content)
+			if err != nil {
+				return err
+			}
+			b.Write(out)
+		}
+		content := b.String()
+		previous := snapshot(content)
+		return a.result(content == previous, "got:\n"+content+"snapshot:\n"+previous)
+	}
+
+	if len(selected) == 0 {
+		return errors.New("no documents selected")
+	}
+	for _, d := range selected {
+		ok, actual, err := a.checkDocument(d)
+		if err != nil {
+			return err
+		}
+		if err := a.result(ok, actual+" in "+d.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDocument checks a path assertion against a document.
+func (a *Assertion) checkDocument(d document) (bool, string, error) {
+	switch {
+	case a.Equal != nil:
+		values, err := findPath(d.content, a.Equal.Path)
+		if err != nil {
+			return false, "", err
+		}
+		var actual interface{} = values
+		if len(values) == 1 {
+			actual = values[0]
+		}
+		return len(values) > 0 && reflect.DeepEqual(normalize(actual), normalize(a.Equal.Value)), got(actual, len(values)), nil
+	case a.MatchRegex != nil:
+		values, err := findPath(d.content, a.MatchRegex.Path)
+		if err != nil {
+			return false, "", err
+		}
+		re := regexp.MustCompile(a.MatchRegex.Pattern)
+		ok := len(values) > 0
+		for _, v := range values {
+			s, isString := v.(string)
+			ok = ok && isString && re.MatchString(s)
+		}
+		return ok, got(values, len(values)), nil
+	default:
+		values, err := findPath(d.content, a.Exists.Path)
+		if err != nil {
+			return false, "", err
+		}
+		return len(values) > 0, got(values, len(values)), nil
+	}
+}
+
+// got describes the values found at a path.
+func got(values interface{}, n int) string {
+	if n == 0 {
+		return "got no value"
+	}
+	return "got " + toJSON(values)
+}
+
+// result returns an error describing the failure of the assertion unless ok,
+// negated by Not, holds.
+func (a *Assertion) result(ok bool, actual string) error {
+	if ok != a.Not {
+		return nil
+	}
+	return errors.Errorf("expected %s, %s", a.expectation(), actual)
+}
+
+// findPath returns the values at a JSONPath of a document. The braces and the
+// leading dot of the path are optional.
+func findPath(content map[string]interface{}, p string) ([]interface{}, error) {
+	expr := p
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{." + strings.TrimPrefix(expr, ".") + "}"
+	}
+	jp := jsonpath.New(p).AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return nil, errors.Wrapf(err, "invalid path %q", p)
+	}
+	results, err := jp.FindResults(content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid path %q", p)
+	}
+	var values []interface{}
+	for _, result := range results {
+		for _, v := range result {
+			values = append(values, v.Interface())
+		}
+	}
+	return values, nil
+}
+
+// normalize converts a value to the types of decoded JSON, so that e.g. an
+// int and a float64 with the same value are equal.
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var n interface{}
+	if err := json.Unmarshal(data, &n); err != nil {
+		return v
+	}
+	return n
+}
+
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAssertionCheck(t *testing.T) {
+	docs := []document{
+		{template: "deployment.yaml", content: map[string]interface{}{
+			"kind":     "Deployment",
+			"metadata": map[string]interface{}{"name": "web"},
+			"spec": map[string]interface{}{
+				"replicas": float64(2),
+				"ports":    []interface{}{float64(80), float64(443)},
+			},
+		}},
+		{template: "service.yaml", content: map[string]interface{}{
+			"kind":     "Service",
+			"metadata": map[string]interface{}{"name": "web"},
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		assertion Assertion
+		renderErr error
+		expect    string
+	}{
+		{
+			name:      "equal",
+			assertion: Assertion{Kind: "Deployment", Equal: &EqualAssertion{Path: "spec.replicas", Value: 2}},
+		},
+		{
+			name:      "equal list",
+			assertion: Assertion{Kind: "Deployment", Equal: &EqualAssertion{Path: "{.spec.ports[*]}", Value: []interface{}{80, 443}}},
+		},
+		{
+			name:      "not equal",
+			assertion: Assertion{Kind: "Deployment", Equal: &EqualAssertion{Path: "spec.replicas", Value: 3}},
+			expect:    "expected spec.replicas to equal 3, got 2 in Deployment/web in deployment.yaml",
+		},
+		{
+			name:      "negated equal",
+			assertion: Assertion{Template: "deploy*", Not: true, Equal: &EqualAssertion{Path: "spec.replicas", Value: 2}},
+			expect:    "expected spec.replicas not to equal 2, got 2 in Deployment/web in deployment.yaml",
+		},
+		{
+			name:      "missing path",
+			assertion: Assertion{Equal: &EqualAssertion{Path: "spec.replicas", Value: 2}},
+			expect:    "expected spec.replicas to equal 2, got no value in Service/web in service.yaml",
+		},
+		{
+			name:      "match regex",
+			assertion: Assertion{MatchRegex: &MatchRegexAssertion{Path: "metadata.name", Pattern: "^w"}},
+		},
+		{
+			name:      "exists",
+			assertion: Assertion{Name: "web", Kind: "Service", Not: true, Exists: &ExistsAssertion{Path: "spec"}},
+		},
+		{
+			name:      "no documents selected",
+			assertion: Assertion{Kind: "Ingress", Exists: &ExistsAssertion{Path: "spec"}},
+			expect:    "no documents selected",
+		},
+		{
+			name:      "has documents",
+			assertion: Assertion{Name: "web", HasDocuments: &HasDocumentsAssertion{Count: 1}},
+			expect:    "expected the templates to render 1 documents, got 2",
+		},
+		{
+			name:      "render error",
+			assertion: Assertion{HasDocuments: &HasDocumentsAssertion{Count: 0}},
+			renderErr: errors.New("boom"),
+			expect:    "failed to render: boom",
+		},
+		{
+			name:      "failed template",
+			assertion: Assertion{FailedTemplate: &FailedTemplateAssertion{ErrorMessage: "boom"}},
+			renderErr: errors.New("template: boom"),
+		},
+		{
+			name:      "failed template with another error",
+			assertion: Assertion{FailedTemplate: &FailedTemplateAssertion{ErrorPattern: "^boom$"}},
+			renderErr: errors.New("template: boom"),
+			expect:    "expected the templates to fail to render with an error matching ^boom$, failed with: template: boom",
+		},
+		{
+			name:      "template rendered",
+			assertion: Assertion{FailedTemplate: &FailedTemplateAssertion{}},
+			expect:    "expected the templates to fail to render, rendered successfully",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.assertion.check(docs, tt.renderErr, nil)
+			if tt.expect == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expect {
+				t.Errorf("expected error %q, got %v", tt.expect, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// Resource is a document of a rendered manifest, normalized so that neither
+// its formatting nor the order of its fields matter.
+type Resource struct {
+	// ID identifies the resource by kind, namespace and name, e.g.
+	// 'Deployment/default/web'.
+	ID string
+	// Manifest is the resource as YAML, with its fields sorted.
+	Manifest string
+}
+
+// NormalizeManifest splits a rendered manifest into resources, sorted by
+// kind, namespace and name. Resources with the same identity are kept in
+// the order they are rendered, and their ID is suffixed by their position.
+func NormalizeManifest(manifest string) ([]Resource, error) {
+	docs := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	type entry struct {
+		kind, namespace, name string
+		resource              Resource
+	}
+	var entries []entry
+	for _, key := range keys {
+		var content map[string]interface{}
+		if err := yaml.Unmarshal([]byte(docs[key]), &content); err != nil {
+			return nil, errors.Wrapf(err, "invalid YAML in document:\n%s", docs[key])
+		}
+		if content == nil {
+			continue
+		}
+		out, err := yaml.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		kind, _ := content["kind"].(string)
+		metadata, _ := content["metadata"].(map[string]interface{})
+		namespace, _ := metadata["namespace"].(string)
+		name, _ := metadata["name"].(string)
+		entries = append(entries, entry{kind, namespace, name, Resource{Manifest: string(out)}})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		return a.name < b.name
+	})
+
+	resources := make([]Resource, len(entries))
+	seen := map[string]int{}
+	for i, e := range entries {
+		id := strings.Join([]string{e.kind, e.namespace, e.name}, "/")
+		if e.namespace == "" {
+			id = e.kind + "/" + e.name
+		}
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			id = fmt.Sprintf("%s (%d)", id, n)
+		}
+		e.resource.ID = id
+		resources[i] = e.resource
+	}
+	return resources, nil
+}
+
+// FormatResources formats resources as a manifest, each preceded by a
+// comment with its ID.
+func FormatResources(resources []Resource) string {
+	var b strings.Builder
+	for _, r := range resources {
+		fmt.Fprintf(&b, "---\n# %s\n%s", r.ID, r.Manifest)
+	}
+	return b.String()
+}
+
+// DiffResources describes the differences between the expected and actual
+// resources, resource by resource. It returns an empty string if they are
+// the same.
+func DiffResources(expected, actual []Resource) string {
+	expectedByID := make(map[string]string, len(expected))
+	for _, r := range expected {
+		expectedByID[r.ID] = r.Manifest
+	}
+	actualByID := make(map[string]string, len(actual))
+	for _, r := range actual {
+		actualByID[r.ID] = r.Manifest
+	}
+
+	ids := make([]string, 0, len(expectedByID)+len(actualByID))
+	for id := range expectedByID {
+		ids = append(ids, id)
+	}
+	for id := range actualByID {
+		if _, ok := expectedByID[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var b strings.Builder
+	for _, id := range ids {
+		before, inExpected := expectedByID[id]
+		after, inActual := actualByID[id]
+		switch {
+		case !inExpected:
+			fmt.Fprintf(&b, "%s: added\n", id)
+		case !inActual:
+			fmt.Fprintf(&b, "%s: removed\n", id)
+		case before != after:
+			diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        splitLines(before),
+				B:        splitLines(after),
+				FromFile: "snapshot",
+				ToFile:   "rendered",
+				Context:  3,
+			})
+			fmt.Fprintf(&b, "%s: changed\n%s", id, diff)
+		}
+	}
+	return b.String()
+}
+
+// splitLines splits YAML into lines, each ending in a newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// TemplateSnapshot is a snapshot of the manifest rendered by a chart with a
+// values file, taken by 'helm template --snapshot'.
+type TemplateSnapshot struct {
+	// Name is the name of the snapshot: 'default', or the name of the values
+	// file without its '-values.yaml' suffix.
+	Name string
+	// ValuesFile is the values file of the snapshot. It is empty for the
+	// default values of the chart.
+	ValuesFile string
+	// Path is the path of the snapshot file.
+	Path string
+}
+
+// TemplateSnapshots returns the snapshots of a chart directory: one with the
+// default values of the chart, and one for each values file in its 'ci'
+// directory ending in '-values.yaml'.
+func TemplateSnapshots(chartPath string) ([]TemplateSnapshot, error) {
+	dir := filepath.Join(chartPath, chartutil.UnitTestsDir, SnapshotDir, "template")
+	snapshots := []TemplateSnapshot{{Name: "default", Path: filepath.Join(dir, "default.yaml")}}
+
+	files, err := filepath.Glob(filepath.Join(chartPath, "ci", "*-values.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), "-values.yaml")
+		snapshots = append(snapshots, TemplateSnapshot{
+			Name:       name,
+			ValuesFile: file,
+			Path:       filepath.Join(dir, name+".yaml"),
+		})
+	}
+	return snapshots, nil
+}
+
+// Check compares a rendered manifest with the snapshot and returns the
+// differences, as DiffResources does. The snapshot is written instead if it
+// does not exist or update is set, and written is true.
+func (s TemplateSnapshot) Check(manifest string, update bool) (diff string, written bool, err error) {
+	actual, err := NormalizeManifest(manifest)
+	if err != nil {
+		return "", false, err
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", false, err
+	}
+	if os.IsNotExist(err) || update {
+		if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+			return "", false, err
+		}
+		return "", true, os.WriteFile(s.Path, []byte(FormatResources(actual)), 0644)
+	}
+
+	expected, err := NormalizeManifest(string(data))
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed to parse snapshot %s", s.Path)
+	}
+	return DiffResources(expected, actual), false, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const manifest = `---
+# Source: web/templates/service.yaml
+kind: Service
+apiVersion: v1
+metadata:
+  name: web
+---
+# Source: web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  namespace: prod
+  name: web
+spec:
+  replicas: 2
+---
+# Source: web/templates/empty.yaml
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  clusterIP: None
+`
+
+func TestNormalizeManifest(t *testing.T) {
+	resources, err := NormalizeManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Resource{
+		{ID: "Deployment/prod/web", Manifest: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: prod\nspec:\n  replicas: 2\n"},
+		{ID: "Service/web", Manifest: "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"},
+		{ID: "Service/web (2)", Manifest: "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  clusterIP: None\n"},
+	}
+	if len(resources) != len(expected) {
+		t.Fatalf("expected %d resources, got %d: %v", len(expected), len(resources), resources)
+	}
+	for i := range expected {
+		if resources[i] != expected[i] {
+			t.Errorf("expected resource %d to be %+v, got %+v", i, expected[i], resources[i])
+		}
+	}
+
+	// A formatted manifest is normalized to the same resources.
+	again, err := NormalizeManifest(FormatResources(resources))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := DiffResources(resources, again); diff != "" {
+		t.Errorf("expected no differences, got:\n%s", diff)
+	}
+}
+
+func TestDiffResources(t *testing.T) {
+	expected := []Resource{
+		{ID: "ConfigMap/config", Manifest: "data:\n  a: b\n"},
+		{ID: "Deployment/web", Manifest: "spec:\n  replicas: 1\n"},
+	}
+	actual := []Resource{
+		{ID: "Deployment/web", Manifest: "spec:\n  replicas: 2\n"},
+		{ID: "Service/web", Manifest: "spec: {}\n"},
+	}
+	got := DiffResources(expected, actual)
+	want := `ConfigMap/config: removed
+Deployment/web: changed
+--- snapshot
++++ rendered
+@@ -1,2 +1,2 @@
+ spec:
+-  replicas: 1
++  replicas: 2
+Service/web: added
+`
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestTemplateSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "ci", "production-values.yaml"), "replicas: 3\n")
+	writeFile(t, filepath.Join(dir, "ci", "notes.txt"), "not a values file\n")
+
+	snapshots, err := TemplateSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Name != "default" || snapshots[1].Name != "production" {
+		t.Fatalf("unexpected snapshots: %+v", snapshots)
+	}
+	s := snapshots[1]
+	if s.ValuesFile != filepath.Join(dir, "ci", "production-values.yaml") {
+		t.Errorf("unexpected values file %s", s.ValuesFile)
+	}
+
+	// The snapshot is taken the first time.
+	if _, written, err := s.Check(manifest, false); err != nil || !written {
+		t.Fatalf("expected the snapshot to be written, got %v, %v", written, err)
+	}
+	if diff, written, err := s.Check(manifest, false); err != nil || written || diff != "" {
+		t.Fatalf("expected the snapshot to match, got %v, %v, %q", written, err, diff)
+	}
+
+	changed := manifest + "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"
+	if diff, _, err := s.Check(changed, false); err != nil || diff != "ConfigMap/config: added\n" {
+		t.Fatalf("expected the snapshot to differ, got %v, %q", err, diff)
+	}
+	if _, written, err := s.Check(changed, true); err != nil || !written {
+		t.Fatalf("expected the snapshot to be updated, got %v, %v", written, err)
+	}
+	if diff, _, err := s.Check(changed, false); err != nil || diff != "" {
+		t.Fatalf("expected the updated snapshot to match, got %v, %q", err, diff)
+	}
+}
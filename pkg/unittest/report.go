@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteTAP writes the results in the Test Anything Protocol format.
+func WriteTAP(out io.Writer, results []*Result) error {
+	fmt.Fprintln(out, "TAP version 13")
+	fmt.Fprintf(out, "1..%d\n", len(results))
+	for i, r := range results {
+		status := "ok"
+		if !r.Passed() {
+			status = "not ok"
+		}
+		if _, err := fmt.Fprintf(out, "%s %d - %s: %s\n", status, i+1, r.Suite, r.Name()); err != nil {
+			return err
+		}
+		if r.Passed() {
+			continue
+		}
+		fmt.Fprintln(out, "  ---")
+		fmt.Fprintln(out, "  failures:")
+		for _, failure := range r.Failures {
+			fmt.Fprintln(out, "    - |-")
+			for _, line := range strings.Split(failure, "\n") {
+				fmt.Fprintf(out, "      %s\n", line)
+			}
+		}
+		fmt.Fprintln(out, "  ...")
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+
+	duration time.Duration
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as a JUnit XML report, with a test suite for
+// each test suite of each chart.
+func WriteJUnit(out io.Writer, results []*Result) error {
+	var report junitTestSuites
+	index := map[string]int{}
+	for _, r := range results {
+		name := r.Chart + ": " + r.Suite
+		i, ok := index[name]
+		if !ok {
+			i = len(report.Suites)
+			index[name] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: name})
+		}
+		suite := &report.Suites[i]
+
+		c := junitTestCase{
+			Name:      r.Name(),
+			ClassName: name,
+			Time:      fmt.Sprintf("%.3f", r.Duration.Seconds()),
+		}
+		if !r.Passed() {
+			c.Failure = &junitFailure{
+				Message:  r.Failures[0],
+				Contents: strings.Join(r.Failures, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, c)
+		suite.duration += r.Duration
+		suite.Time = fmt.Sprintf("%.3f", suite.duration.Seconds())
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
@@ -0,0 +1,330 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// Runner runs the test suites of charts.
+type Runner struct {
+	// KubeVersions overrides the Kubernetes versions the tests are run for.
+	KubeVersions []string
+	// UpdateSnapshots replaces the snapshots by the rendered documents
+	// instead of comparing them.
+	UpdateSnapshots bool
+}
+
+// Result is the result of a test, for a Kubernetes version.
+type Result struct {
+	Chart       string
+	Suite       string
+	Test        string
+	KubeVersion string
+	Failures    []string
+	Duration    time.Duration
+}
+
+// Passed returns whether all the assertions of the test hold.
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Name returns the name of the test, with the Kubernetes version it was run for.
+func (r *Result) Name() string {
+	if r.KubeVersion == "" {
+		return r.Test
+	}
+	return fmt.Sprintf("%s [kube %s]", r.Test, r.KubeVersion)
+}
+
+// Run runs the test suites of the chart at chartPath.
+func (r *Runner) Run(chartPath string) ([]*Result, error) {
+	suites, err := LoadSuites(chartPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*Result
+	for _, suite := range suites {
+		snapshots, err := loadSnapshots(suite.path, r.UpdateSnapshots)
+		if err != nil {
+			return nil, err
+		}
+		kubeVersions := r.KubeVersions
+		if len(kubeVersions) == 0 {
+			kubeVersions = suite.KubeVersions
+		}
+		if len(kubeVersions) == 0 {
+			kubeVersions = []string{""}
+		}
+
+		for _, test := range suite.Tests {
+			for _, kubeVersion := range kubeVersions {
+				start := time.Now()
+				result := &Result{
+					Chart:       chartPath,
+					Suite:       suite.Name,
+					Test:        test.It,
+					KubeVersion: kubeVersion,
+				}
+				out, err := r.render(chartPath, suite, test, kubeVersion)
+				if err != nil {
+					result.Failures = append(result.Failures, err.Error())
+				}
+				hasFailedTemplate := false
+				for i, assertion := range test.Asserts {
+					hasFailedTemplate = hasFailedTemplate || assertion.FailedTemplate != nil
+					if err != nil {
+						break
+					}
+					key := fmt.Sprintf("%s %d", result.Name(), i+1)
+					snapshot := func(content string) string {
+						return snapshots.match(key, content)
+					}
+					if err := assertion.check(out.docs, out.err, snapshot); err != nil {
+						result.Failures = append(result.Failures, fmt.Sprintf("assertion %d: %s", i+1, err))
+					}
+				}
+				if err == nil && out.err != nil && !hasFailedTemplate && len(result.Failures) == 0 {
+					result.Failures = append(result.Failures, fmt.Sprintf("failed to render: %s", out.err))
+				}
+				result.Duration = time.Since(start)
+				results = append(results, result)
+			}
+		}
+
+		if err := snapshots.save(); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// output is the output of rendering a chart for a test: the documents
+// rendered by the templates of the suite, or the error rendering them.
+type output struct {
+	docs []document
+	err  error
+}
+
+// render renders the chart for a test. It returns an error if the test is
+// invalid, e.g. when a values file cannot be read.
+func (r *Runner) render(chartPath string, suite *Suite, test *Test, kubeVersion string) (*output, error) {
+	// The chart is loaded for each test, as processing its dependencies
+	// changes it.
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := testValues(suite, test)
+	if err != nil {
+		return nil, err
+	}
+	caps, err := testCapabilities(suite, test, kubeVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := Release{Name: "release-name", Namespace: "default", Revision: 1}
+	isUpgrade := false
+	for _, override := range []*Release{suite.Release, test.Release} {
+		if override == nil {
+			continue
+		}
+		if override.Name != "" {
+			rel.Name = override.Name
+		}
+		if override.Namespace != "" {
+			rel.Namespace = override.Namespace
+		}
+		if override.Revision != 0 {
+			rel.Revision = override.Revision
+		}
+		if override.IsUpgrade != nil {
+			isUpgrade = *override.IsUpgrade
+		}
+	}
+	options := chartutil.ReleaseOptions{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Revision,
+		IsInstall: !isUpgrade,
+		IsUpgrade: isUpgrade,
+	}
+
+	if err := chartutil.ProcessDependenciesWithMerge(chrt, vals); err != nil {
+		return &output{err: err}, nil
+	}
+	valuesToRender, err := chartutil.ToRenderValues(chrt, vals, options, caps)
+	if err != nil {
+		return &output{err: err}, nil
+	}
+	rendered, err := engine.Render(chrt, valuesToRender)
+	if err != nil {
+		return &output{err: err}, nil
+	}
+	docs, err := documents(chrt, rendered, suite.Templates)
+	return &output{docs: docs, err: err}, nil
+}
+
+// documents splits the rendered templates into documents, ordered by
+// template name. Only the documents of the given templates are returned if
+// any is given.
+func documents(chrt *chart.Chart, rendered map[string]string, templates []string) ([]document, error) {
+	prefix := path.Join(chrt.Name(), "templates") + "/"
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var docs []document
+	for _, name := range names {
+		if strings.HasSuffix(name, "NOTES.txt") || strings.HasPrefix(path.Base(name), "_") {
+			continue
+		}
+		template := strings.TrimPrefix(name, prefix)
+		if !matchesAny(templates, template) {
+			continue
+		}
+		manifests := releaseutil.SplitManifests(rendered[name])
+		keys := make([]string, 0, len(manifests))
+		for key := range manifests {
+			keys = append(keys, key)
+		}
+		sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+		for _, key := range keys {
+			var content map[string]interface{}
+			if err := yaml.Unmarshal([]byte(manifests[key]), &content); err != nil {
+				return nil, errors.Wrapf(err, "%s: invalid YAML", name)
+			}
+			if content == nil {
+				continue
+			}
+			docs = append(docs, document{template: template, content: content})
+		}
+	}
+	return docs, nil
+}
+
+// matchesAny returns whether a template matches any of the patterns, or
+// there are no patterns.
+func matchesAny(patterns []string, template string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, template); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// testValues returns the values of a test: the values files and values set
+// by the suite, then those of the test.
+func testValues(suite *Suite, test *Test) (map[string]interface{}, error) {
+	vals := map[string]interface{}{}
+	dir := filepath.Dir(suite.path)
+	for _, layer := range []struct {
+		files []string
+		set   map[string]interface{}
+	}{{suite.Values, suite.Set}, {test.Values, test.Set}} {
+		for _, file := range layer.files {
+			fileVals, err := chartutil.ReadValuesFile(filepath.Join(dir, file))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read values file %s", file)
+			}
+			vals = mergeMaps(vals, fileVals)
+		}
+		keys := make([]string, 0, len(layer.set))
+		for key := range layer.set {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			vals = mergeMaps(vals, nest(strings.Split(key, "."), layer.set[key]))
+		}
+	}
+	return vals, nil
+}
+
+// testCapabilities returns the capabilities of a test for a Kubernetes
+// version, which overrides the version of the capabilities if it is set.
+func testCapabilities(suite *Suite, test *Test, kubeVersion string) (*chartutil.Capabilities, error) {
+	caps := chartutil.DefaultCapabilities.Copy()
+	c := test.Capabilities
+	if c == nil {
+		c = suite.Capabilities
+	}
+	if c != nil {
+		if kubeVersion == "" {
+			kubeVersion = c.KubeVersion
+		}
+		caps.APIVersions = append(caps.APIVersions, c.APIVersions...)
+	}
+	if kubeVersion != "" {
+		v, err := chartutil.ParseKubeVersion(kubeVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid kube version %q", kubeVersion)
+		}
+		caps.KubeVersion = *v
+	}
+	return caps, nil
+}
+
+// nest returns a value nested in maps by the given keys.
+func nest(keys []string, v interface{}) map[string]interface{} {
+	if len(keys) == 1 {
+		return map[string]interface{}{keys[0]: v}
+	}
+	return map[string]interface{}{keys[0]: nest(keys[1:], v)}
+}
+
+func mergeMaps(a, b map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if v, ok := v.(map[string]interface{}); ok {
+			if bv, ok := out[k]; ok {
+				if bv, ok := bv.(map[string]interface{}); ok {
+					out[k] = mergeMaps(bv, v)
+					continue
+				}
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
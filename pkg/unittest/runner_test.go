@@ -0,0 +1,247 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunner(t *testing.T) {
+	results, err := (&Runner{}).Run("testdata/mychart")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"deployment: renders the defaults [kube 1.27.0]",
+		"deployment: renders the defaults [kube 1.29.0]",
+		"deployment: uses the values files and set values [kube 1.27.0]",
+		"deployment: uses the values files and set values [kube 1.29.0]",
+		"deployment: requires an image tag [kube 1.27.0]",
+		"deployment: requires an image tag [kube 1.29.0]",
+		"service: renders the services",
+		"service: can be disabled",
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i, r := range results {
+		if name := r.Suite + ": " + r.Name(); name != expected[i] {
+			t.Errorf("expected result %d to be %q, got %q", i, expected[i], name)
+		}
+		if !r.Passed() {
+			t.Errorf("%s: unexpected failures: %v", expected[i], r.Failures)
+		}
+	}
+}
+
+func TestRunnerKubeVersions(t *testing.T) {
+	results, err := (&Runner{KubeVersions: []string{"1.25.0"}}).Run("testdata/mychart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+
+	r := results[0]
+	if r.KubeVersion != "1.25.0" {
+		t.Errorf("expected the tests to run for 1.25.0, got %q", r.KubeVersion)
+	}
+	if len(r.Failures) != 1 || !strings.Contains(r.Failures[0], `expected metadata.labels.kube to match ^2[79]$, got ["25"]`) {
+		t.Errorf("unexpected failures: %v", r.Failures)
+	}
+}
+
+func TestRunnerSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Chart.yaml"), "apiVersion: v2\nname: snap\nversion: 0.1.0\n")
+	writeFile(t, filepath.Join(dir, "templates", "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\ndata:\n  value: {{ .Values.value | quote }}\n")
+	writeFile(t, filepath.Join(dir, "tests", "cm_test.yaml"), `
+tests:
+  - it: matches the snapshot
+    set:
+      value: first
+    asserts:
+      - matchSnapshot: {}
+`)
+	snapshot := filepath.Join(dir, "tests", SnapshotDir, "cm_test.snap")
+
+	// The snapshot is taken the first time.
+	results, err := (&Runner{}).Run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Passed() {
+		t.Fatalf("unexpected failures: %v", results[0].Failures)
+	}
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "value: first") {
+		t.Errorf("unexpected snapshot:\n%s", data)
+	}
+
+	// The snapshot is compared against afterwards.
+	writeFile(t, filepath.Join(dir, "tests", "cm_test.yaml"), strings.ReplaceAll(readFile(t, filepath.Join(dir, "tests", "cm_test.yaml")), "first", "second"))
+	results, err = (&Runner{}).Run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Passed() || !strings.Contains(results[0].Failures[0], "expected the documents to match the snapshot") {
+		t.Fatalf("expected the snapshot not to match, got %v", results[0].Failures)
+	}
+
+	// The snapshot is replaced when it is updated.
+	results, err = (&Runner{UpdateSnapshots: true}).Run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Passed() {
+		t.Fatalf("unexpected failures: %v", results[0].Failures)
+	}
+	if !strings.Contains(readFile(t, snapshot), "value: second") {
+		t.Errorf("expected the snapshot to be updated")
+	}
+}
+
+func TestRunnerRenderError(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Chart.yaml"), "apiVersion: v2\nname: broken\nversion: 0.1.0\n")
+	writeFile(t, filepath.Join(dir, "templates", "cm.yaml"), "{{ fail \"broken\" }}\n")
+	writeFile(t, filepath.Join(dir, "tests", "cm_test.yaml"), `
+tests:
+  - it: renders
+    asserts:
+      - hasDocuments:
+          count: 1
+  - it: fails
+    asserts:
+      - failedTemplate:
+          errorPattern: bro+ken
+`)
+
+	results, err := (&Runner{}).Run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Passed() || !strings.Contains(results[0].Failures[0], "failed to render") {
+		t.Errorf("expected the first test to fail to render, got %v", results[0].Failures)
+	}
+	if !results[1].Passed() {
+		t.Errorf("unexpected failures: %v", results[1].Failures)
+	}
+}
+
+func TestRunnerRelease(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Chart.yaml"), "apiVersion: v2\nname: upgrade\nversion: 0.1.0\n")
+	writeFile(t, filepath.Join(dir, "templates", "cm.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  upgrade: {{ .Release.IsUpgrade | quote }}\n")
+	writeFile(t, filepath.Join(dir, "tests", "cm_test.yaml"), `
+release:
+  upgrade: true
+tests:
+  - it: keeps the release of the suite
+    release:
+      name: other
+    asserts:
+      - equal: {path: data.upgrade, value: "true"}
+      - equal: {path: metadata.name, value: other}
+  - it: overrides the release of the suite
+    release:
+      upgrade: false
+    asserts:
+      - equal: {path: data.upgrade, value: "false"}
+`)
+
+	results, err := (&Runner{}).Run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			t.Errorf("%s: unexpected failures: %v", r.Test, r.Failures)
+		}
+	}
+}
+
+func TestLoadSuiteErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		suite  string
+		expect string
+	}{
+		{
+			name:   "unknown field",
+			suite:  "tests:\n  - it: works\n    assert: []\n",
+			expect: `unknown field "assert"`,
+		},
+		{
+			name:   "no description",
+			suite:  "tests:\n  - asserts: []\n",
+			expect: "has no description",
+		},
+		{
+			name:   "no assertion type",
+			suite:  "tests:\n  - it: works\n    asserts:\n      - kind: Deployment\n",
+			expect: "exactly one of",
+		},
+		{
+			name:   "two assertion types",
+			suite:  "tests:\n  - it: works\n    asserts:\n      - exists: {path: a}\n        hasDocuments: {count: 1}\n",
+			expect: "exactly one of",
+		},
+		{
+			name:   "invalid pattern",
+			suite:  "tests:\n  - it: works\n    asserts:\n      - matchRegex: {path: a, pattern: '('}\n",
+			expect: "invalid matchRegex pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "suite_test.yaml")
+			writeFile(t, path, tt.suite)
+			_, err := LoadSuite(path)
+			if err == nil || !strings.Contains(err.Error(), tt.expect) {
+				t.Errorf("expected an error containing %q, got %v", tt.expect, err)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
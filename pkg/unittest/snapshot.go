@@ -0,0 +1,88 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// SnapshotDir is the directory, next to the test suite files, of the
+// snapshots of the matchSnapshot assertions.
+const SnapshotDir = "__snapshot__"
+
+// snapshots are the snapshots of a test suite, keyed by test and assertion.
+type snapshots struct {
+	path    string
+	entries map[string]string
+	update  bool
+	changed bool
+}
+
+// loadSnapshots loads the snapshots of the suite at suitePath. If update is
+// set, the snapshots are replaced by the content they are matched against.
+func loadSnapshots(suitePath string, update bool) (*snapshots, error) {
+	name := strings.TrimSuffix(filepath.Base(suitePath), filepath.Ext(suitePath)) + ".snap"
+	s := &snapshots{
+		path:    filepath.Join(filepath.Dir(suitePath), SnapshotDir, name),
+		entries: map[string]string{},
+		update:  update,
+	}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &s.entries); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse snapshots %s", s.path)
+	}
+	return s, nil
+}
+
+// match returns the snapshot of key. The snapshot is taken from content if
+// there is none or the snapshots are updated.
+func (s *snapshots) match(key, content string) string {
+	if previous, ok := s.entries[key]; ok && !s.update {
+		return previous
+	}
+	if s.entries[key] != content {
+		s.entries[key] = content
+		s.changed = true
+	}
+	return content
+}
+
+// save writes the snapshots if any was taken.
+func (s *snapshots) save() error {
+	if !s.changed {
+		return nil
+	}
+	data, err := yaml.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unittest
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// SuiteFileSuffix is the suffix of the test suite files in the tests directory
+// of a chart.
+const SuiteFileSuffix = "_test.yaml"
+
+// Suite is a file of tests of the templates of a chart, e.g.
+//
+//	suite: deployment
+//	templates:
+//	  - deployment.yaml
+//	tests:
+//	  - it: sets the number of replicas
+//	    set:
+//	      replicaCount: 3
+//	    asserts:
+//	      - kind: Deployment
+//	        equal:
+//	          path: spec.replicas
+//	          value: 3
+//
+// The settings of the suite apply to all its tests, which can override them.
+type Suite struct {
+	// Name is the name of the suite. It defaults to the name of the file.
+	Name string `json:"suite,omitempty"`
+	// Templates restricts the documents asserted on to those rendered by
+	// these templates, relative to the templates directory.
+	Templates []string `json:"templates,omitempty"`
+	// Values are values files, relative to the suite file, merged in order.
+	Values []string `json:"values,omitempty"`
+	// Set sets values by path, e.g. 'image.tag', after the values files.
+	Set map[string]interface{} `json:"set,omitempty"`
+	// Release describes the release rendering the chart.
+	Release *Release `json:"release,omitempty"`
+	// Capabilities describes the cluster rendering the chart.
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+	// KubeVersions runs each test once for each of these Kubernetes versions.
+	KubeVersions []string `json:"kubeVersions,omitempty"`
+	// Tests are the tests of the suite.
+	Tests []*Test `json:"tests"`
+
+	// path is the path of the suite file.
+	path string
+}
+
+// Test renders the chart and asserts on the result.
+type Test struct {
+	// It describes what the test checks.
+	It string `json:"it"`
+	// Values are values files, relative to the suite file, merged after
+	// those of the suite.
+	Values []string `json:"values,omitempty"`
+	// Set sets values by path, after those of the suite.
+	Set map[string]interface{} `json:"set,omitempty"`
+	// Release overrides the release of the suite.
+	Release *Release `json:"release,omitempty"`
+	// Capabilities overrides the capabilities of the suite.
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+	// Asserts are the assertions of the test.
+	Asserts []*Assertion `json:"asserts"`
+}
+
+// Release describes the release rendering the chart.
+type Release struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Revision  int    `json:"revision,omitempty"`
+	IsUpgrade *bool  `json:"upgrade,omitempty"`
+}
+
+// Capabilities describes the cluster rendering the chart.
+type Capabilities struct {
+	KubeVersion string   `json:"kubeVersion,omitempty"`
+	APIVersions []string `json:"apiVersions,omitempty"`
+}
+
+// HasUnitTests reports whether the files of a chart include test suites or
+// snapshots in its tests directory.
+func HasUnitTests(files []*chart.File) bool {
+	for _, f := range files {
+		dir, name := path.Split(f.Name)
+		if dir == chartutil.UnitTestsDir+"/" && strings.HasSuffix(name, SuiteFileSuffix) ||
+			strings.HasPrefix(f.Name, path.Join(chartutil.UnitTestsDir, SnapshotDir)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadSuites loads the test suites in the tests directory of a chart, sorted
+// by file name.
+func LoadSuites(chartPath string) ([]*Suite, error) {
+	paths, err := filepath.Glob(filepath.Join(chartPath, chartutil.UnitTestsDir, "*"+SuiteFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var suites []*Suite
+	for _, path := range paths {
+		suite, err := LoadSuite(path)
+		if err != nil {
+			return nil, err
+		}
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+// LoadSuite loads a test suite file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	suite := &Suite{path: path}
+	if err := yaml.UnmarshalStrict(data, suite); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse test suite %s", path)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), SuiteFileSuffix)
+	}
+	for i, test := range suite.Tests {
+		if test == nil || test.It == "" {
+			return nil, errors.Errorf("test %d of suite %s has no description ('it')", i+1, path)
+		}
+		for j, assertion := range test.Asserts {
+			if err := assertion.validate(); err != nil {
+				return nil, errors.Wrapf(err, "assertion %d of test %q of suite %s", j+1, test.It, path)
+			}
+		}
+	}
+	return suite, nil
+}